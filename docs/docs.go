@@ -15,18 +15,18 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/group/list": {
+        "/api/calendar/list": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Query"
+                    "Calendar"
                 ],
-                "summary": "查詢排程Group清單",
+                "summary": "查詢排除日曆清單",
                 "responses": {
                     "200": {
-                        "description": "{\"data\":[\"test\"],\"errors\":[]}",
+                        "description": "{\"data\":[],\"errors\":[]}",
                         "schema": {
                             "allOf": [
                                 {
@@ -38,7 +38,7 @@ const docTemplate = `{
                                         "data": {
                                             "type": "array",
                                             "items": {
-                                                "type": "string"
+                                                "$ref": "#/definitions/calendar.Calendar"
                                             }
                                         }
                                     }
@@ -49,58 +49,72 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/job/active/{group}/{id}": {
-            "put": {
+        "/api/calendar/{name}": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Update"
+                    "Calendar"
                 ],
-                "summary": "啟用排程",
+                "summary": "查詢排除日曆",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "group_name",
-                        "name": "group",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "job_id",
-                        "name": "id",
+                        "description": "日曆名稱",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{\"success\":true,\"errors\":[]}",
+                        "description": "{\"data\":{\"name\":\"holiday\",\"dates\":[\"2024-01-01\"]},\"errors\":[]}",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.SuccessRes"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/calendar.Calendar"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
                 }
-            }
-        },
-        "/api/job/add": {
-            "post": {
+            },
+            "put": {
+                "description": "引用此日曆的任務在日期或每週時段內的執行會被略過, 執行紀錄的 outcome 為 skipped; 更新後立即生效",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Update"
+                    "Calendar"
                 ],
-                "summary": "註冊排程任務",
+                "summary": "新增或更新排除日曆",
                 "parameters": [
                     {
-                        "description": "排程資料",
+                        "type": "string",
+                        "description": "日曆名稱",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "日曆內容, name 以路徑為準",
                         "name": "data",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/cronjob.TaskPayloadReq"
+                            "$ref": "#/definitions/calendar.Calendar"
                         }
                     }
                 ],
@@ -112,29 +126,21 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/job/delete/{group}/{id}": {
+            },
             "delete": {
+                "description": "引用此日曆的任務不再排除",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Update"
+                    "Calendar"
                 ],
-                "summary": "刪除註冊任務",
+                "summary": "刪除排除日曆",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "group_name",
-                        "name": "group",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "job_id",
-                        "name": "id",
+                        "description": "日曆名稱",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
@@ -149,34 +155,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/job/game/list": {
-            "get": {
+        "/api/deadletter/replay/{group}/{id}": {
+            "post": {
+                "description": "依失敗當下保存的任務內容重新執行一次(含重試策略), 成功後移除該筆紀錄",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Tasks List"
+                    "DeadLetter"
                 ],
-                "summary": "查詢遊戲排程任務清單 By Game",
+                "summary": "重新執行失敗紀錄 (dead letter)",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "group_name",
-                        "name": "group_name",
-                        "in": "query",
+                        "name": "group",
+                        "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "game_type",
-                        "name": "game_type",
-                        "in": "query",
+                        "description": "dead letter id",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{\"data\":[],\"errors\":[]}",
+                        "description": "{\"data\":{\"outcome\":\"success\"},\"errors\":[]}",
                         "schema": {
                             "allOf": [
                                 {
@@ -186,10 +193,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/cronjob.TaskPayload"
-                                            }
+                                            "$ref": "#/definitions/history.Record"
                                         }
                                     }
                                 }
@@ -199,34 +203,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/job/info": {
+        "/api/deadletter/{group}": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Query"
+                    "DeadLetter"
                 ],
-                "summary": "查詢單一排程資料",
+                "summary": "查詢群組的失敗紀錄 (dead letter)",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "group_name",
-                        "name": "group_name",
-                        "in": "query",
+                        "name": "group",
+                        "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "integer",
+                        "description": "紀錄寫入(失敗)時間起始 unix timestamp, 不是 scheduled_at",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "紀錄寫入(失敗)時間結束 unix timestamp, 包含該秒, 不是 scheduled_at",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每頁筆數, 預設20, 最大100",
+                        "name": "limit",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "job_id",
-                        "name": "job_id",
-                        "in": "query",
-                        "required": true
+                        "description": "上一頁回傳的 next_cursor",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{\"data\":[],\"errors\":[]}",
+                        "description": "{\"data\":{\"entries\":[],\"next_cursor\":\"\"},\"errors\":[]}",
                         "schema": {
                             "allOf": [
                                 {
@@ -236,7 +257,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/cronjob.TaskPayload"
+                                            "$ref": "#/definitions/deadletter.Page"
                                         }
                                     }
                                 }
@@ -244,79 +265,62 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/job/list": {
-            "get": {
+            },
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Tasks List"
+                    "DeadLetter"
                 ],
-                "summary": "查詢已註冊排程任務清單 By Group",
+                "summary": "清除群組所有失敗紀錄 (dead letter)",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "group_name",
-                        "name": "group_name",
-                        "in": "query",
+                        "name": "group",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{\"data\":[],\"errors\":[]}",
+                        "description": "{\"success\":true,\"errors\":[]}",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/httpserver.DataRespSchema"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "data": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/cronjob.TaskPayload"
-                                            }
-                                        }
-                                    }
-                                }
-                            ]
+                            "$ref": "#/definitions/httpserver.SuccessRes"
                         }
                     }
                 }
             }
         },
-        "/api/job/match/list": {
+        "/api/deadletter/{group}/{id}": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Tasks List"
+                    "DeadLetter"
                 ],
-                "summary": "查詢已註冊排程任務清單 By Match",
+                "summary": "查詢單筆失敗紀錄 (dead letter)",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "group_name",
-                        "name": "group_name",
-                        "in": "query",
+                        "name": "group",
+                        "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "match by name",
-                        "name": "match",
-                        "in": "query",
+                        "description": "dead letter id",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{\"data\":[],\"errors\":[]}",
+                        "description": "{\"data\":{},\"errors\":[]}",
                         "schema": {
                             "allOf": [
                                 {
@@ -326,10 +330,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/cronjob.TaskPayload"
-                                            }
+                                            "$ref": "#/definitions/deadletter.Entry"
                                         }
                                     }
                                 }
@@ -337,17 +338,15 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/job/pause/{group}/{id}": {
-            "put": {
+            },
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Update"
+                    "DeadLetter"
                 ],
-                "summary": "暫停排程",
+                "summary": "刪除單筆失敗紀錄 (dead letter)",
                 "parameters": [
                     {
                         "type": "string",
@@ -358,7 +357,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "job_id",
+                        "description": "dead letter id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -374,7 +373,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/job/query": {
+        "/api/group/jitter/{group}": {
             "get": {
                 "produces": [
                     "application/json"
@@ -382,77 +381,114 @@ const docTemplate = `{
                 "tags": [
                     "CronJob Query"
                 ],
-                "summary": "查詢排程註冊數和執行數量",
+                "summary": "查詢群組 jitter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "{\"data\":{\"running\":0,\"register\":0},\"errors\":[]}",
+                        "description": "{\"data\":{\"group_name\":\"test\",\"jitter\":60},\"errors\":[]}",
                         "schema": {
-                            "type": "object"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.GroupJitter"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
                 }
-            }
-        },
-        "/api/job/query/{id}": {
-            "get": {
+            },
+            "put": {
+                "description": "群組內未設定 jitter 的新任務依 job_id 固定延後 0 ~ jitter-1 秒, 已註冊的任務不受影響; 0 為清除",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Query"
+                    "CronJob Update"
                 ],
-                "summary": "查詢排程狀態",
+                "summary": "設定群組 jitter",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "job_id",
-                        "name": "id",
+                        "description": "group_name",
+                        "name": "group",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "秒, 上限 3600",
+                        "name": "jitter",
+                        "in": "query"
                     }
                 ],
-                "responses": {}
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
             }
         },
-        "/api/job/replace": {
-            "post": {
+        "/api/group/list": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Update"
-                ],
-                "summary": "更新排程任務",
-                "parameters": [
-                    {
-                        "description": "排程資料",
-                        "name": "data",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/cronjob.TaskPayloadReq"
-                        }
-                    }
+                    "CronJob Query"
                 ],
+                "summary": "查詢排程Group清單",
                 "responses": {
                     "200": {
-                        "description": "{\"success\":true,\"errors\":[]}",
+                        "description": "{\"data\":[\"test\"],\"errors\":[]}",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.SuccessRes"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "string"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
                 }
             }
         },
-        "/api/jobs/delete/{group}": {
-            "delete": {
+        "/api/group/notify/{group}": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Update"
+                    "Notify"
                 ],
-                "summary": "刪除Grop所有註冊任務",
+                "summary": "查詢群組通知管道",
                 "parameters": [
                     {
                         "type": "string",
@@ -464,23 +500,34 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "{\"success\":true,\"errors\":[]}",
+                        "description": "{\"data\":{\"group_name\":\"test\",\"channels\":[\"oncall\"]},\"errors\":[]}",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.SuccessRes"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/jobs/delete/{group}/{match}": {
-            "delete": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.GroupNotify"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "群組內所有任務的執行結果都通知這些管道, 與任務的 notifiers 合併; 立即生效, 空值為清除",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Update"
+                    "Notify"
                 ],
-                "summary": "刪除註冊任務 By Match",
+                "summary": "設定群組通知管道",
                 "parameters": [
                     {
                         "type": "string",
@@ -491,10 +538,9 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "match",
-                        "name": "match",
-                        "in": "path",
-                        "required": true
+                        "description": "通知管道名稱, 以逗號分隔",
+                        "name": "channels",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -507,36 +553,88 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/jobs/export": {
-            "post": {
-                "description": "Export all jobs as a JSON file",
+        "/api/group/timezone/{group}": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Import/export"
+                    "CronJob Query"
+                ],
+                "summary": "查詢群組時區",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "匯出任務 By All",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "{\"data\":{\"group_name\":\"test\",\"timezone\":\"Asia/Taipei\",\"is_default\":true},\"errors\":[]}",
                         "schema": {
-                            "type": "file"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.GroupTimezone"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "群組內未設定 timezone 的新任務使用此時區, 已註冊的任務不受影響; 空值為清除",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Update"
+                ],
+                "summary": "設定群組時區",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA 時區 ex.Europe/London",
+                        "name": "timezone",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
                         }
                     }
                 }
             }
         },
-        "/api/jobs/export/{group}": {
-            "post": {
-                "description": "Export all jobs as a JSON file",
+        "/api/job/active/{group}/{id}": {
+            "put": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Import/export"
+                    "CronJob Update"
                 ],
-                "summary": "匯出任務 By Group",
+                "summary": "啟用排程",
                 "parameters": [
                     {
                         "type": "string",
@@ -544,28 +642,65 @@ const docTemplate = `{
                         "name": "group",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "job_id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "{\"success\":true,\"errors\":[]}",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/httpserver.SuccessRes"
                         }
                     }
                 }
             }
         },
-        "/api/jobs/export/{group}/{match}": {
+        "/api/job/add": {
             "post": {
-                "description": "Export all jobs as a JSON file",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Import/export"
+                    "CronJob Update"
                 ],
-                "summary": "匯出任務 By Match",
+                "summary": "註冊排程任務",
+                "parameters": [
+                    {
+                        "description": "排程資料",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cronjob.TaskPayloadReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/job/cancel/{group}/{id}": {
+            "post": {
+                "description": "通知所有節點取消該排程正在執行的請求, 不影響之後的排程",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Update"
+                ],
+                "summary": "取消執行中的排程",
                 "parameters": [
                     {
                         "type": "string",
@@ -576,63 +711,85 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "match",
-                        "name": "match",
+                        "description": "job_id",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "{\"success\":true,\"errors\":[]}",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/httpserver.SuccessRes"
                         }
                     }
                 }
             }
         },
-        "/api/jobs/import": {
-            "post": {
-                "description": "Import a file using a form-data request",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+        "/api/job/delete/{group}/{id}": {
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "CronJob Import/export"
+                    "CronJob Update"
                 ],
-                "summary": "匯入任務",
+                "summary": "刪除註冊任務",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "File to import",
-                        "name": "file",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "job_id",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{\"message\": \"imported successfully\"}",
+                        "description": "{\"success\":true,\"errors\":[]}",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httpserver.SuccessRes"
                         }
                     }
                 }
             }
         },
-        "/api/ping": {
+        "/api/job/game/list": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
-                "summary": "確認服務連線",
+                "tags": [
+                    "CronJob Tasks List"
+                ],
+                "summary": "查詢遊戲排程任務清單 By Game",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group_name",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "game_type",
+                        "name": "game_type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "{\"data\":{\"pong\":\"pong\"}, \"errors\":[]}",
+                        "description": "{\"data\":[],\"errors\":[]}",
                         "schema": {
                             "allOf": [
                                 {
@@ -642,7 +799,10 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/httpserver.Pong"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/cronjob.TaskPayload"
+                                            }
                                         }
                                     }
                                 }
@@ -652,34 +812,312 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/service/cronjob/start": {
-            "post": {
+        "/api/job/history/{group}/{id}": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Service"
+                    "CronJob Query"
+                ],
+                "summary": "查詢排程執行紀錄",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "job_id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "預定執行時間(scheduled_at)起始 unix timestamp",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "預定執行時間(scheduled_at)結束 unix timestamp, 包含該秒",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每頁筆數, 預設20, 最大100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "上一頁回傳的 next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    }
                 ],
-                "summary": "排程功能啟動",
                 "responses": {
                     "200": {
-                        "description": "{\"success\":true,\"errors\":[]}",
+                        "description": "{\"data\":{\"records\":[],\"next_cursor\":\"\"},\"errors\":[]}",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.SuccessRes"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/history.Page"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
                 }
             }
         },
-        "/api/service/cronjob/stop": {
-            "post": {
+        "/api/job/info": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Service"
+                    "CronJob Query"
+                ],
+                "summary": "查詢單一排程資料",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group_name",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "job_id",
+                        "name": "job_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":[],\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/cronjob.TaskPayload"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/job/list": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Tasks List"
+                ],
+                "summary": "查詢已註冊排程任務清單 By Group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group_name",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":[],\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/cronjob.TaskPayload"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/job/match/list": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Tasks List"
+                ],
+                "summary": "查詢已註冊排程任務清單 By Match",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group_name",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "match by name",
+                        "name": "match",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":[],\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/cronjob.TaskPayload"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/job/pause/{group}/{id}": {
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Update"
+                ],
+                "summary": "暫停排程",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "job_id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/job/query": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Query"
+                ],
+                "summary": "查詢排程註冊數和執行數量",
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":{\"running\":0,\"register\":0},\"errors\":[]}",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/job/query/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Query"
+                ],
+                "summary": "查詢排程狀態",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job_id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/job/replace": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Update"
+                ],
+                "summary": "更新排程任務",
+                "parameters": [
+                    {
+                        "description": "排程資料",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cronjob.TaskPayloadReq"
+                        }
+                    }
                 ],
-                "summary": "排程功能停止",
                 "responses": {
                     "200": {
                         "description": "{\"success\":true,\"errors\":[]}",
@@ -689,128 +1127,1566 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "cronjob.TaskPayload": {
+        },
+        "/api/job/run/{group}/{id}": {
+            "post": {
+                "description": "依註冊的任務內容立即執行一次, 走一般的鎖、overlap policy 及執行紀錄, 不影響原本的排程; 可覆蓋本次的 nsq 訊息或 http 請求內容; 最多等待 30 秒, 超過時回傳的 id 及 outcome 為空值, 執行照常進行, 結果由執行紀錄查詢",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Update"
+                ],
+                "summary": "立即執行排程",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "job_id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "覆蓋內容",
+                        "name": "data",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RunJobRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":{\"trigger\":\"manual\",\"outcome\":\"success\"},\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/history.Record"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/jobs/delete/{group}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Update"
+                ],
+                "summary": "刪除Grop所有註冊任務",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/jobs/delete/{group}/{match}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Update"
+                ],
+                "summary": "刪除註冊任務 By Match",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "match",
+                        "name": "match",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/jobs/export": {
+            "post": {
+                "description": "Export all jobs as a JSON file",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Import/export"
+                ],
+                "summary": "匯出任務 By All",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/jobs/export/{group}": {
+            "post": {
+                "description": "Export all jobs as a JSON file",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Import/export"
+                ],
+                "summary": "匯出任務 By Group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/jobs/export/{group}/{match}": {
+            "post": {
+                "description": "Export all jobs as a JSON file",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Import/export"
+                ],
+                "summary": "匯出任務 By Match",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "group_name",
+                        "name": "group",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "match",
+                        "name": "match",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/jobs/import": {
+            "post": {
+                "description": "Import a file using a form-data request",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Import/export"
+                ],
+                "summary": "匯入任務",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "File to import",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"message\": \"imported successfully\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notify/list": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notify"
+                ],
+                "summary": "查詢通知管道清單",
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":[],\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/notify.Channel"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notify/test/{name}": {
+            "post": {
+                "description": "立即送出一則 test 事件的通知, 不受 dedup 限制",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notify"
+                ],
+                "summary": "測試通知管道",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "通知管道名稱",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notify/{name}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notify"
+                ],
+                "summary": "查詢通知管道",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "通知管道名稱",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":{\"name\":\"oncall\",\"type\":\"slack\"},\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/notify.Channel"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "任務執行失敗、連續失敗達到 threshold 次及失敗後恢復時通知; 同一任務同一事件在 dedup 秒內只通知一次",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notify"
+                ],
+                "summary": "新增或更新通知管道",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "通知管道名稱",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "通知管道內容, name 以路徑為準",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/notify.Channel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "引用此通知管道的群組及任務不再通知",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notify"
+                ],
+                "summary": "刪除通知管道",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "通知管道名稱",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ping": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "確認服務連線",
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":{\"pong\":\"pong\"}, \"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.Pong"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/schedule/preview": {
+            "get": {
+                "description": "以註冊時相同的解析方式計算接下來的執行時間, timestamp 會轉換為一次性任務",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CronJob Query"
+                ],
+                "summary": "預覽排程表達式",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "排程表達式 ex.` + "`" + `0 */5 * * * *` + "`" + ` ` + "`" + `@hourly` + "`" + ` ` + "`" + `1685935821` + "`" + `",
+                        "name": "pattern",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA 時區, 預設為伺服器預設時區",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "筆數, 預設5, 最大100",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":{\"pattern\":\"0 */5 * * * *\",\"description\":\"Every 5 minutes\",\"next\":[]},\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/cronjob.Preview"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/service/cronjob/start": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "排程功能啟動",
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/service/cronjob/stop": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "排程功能停止",
+                "responses": {
+                    "200": {
+                        "description": "{\"success\":true,\"errors\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.SuccessRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/service/leader": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "查詢選主狀態",
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":{\"enabled\":true,\"host_name\":\"dcron-1\",\"is_leader\":true,\"leader\":\"dcron-1\",\"token\":1,\"lease_time\":10},\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/leader.Status"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/service/reconcile": {
+            "post": {
+                "description": "補回遺漏的排程、移除已刪除任務的排程, 清除殘留的 CK_ 及 TIME_; 回傳本節點的結果, 其他節點在背景執行",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "比對 redis 與排程並修正不一致",
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":{\"host_name\":\"dcron-1\",\"added\":[\"500001\"],\"removed\":[],\"entries\":[],\"team_added\":[],\"team_removed\":[],\"locks\":[\"CK_test_job02\"],\"times\":[],\"errors\":[],\"duration_ms\":15},\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/ctl.ReconcileReport"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/service/shard": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "查詢分片狀態",
+                "responses": {
+                    "200": {
+                        "description": "{\"data\":{\"enabled\":true,\"host_name\":\"dcron-1\",\"nodes\":[\"dcron-1\",\"dcron-2\"]},\"errors\":[]}",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.DataRespSchema"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/shard.Status"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "檢查 Redis、NSQ、cron、啟動匯入及 worker pool, 任一項失敗回傳 503",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "健康檢查",
+                "responses": {
+                    "200": {
+                        "description": "{\"status\":\"ok\",\"checks\":[{\"name\":\"redis\",\"status\":\"ok\",\"detail\":\"\",\"duration_ms\":1}]}",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "{\"status\":\"fail\",\"checks\":[{\"name\":\"redis\",\"status\":\"fail\",\"detail\":\"connection refused\",\"duration_ms\":1}]}",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/livez": {
+            "get": {
+                "description": "供 livenessProbe 使用, 只檢查 worker pool 是否仍在運作; 外部相依異常、cron 停止及 worker pool 忙碌都不影響",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "存活檢查",
+                "responses": {
+                    "200": {
+                        "description": "{\"status\":\"ok\",\"checks\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "{\"status\":\"fail\",\"checks\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "執行次數、執行時間、排程延遲、鎖競爭略過、worker pool、Redis 及 NSQ 錯誤、任務數",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "Prometheus 指標",
+                "responses": {
+                    "200": {
+                        "description": "Prometheus text format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "供 readinessProbe 使用, 啟動完成、Redis 及 NSQ 可用、任務已匯入且 worker pool 未飽和才回傳 200",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "就緒檢查",
+                "responses": {
+                    "200": {
+                        "description": "{\"status\":\"ok\",\"checks\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "{\"status\":\"fail\",\"checks\":[]}",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "calendar.Calendar": {
+            "type": "object",
+            "properties": {
+                "dates": {
+                    "description": "整天排除的日期 ` + "`" + `YYYY-MM-DD` + "`" + `",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "2024-01-01",
+                        "2024-02-28"
+                    ]
+                },
+                "description": {
+                    "description": "說明",
+                    "type": "string",
+                    "example": "國定假日"
+                },
+                "name": {
+                    "description": "日曆名稱",
+                    "type": "string",
+                    "example": "holiday"
+                },
+                "timezone": {
+                    "description": "IANA 時區, 空值使用任務的時區",
+                    "type": "string",
+                    "example": "Asia/Taipei"
+                },
+                "weekly": {
+                    "description": "每週排除的時段",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/calendar.Weekly"
+                    }
+                }
+            }
+        },
+        "calendar.Weekly": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "description": "星期, 0 為星期日",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2,
+                        3,
+                        4,
+                        5
+                    ]
+                },
+                "end": {
+                    "description": "結束時間 ` + "`" + `HH:MM` + "`" + `, 不包含; 早於開始時間表示跨日",
+                    "type": "string",
+                    "example": "23:30"
+                },
+                "start": {
+                    "description": "開始時間 ` + "`" + `HH:MM` + "`" + `, 包含",
+                    "type": "string",
+                    "example": "22:00"
+                }
+            }
+        },
+        "cronjob.Dependency": {
+            "type": "object",
+            "properties": {
+                "group_name": {
+                    "description": "上游群組名稱, 空值為同群組",
+                    "type": "string",
+                    "example": "test"
+                },
+                "name": {
+                    "description": "上游排程名稱",
+                    "type": "string",
+                    "example": "job01"
+                },
+                "on": {
+                    "description": "` + "`" + `success` + "`" + ` ` + "`" + `failure` + "`" + ` ` + "`" + `any` + "`" + `, 預設 ` + "`" + `success` + "`" + `",
+                    "type": "string",
+                    "example": "success"
+                }
+            }
+        },
+        "cronjob.Preview": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "description": "表達式說明",
+                    "type": "string",
+                    "example": "Every 5 minutes"
+                },
+                "expression": {
+                    "description": "實際註冊的表達式, timestamp 會轉換為 cron 表達式",
+                    "type": "string",
+                    "example": "0 */5 * * * *"
+                },
+                "next": {
+                    "description": "接下來的執行時間",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "2024-01-01T12:05:00+08:00"
+                    ]
+                },
+                "once": {
+                    "description": "true: timestamp 一次性任務",
+                    "type": "boolean",
+                    "example": false
+                },
+                "pattern": {
+                    "description": "輸入的表達式",
+                    "type": "string",
+                    "example": "0 */5 * * * *"
+                },
+                "timezone": {
+                    "description": "計算使用的時區",
+                    "type": "string",
+                    "example": "Asia/Taipei"
+                }
+            }
+        },
+        "cronjob.TaskPayload": {
+            "type": "object",
+            "properties": {
+                "calendars": {
+                    "description": "排除日曆",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "depends_on": {
+                    "description": "上游任務",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/cronjob.Dependency"
+                    }
+                },
+                "end_at": {
+                    "description": "結束時間, 零值為不限制",
+                    "type": "string"
+                },
+                "end_policy": {
+                    "description": "` + "`" + `finish` + "`" + ` ` + "`" + `delete` + "`" + `",
+                    "type": "string"
+                },
+                "exec_right_now": {
+                    "description": "true: 馬上執行",
+                    "type": "boolean"
+                },
+                "group_name": {
+                    "description": "群組名稱",
+                    "type": "string"
+                },
+                "interval_pattern": {
+                    "description": "支援 ` + "`" + `0 0 * * * *` + "`" + ` ` + "`" + `@hourly` + "`" + ` ` + "`" + `1685935821` + "`" + `",
+                    "type": "string"
+                },
+                "jitter": {
+                    "description": "錯開執行的範圍(秒), 依 job_id 固定偏移",
+                    "type": "integer"
+                },
+                "job_id": {
+                    "description": "排程ID",
+                    "type": "string"
+                },
+                "max_runs": {
+                    "description": "最多執行次數, 0 為不限制",
+                    "type": "integer"
+                },
+                "max_runs_count": {
+                    "description": "` + "`" + `success` + "`" + ` ` + "`" + `attempt` + "`" + `",
+                    "type": "string"
+                },
+                "max_runs_policy": {
+                    "description": "` + "`" + `pause` + "`" + ` ` + "`" + `delete` + "`" + `",
+                    "type": "string"
+                },
+                "memo": {
+                    "description": "備註",
+                    "type": "string"
+                },
+                "misfire_limit": {
+                    "description": "` + "`" + `fire_all` + "`" + ` 最多補跑次數",
+                    "type": "integer"
+                },
+                "misfire_policy": {
+                    "description": "` + "`" + `skip` + "`" + ` ` + "`" + `fire_once` + "`" + ` ` + "`" + `fire_all` + "`" + `",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "排程名稱",
+                    "type": "string"
+                },
+                "next": {
+                    "description": "下次執行時間",
+                    "type": "string"
+                },
+                "notifiers": {
+                    "description": "通知管道",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "nsq_message": {
+                    "description": "nsq回傳的訊息",
+                    "type": "string",
+                    "example": ""
+                },
+                "nsq_topic": {
+                    "description": "type選擇nsq,topic不能為空",
+                    "type": "string"
+                },
+                "overlap_policy": {
+                    "description": "` + "`" + `allow` + "`" + ` ` + "`" + `skip` + "`" + ` ` + "`" + `queue` + "`" + `",
+                    "type": "string"
+                },
+                "prev": {
+                    "description": "上次執行時間",
+                    "type": "string"
+                },
+                "register": {
+                    "description": "註冊時間",
+                    "type": "string"
+                },
+                "request_body": {
+                    "description": "請求內容",
+                    "type": "string"
+                },
+                "request_headers": {
+                    "description": "自訂 header",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "request_method": {
+                    "description": "` + "`" + `GET` + "`" + ` ` + "`" + `POST` + "`" + ` ` + "`" + `PUT` + "`" + ` ` + "`" + `PATCH` + "`" + ` ` + "`" + `DELETE` + "`" + `",
+                    "type": "string"
+                },
+                "request_url": {
+                    "description": "網址",
+                    "type": "string"
+                },
+                "retry": {
+                    "description": "true: http失敗重新執行",
+                    "type": "boolean"
+                },
+                "retry_policy": {
+                    "description": "重試策略",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/retry.Policy"
+                        }
+                    ]
+                },
+                "runs": {
+                    "description": "已執行次數, 只有設定 max_runs 時計算",
+                    "type": "integer"
+                },
+                "start_at": {
+                    "description": "開始時間, 零值為不限制",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "0:暫停 1:執行中 2:已結束",
+                    "type": "integer"
+                },
+                "stop_reason": {
+                    "description": "自動停止的原因",
+                    "type": "string"
+                },
+                "success_rule": {
+                    "description": "http 成功條件",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/httptarget.SuccessRule"
+                        }
+                    ]
+                },
+                "timeout": {
+                    "description": "單次執行逾時(秒)",
+                    "type": "integer"
+                },
+                "timezone": {
+                    "description": "IANA 時區, 空值為伺服器預設時區",
+                    "type": "string"
+                },
+                "trace_parent": {
+                    "description": "註冊時的 traceparent, 執行的 span 以 link 關聯",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "` + "`" + `nsq` + "`" + ` ` + "`" + `http` + "`" + `",
+                    "type": "string"
+                }
+            }
+        },
+        "cronjob.TaskPayloadReq": {
+            "type": "object",
+            "required": [
+                "group_name",
+                "name",
+                "type"
+            ],
+            "properties": {
+                "calendars": {
+                    "description": "排除日曆名稱, 落在任一日曆內的執行會被略過",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "holiday"
+                    ]
+                },
+                "depends_on": {
+                    "description": "上游任務, 任一上游完成且符合條件時觸發",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/cronjob.Dependency"
+                    }
+                },
+                "end_at": {
+                    "description": "結束時間 unix timestamp, 之後移出排程, 0 為不限制",
+                    "type": "integer",
+                    "example": 1688527821
+                },
+                "end_policy": {
+                    "description": "超過結束時間: ` + "`" + `finish` + "`" + ` 標記為已結束 ` + "`" + `delete` + "`" + ` 刪除任務, 預設 ` + "`" + `finish` + "`" + `",
+                    "type": "string",
+                    "example": "finish"
+                },
+                "exec_right_now": {
+                    "description": "true: 馬上執行",
+                    "type": "boolean",
+                    "example": false
+                },
+                "group_name": {
+                    "description": "群組名稱",
+                    "type": "string",
+                    "example": "test"
+                },
+                "interval_pattern": {
+                    "description": "支援 ` + "`" + `0 0 * * * *` + "`" + ` ` + "`" + `0 0 0 L * ?` + "`" + ` ` + "`" + `@hourly` + "`" + ` ` + "`" + `1685935821` + "`" + `, 設定 depends_on 時可為空, 只由上游觸發",
+                    "type": "string",
+                    "example": "0 * * * * *"
+                },
+                "jitter": {
+                    "description": "錯開執行的範圍(秒), 依 job_id 固定延後, 0 使用群組設定",
+                    "type": "integer",
+                    "example": 0
+                },
+                "max_runs": {
+                    "description": "最多執行次數, 達到後依 max_runs_policy 停止, 0 為不限制",
+                    "type": "integer",
+                    "example": 0
+                },
+                "max_runs_count": {
+                    "description": "計算方式: ` + "`" + `success` + "`" + ` 只計算成功 ` + "`" + `attempt` + "`" + ` 每次執行都計算, 預設 ` + "`" + `success` + "`" + `",
+                    "type": "string",
+                    "example": "success"
+                },
+                "max_runs_policy": {
+                    "description": "達到 max_runs: ` + "`" + `pause` + "`" + ` 暫停任務 ` + "`" + `delete` + "`" + ` 刪除任務, 預設 ` + "`" + `pause` + "`" + `",
+                    "type": "string",
+                    "example": "pause"
+                },
+                "misfire_limit": {
+                    "description": "` + "`" + `fire_all` + "`" + ` 最多補跑次數, 預設 10, 上限 100",
+                    "type": "integer",
+                    "example": 10
+                },
+                "misfire_policy": {
+                    "description": "停機期間錯過的執行: ` + "`" + `skip` + "`" + ` 不補跑 ` + "`" + `fire_once` + "`" + ` 補跑一次 ` + "`" + `fire_all` + "`" + ` 依序補跑, 預設 cron 任務 ` + "`" + `skip` + "`" + ` 一次性任務 ` + "`" + `fire_once` + "`" + `",
+                    "type": "string",
+                    "example": "skip"
+                },
+                "name": {
+                    "description": "排程名稱",
+                    "type": "string",
+                    "example": "job01"
+                },
+                "notifiers": {
+                    "description": "通知管道名稱, 與群組的通知管道合併",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "oncall"
+                    ]
+                },
+                "nsq_message": {
+                    "description": "nsq回傳的訊息 ex.{\"game_name\": \"BBLT\",\"draw_mode\":1,\"open_timestamp\": 1686116327,\"close_timestamp\": 1686116387}",
+                    "type": "string",
+                    "example": ""
+                },
+                "nsq_topic": {
+                    "description": "type選擇nsq,topic不能為空",
+                    "type": "string",
+                    "example": ""
+                },
+                "overlap_policy": {
+                    "description": "上一次尚未結束時: ` + "`" + `allow` + "`" + ` 同時執行 ` + "`" + `skip` + "`" + ` 略過 ` + "`" + `queue` + "`" + ` 排隊一次, 預設 ` + "`" + `allow` + "`" + `",
+                    "type": "string",
+                    "example": "allow"
+                },
+                "request_body": {
+                    "description": "請求內容, 依 Content-Type 檢查 json 或 form 格式",
+                    "type": "string",
+                    "example": ""
+                },
+                "request_headers": {
+                    "description": "自訂 header ex.{\"Authorization\": \"Bearer token\"}",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "request_method": {
+                    "description": "` + "`" + `GET` + "`" + ` ` + "`" + `POST` + "`" + ` ` + "`" + `PUT` + "`" + ` ` + "`" + `PATCH` + "`" + ` ` + "`" + `DELETE` + "`" + `, 預設 ` + "`" + `GET` + "`" + `",
+                    "type": "string",
+                    "example": "GET"
+                },
+                "request_url": {
+                    "description": "網址",
+                    "type": "string",
+                    "example": "http://127.0.0.1/api/ping"
+                },
+                "retry": {
+                    "description": "true: http失敗重新執行",
+                    "type": "boolean",
+                    "example": false
+                },
+                "retry_policy": {
+                    "description": "重試策略, 未設定 max_attempts 時 retry=true 為最多 3 次間隔 100ms",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/retry.Policy"
+                        }
+                    ]
+                },
+                "start_at": {
+                    "description": "開始時間 unix timestamp, 之前不執行, 0 為不限制",
+                    "type": "integer",
+                    "example": 1685935821
+                },
+                "success_rule": {
+                    "description": "http 成功條件, 未設定為狀態碼 200",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/httptarget.SuccessRule"
+                        }
+                    ]
+                },
+                "timeout": {
+                    "description": "單次執行逾時(秒), 0 為預設 20 秒",
+                    "type": "integer",
+                    "example": 20
+                },
+                "timezone": {
+                    "description": "IANA 時區 ex.` + "`" + `Europe/London` + "`" + `, 未設定使用群組時區, 再未設定使用伺服器預設時區",
+                    "type": "string",
+                    "example": "Asia/Taipei"
+                },
+                "type": {
+                    "description": "` + "`" + `nsq` + "`" + ` ` + "`" + `http` + "`" + `",
+                    "type": "string",
+                    "example": "http"
+                }
+            }
+        },
+        "ctl.ReconcileReport": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "description": "補回 cron 的 job_id",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "500001"
+                    ]
+                },
+                "duration_ms": {
+                    "description": "對帳時間(毫秒)",
+                    "type": "integer",
+                    "example": 15
+                },
+                "entries": {
+                    "description": "移除沒有對應任務的 cron entry id",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        12
+                    ]
+                },
+                "errors": {
+                    "description": "無法修正的項目",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "500003: invalid pattern"
+                    ]
+                },
+                "host_name": {
+                    "description": "執行對帳的節點",
+                    "type": "string",
+                    "example": "dcron-1"
+                },
+                "locks": {
+                    "description": "刪除的註冊鎖",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "CK_test_job02"
+                    ]
+                },
+                "removed": {
+                    "description": "移出 cron 的 job_id, 已刪除、非啟用或不由本節點負責",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "500002"
+                    ]
+                },
+                "team_added": {
+                    "description": "補回的群組欄位 group_name/name",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "test/job01"
+                    ]
+                },
+                "team_removed": {
+                    "description": "移除指向不存在任務的群組欄位 group_name/name",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "test/job02"
+                    ]
+                },
+                "times": {
+                    "description": "刪除的執行時間紀錄",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "TIME_test_500002"
+                    ]
+                }
+            }
+        },
+        "deadletter.Entry": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "嘗試次數",
+                    "type": "integer"
+                },
+                "code": {
+                    "description": "http 回應狀態碼",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "錯誤訊息",
+                    "type": "string"
+                },
+                "failed_at": {
+                    "description": "失敗時間",
+                    "type": "string"
+                },
+                "group_name": {
+                    "description": "群組名稱",
+                    "type": "string"
+                },
+                "history_id": {
+                    "description": "對應的執行紀錄ID",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "紀錄ID (stream ID)",
+                    "type": "string"
+                },
+                "job_id": {
+                    "description": "排程ID",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "排程名稱",
+                    "type": "string"
+                },
+                "node": {
+                    "description": "執行節點",
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "失敗當下的完整任務內容, replay 依此重新執行",
+                    "type": "object"
+                },
+                "scheduled_at": {
+                    "description": "預定執行時間",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "` + "`" + `nsq` + "`" + ` ` + "`" + `http` + "`" + `",
+                    "type": "string"
+                }
+            }
+        },
+        "deadletter.Page": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "description": "新的在前",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/deadletter.Entry"
+                    }
+                },
+                "next_cursor": {
+                    "description": "下一頁的 cursor, 空值表示沒有下一頁",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.RunJobRequest": {
+            "type": "object",
+            "properties": {
+                "nsq_message": {
+                    "description": "覆蓋 nsq 訊息",
+                    "type": "string",
+                    "example": "{\"game_name\":\"BBLT\"}"
+                },
+                "request_body": {
+                    "description": "覆蓋 http 請求內容",
+                    "type": "string",
+                    "example": "{\"id\":1}"
+                }
+            }
+        },
+        "health.Check": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "description": "說明或錯誤訊息",
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "description": "檢查時間(毫秒)",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "檢查項目",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "` + "`" + `ok` + "`" + ` ` + "`" + `fail` + "`" + `",
+                    "type": "string"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.Check"
+                    }
+                },
+                "status": {
+                    "description": "` + "`" + `ok` + "`" + ` ` + "`" + `fail` + "`" + `",
+                    "type": "string"
+                }
+            }
+        },
+        "history.Attempt": {
             "type": "object",
             "properties": {
-                "exec_right_now": {
-                    "description": "true: 馬上執行",
-                    "type": "boolean"
+                "code": {
+                    "description": "http 回應狀態碼",
+                    "type": "integer"
+                },
+                "duration": {
+                    "description": "執行時間(毫秒)",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "錯誤訊息",
+                    "type": "string"
+                },
+                "started_at": {
+                    "description": "開始時間",
+                    "type": "string"
+                }
+            }
+        },
+        "history.Page": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "description": "下一頁的 cursor, 空值表示沒有下一頁",
+                    "type": "string"
+                },
+                "records": {
+                    "description": "執行紀錄, 新的在前",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/history.Record"
+                    }
+                }
+            }
+        },
+        "history.Record": {
+            "type": "object",
+            "properties": {
+                "attempt_log": {
+                    "description": "每次嘗試的結果",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/history.Attempt"
+                    }
+                },
+                "attempts": {
+                    "description": "嘗試次數",
+                    "type": "integer"
+                },
+                "body": {
+                    "description": "回應內容, 超過長度會截斷",
+                    "type": "string"
+                },
+                "code": {
+                    "description": "http 回應狀態碼",
+                    "type": "integer"
+                },
+                "duration": {
+                    "description": "執行時間(毫秒)",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "錯誤訊息",
+                    "type": "string"
                 },
                 "group_name": {
                     "description": "群組名稱",
                     "type": "string"
                 },
-                "interval_pattern": {
-                    "description": "支援 ` + "`" + `0 0 * * * *` + "`" + ` ` + "`" + `@hourly` + "`" + ` ` + "`" + `1685935821` + "`" + `",
+                "id": {
+                    "description": "紀錄ID (stream ID)",
                     "type": "string"
                 },
                 "job_id": {
                     "description": "排程ID",
                     "type": "string"
                 },
-                "memo": {
-                    "description": "備註",
-                    "type": "string"
-                },
                 "name": {
                     "description": "排程名稱",
                     "type": "string"
                 },
-                "next": {
-                    "description": "下次執行時間",
+                "node": {
+                    "description": "執行節點",
                     "type": "string"
                 },
-                "nsq_message": {
-                    "description": "nsq回傳的訊息",
-                    "type": "string",
-                    "example": ""
-                },
-                "nsq_topic": {
-                    "description": "type選擇nsq,topic不能為空",
+                "outcome": {
+                    "description": "` + "`" + `success` + "`" + ` ` + "`" + `failed` + "`" + ` ` + "`" + `cancelled` + "`" + ` ` + "`" + `skipped` + "`" + `",
                     "type": "string"
                 },
-                "prev": {
-                    "description": "上次執行時間",
+                "scheduled_at": {
+                    "description": "預定執行時間",
                     "type": "string"
                 },
-                "register": {
-                    "description": "註冊時間",
+                "started_at": {
+                    "description": "實際開始時間",
                     "type": "string"
                 },
-                "request_url": {
-                    "description": "網址",
+                "trigger": {
+                    "description": "` + "`" + `schedule` + "`" + ` ` + "`" + `misfire` + "`" + ` ` + "`" + `manual` + "`" + ` ` + "`" + `replay` + "`" + ` ` + "`" + `dependency` + "`" + `",
                     "type": "string"
                 },
-                "retry": {
-                    "description": "true: http失敗重新執行",
-                    "type": "boolean"
-                },
-                "status": {
-                    "description": "1:執行中",
-                    "type": "integer"
-                },
                 "type": {
                     "description": "` + "`" + `nsq` + "`" + ` ` + "`" + `http` + "`" + `",
                     "type": "string"
+                },
+                "upstream": {
+                    "description": "觸發本次執行的上游執行, 只有 ` + "`" + `dependency` + "`" + ` 有值",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/history.Upstream"
+                        }
+                    ]
                 }
             }
         },
-        "cronjob.TaskPayloadReq": {
+        "history.Upstream": {
             "type": "object",
-            "required": [
-                "group_name",
-                "interval_pattern",
-                "name",
-                "type"
-            ],
             "properties": {
-                "exec_right_now": {
-                    "description": "true: 馬上執行",
-                    "type": "boolean",
-                    "example": false
-                },
                 "group_name": {
-                    "description": "群組名稱",
-                    "type": "string",
-                    "example": "test"
-                },
-                "interval_pattern": {
-                    "description": "支援 ` + "`" + `0 0 * * * *` + "`" + ` ` + "`" + `@hourly` + "`" + ` ` + "`" + `1685935821` + "`" + `",
-                    "type": "string",
-                    "example": "0 * * * * *"
-                },
-                "name": {
-                    "description": "排程名稱",
-                    "type": "string",
-                    "example": "job01"
-                },
-                "nsq_message": {
-                    "description": "nsq回傳的訊息 ex.{\"game_name\": \"BBLT\",\"draw_mode\":1,\"open_timestamp\": 1686116327,\"close_timestamp\": 1686116387}",
-                    "type": "string",
-                    "example": ""
+                    "description": "上游群組名稱",
+                    "type": "string"
                 },
-                "nsq_topic": {
-                    "description": "type選擇nsq,topic不能為空",
-                    "type": "string",
-                    "example": ""
+                "history_id": {
+                    "description": "上游執行紀錄ID",
+                    "type": "string"
                 },
-                "request_url": {
-                    "description": "網址",
-                    "type": "string",
-                    "example": "http://127.0.0.1/api/ping"
+                "job_id": {
+                    "description": "上游排程ID",
+                    "type": "string"
                 },
-                "retry": {
-                    "description": "true: http失敗重新執行",
-                    "type": "boolean",
-                    "example": false
+                "name": {
+                    "description": "上游排程名稱",
+                    "type": "string"
                 },
-                "type": {
-                    "description": "` + "`" + `nsq` + "`" + ` ` + "`" + `http` + "`" + `",
-                    "type": "string",
-                    "example": "http"
+                "outcome": {
+                    "description": "上游執行結果",
+                    "type": "string"
                 }
             }
         },
@@ -824,6 +2700,52 @@ const docTemplate = `{
                 }
             }
         },
+        "httpserver.GroupJitter": {
+            "type": "object",
+            "properties": {
+                "group_name": {
+                    "description": "群組名稱",
+                    "type": "string"
+                },
+                "jitter": {
+                    "description": "群組預設 jitter(秒), 0 為未設定",
+                    "type": "integer"
+                }
+            }
+        },
+        "httpserver.GroupNotify": {
+            "type": "object",
+            "properties": {
+                "channels": {
+                    "description": "群組的通知管道",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "group_name": {
+                    "description": "群組名稱",
+                    "type": "string"
+                }
+            }
+        },
+        "httpserver.GroupTimezone": {
+            "type": "object",
+            "properties": {
+                "group_name": {
+                    "description": "群組名稱",
+                    "type": "string"
+                },
+                "is_default": {
+                    "description": "是否為伺服器預設時區",
+                    "type": "boolean"
+                },
+                "timezone": {
+                    "description": "群組時區, 未設定為伺服器預設時區",
+                    "type": "string"
+                }
+            }
+        },
         "httpserver.Pong": {
             "type": "object",
             "properties": {
@@ -843,6 +2765,178 @@ const docTemplate = `{
                     "type": "boolean"
                 }
             }
+        },
+        "httptarget.SuccessRule": {
+            "type": "object",
+            "properties": {
+                "codes": {
+                    "description": "成功的狀態碼, 支援 ` + "`" + `200,201` + "`" + ` ` + "`" + `200-204` + "`" + ` ` + "`" + `2xx` + "`" + `, 預設 ` + "`" + `200` + "`" + `",
+                    "type": "string",
+                    "example": "200-299"
+                },
+                "expect": {
+                    "description": "JSONPath 的值需等於此值, 空值表示值存在且不為 null/false",
+                    "type": "string",
+                    "example": "true"
+                },
+                "json_path": {
+                    "description": "檢查回應內容的 JSONPath, 支援 ` + "`" + `$.a.b` + "`" + ` ` + "`" + `$.list[0].c` + "`" + `",
+                    "type": "string",
+                    "example": "$.ok"
+                },
+                "regex": {
+                    "description": "回應內容需符合的正規表示式",
+                    "type": "string",
+                    "example": ""
+                }
+            }
+        },
+        "leader.Status": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "是否啟用選主模式",
+                    "type": "boolean"
+                },
+                "host_name": {
+                    "description": "本節點名稱",
+                    "type": "string"
+                },
+                "is_leader": {
+                    "description": "本節點是否為 leader",
+                    "type": "boolean"
+                },
+                "leader": {
+                    "description": "目前 leader 節點名稱",
+                    "type": "string"
+                },
+                "lease_time": {
+                    "description": "租約時間(秒)",
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "本節點取得的 fencing token",
+                    "type": "integer"
+                }
+            }
+        },
+        "notify.Channel": {
+            "type": "object",
+            "properties": {
+                "dedup": {
+                    "description": "同一任務同一事件的最短通知間隔(秒), 0 為 300",
+                    "type": "integer",
+                    "example": 300
+                },
+                "events": {
+                    "description": "` + "`" + `failure` + "`" + ` ` + "`" + `consecutive` + "`" + ` ` + "`" + `recovery` + "`" + `, 空值為全部",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "failure",
+                        "consecutive",
+                        "recovery"
+                    ]
+                },
+                "headers": {
+                    "description": "webhook 自訂 header",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "description": "通知管道名稱",
+                    "type": "string",
+                    "example": "oncall"
+                },
+                "template": {
+                    "description": "Go text/template, 空值使用預設訊息",
+                    "type": "string"
+                },
+                "threshold": {
+                    "description": "consecutive 的連續失敗次數, 0 為 3",
+                    "type": "integer",
+                    "example": 3
+                },
+                "to": {
+                    "description": "email 收件人",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "oncall@example.com"
+                    ]
+                },
+                "type": {
+                    "description": "` + "`" + `webhook` + "`" + ` ` + "`" + `slack` + "`" + ` ` + "`" + `email` + "`" + `",
+                    "type": "string",
+                    "example": "slack"
+                },
+                "url": {
+                    "description": "webhook 及 slack 的網址",
+                    "type": "string",
+                    "example": "https://hooks.slack.com/xxx"
+                }
+            }
+        },
+        "retry.Policy": {
+            "type": "object",
+            "properties": {
+                "initial_delay": {
+                    "description": "第一次重試前等待時間 MILLISECOND",
+                    "type": "integer",
+                    "example": 100
+                },
+                "jitter": {
+                    "description": "等待時間隨機浮動比例 0~1",
+                    "type": "number",
+                    "example": 0.2
+                },
+                "max_attempts": {
+                    "description": "最多嘗試次數(含第一次), 上限 10",
+                    "type": "integer",
+                    "example": 3
+                },
+                "max_delay": {
+                    "description": "等待時間上限 MILLISECOND, 0 為不限制",
+                    "type": "integer",
+                    "example": 5000
+                },
+                "multiplier": {
+                    "description": "每次重試等待時間的倍數, 預設 1",
+                    "type": "number",
+                    "example": 2
+                },
+                "retry_on": {
+                    "description": "需重試的 http 狀態碼, 格式同 success_rule codes, 空值表示失敗都重試",
+                    "type": "string",
+                    "example": "429,5xx"
+                }
+            }
+        },
+        "shard.Status": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "是否啟用分片模式",
+                    "type": "boolean"
+                },
+                "host_name": {
+                    "description": "本節點名稱",
+                    "type": "string"
+                },
+                "nodes": {
+                    "description": "目前存活的節點",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
         }
     }
 }`
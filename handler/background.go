@@ -4,6 +4,7 @@ import (
 	"context"
 	"dcron/internal/cronjob"
 	"dcron/internal/ctl"
+	"dcron/internal/shard"
 	"dcron/server"
 	"time"
 )
@@ -15,11 +16,25 @@ func (s *Server) Background(ctx context.Context) {
 
 	time.Sleep(200 * time.Microsecond)
 
+	// 訂閱叢集事件, 需在匯入前完成避免遺漏
+	if err := ctl.EventInit(); err != nil {
+		server.GetServerInstance().GetLogger().Errorf("Cronjob event subscribe error: %v", err)
+	}
+
+	// 分片模式先註冊節點, 匯入時才知道本節點負責哪些任務
+	if err := shard.Join(); err != nil {
+		server.GetServerInstance().GetLogger().Errorf("Cronjob shard join error: %v", err)
+	}
+
 	server.GetServerInstance().GetLogger().Info("Cronjob import jobs start")
 	// 匯入目前cronjob工作
-	jobs, _ := ctl.GetJobsByAll()
+	jobs, _ := ctl.GetJobsByAll(ctx)
 	if len(jobs) > 0 {
 		cronjob.Mgr.ImportJobs(jobs)
 	}
+	cronjob.Mgr.SetImported(true)
 	server.GetServerInstance().GetLogger().Info("Cronjob import jobs end")
+
+	go shard.Run(ctx, ctl.Rebalance)
+	go ctl.RunReconciler(ctx)
 }
@@ -2,14 +2,20 @@ package handler
 
 import (
 	"context"
+	"dcron/internal/calendar"
 	"dcron/internal/cronjob"
 	"dcron/internal/ctl"
+	"dcron/internal/httptarget"
 	"dcron/internal/lib"
+	"dcron/internal/notify"
+	"dcron/internal/retry"
 	"dcron/internal/snowflake"
+	"dcron/internal/tracing"
 	"dcron/server"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -26,15 +32,35 @@ type DataReply struct {
 }
 
 type TaskPayloadRequest struct {
-	GroupName       string `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
-	Name            string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	ExecRightNow    bool   `protobuf:"varint,3,opt,name=exec_right_now,json=execRightNow,proto3" json:"exec_right_now,omitempty"`
-	RequestUrl      string `protobuf:"bytes,4,opt,name=request_url,json=requestUrl,proto3" json:"request_url,omitempty"`
-	Retry           bool   `protobuf:"varint,5,opt,name=retry,proto3" json:"retry,omitempty"`
-	IntervalPattern string `protobuf:"bytes,6,opt,name=interval_pattern,json=intervalPattern,proto3" json:"interval_pattern,omitempty"`
-	Type            string `protobuf:"bytes,7,opt,name=type,proto3" json:"type,omitempty"`
-	NsqTopic        string `protobuf:"bytes,8,opt,name=nsq_topic,json=nsqTopic,proto3" json:"nsq_topic,omitempty"`
-	NsqMessage      string `protobuf:"bytes,9,opt,name=nsq_message,json=nsqMessage,proto3" json:"nsq_message,omitempty"`
+	GroupName       string                  `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	Name            string                  `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	ExecRightNow    bool                    `protobuf:"varint,3,opt,name=exec_right_now,json=execRightNow,proto3" json:"exec_right_now,omitempty"`
+	RequestUrl      string                  `protobuf:"bytes,4,opt,name=request_url,json=requestUrl,proto3" json:"request_url,omitempty"`
+	Retry           bool                    `protobuf:"varint,5,opt,name=retry,proto3" json:"retry,omitempty"`
+	IntervalPattern string                  `protobuf:"bytes,6,opt,name=interval_pattern,json=intervalPattern,proto3" json:"interval_pattern,omitempty"`
+	Type            string                  `protobuf:"bytes,7,opt,name=type,proto3" json:"type,omitempty"`
+	NsqTopic        string                  `protobuf:"bytes,8,opt,name=nsq_topic,json=nsqTopic,proto3" json:"nsq_topic,omitempty"`
+	NsqMessage      string                  `protobuf:"bytes,9,opt,name=nsq_message,json=nsqMessage,proto3" json:"nsq_message,omitempty"`
+	RequestMethod   string                  `protobuf:"bytes,10,opt,name=request_method,json=requestMethod,proto3" json:"request_method,omitempty"`
+	RequestHeaders  map[string]string       `protobuf:"bytes,11,rep,name=request_headers,json=requestHeaders,proto3" json:"request_headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	RequestBody     string                  `protobuf:"bytes,12,opt,name=request_body,json=requestBody,proto3" json:"request_body,omitempty"`
+	SuccessRule     *httptarget.SuccessRule `protobuf:"bytes,13,opt,name=success_rule,json=successRule,proto3" json:"success_rule,omitempty"`
+	RetryPolicy     *retry.Policy           `protobuf:"bytes,14,opt,name=retry_policy,json=retryPolicy,proto3" json:"retry_policy,omitempty"`
+	Timeout         int64                   `protobuf:"varint,15,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	OverlapPolicy   string                  `protobuf:"bytes,16,opt,name=overlap_policy,json=overlapPolicy,proto3" json:"overlap_policy,omitempty"`
+	MisfirePolicy   string                  `protobuf:"bytes,17,opt,name=misfire_policy,json=misfirePolicy,proto3" json:"misfire_policy,omitempty"`
+	MisfireLimit    int                     `protobuf:"varint,18,opt,name=misfire_limit,json=misfireLimit,proto3" json:"misfire_limit,omitempty"`
+	Timezone        string                  `protobuf:"bytes,19,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	DependsOn       []cronjob.Dependency    `protobuf:"bytes,20,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	StartAt         int64                   `protobuf:"varint,21,opt,name=start_at,json=startAt,proto3" json:"start_at,omitempty"`
+	EndAt           int64                   `protobuf:"varint,22,opt,name=end_at,json=endAt,proto3" json:"end_at,omitempty"`
+	EndPolicy       string                  `protobuf:"bytes,23,opt,name=end_policy,json=endPolicy,proto3" json:"end_policy,omitempty"`
+	MaxRuns         int                     `protobuf:"varint,24,opt,name=max_runs,json=maxRuns,proto3" json:"max_runs,omitempty"`
+	MaxRunsCount    string                  `protobuf:"bytes,25,opt,name=max_runs_count,json=maxRunsCount,proto3" json:"max_runs_count,omitempty"`
+	MaxRunsPolicy   string                  `protobuf:"bytes,26,opt,name=max_runs_policy,json=maxRunsPolicy,proto3" json:"max_runs_policy,omitempty"`
+	Calendars       []string                `protobuf:"bytes,27,rep,name=calendars,proto3" json:"calendars,omitempty"`
+	Jitter          int64                   `protobuf:"varint,28,opt,name=jitter,proto3" json:"jitter,omitempty"`
+	Notifiers       []string                `protobuf:"bytes,29,rep,name=notifiers,proto3" json:"notifiers,omitempty"`
 }
 
 func (s *Server) AddJob(ctx context.Context, d1 *TaskPayloadRequest) (d0 *DataReply, err error) {
@@ -44,16 +70,15 @@ func (s *Server) AddJob(ctx context.Context, d1 *TaskPayloadRequest) (d0 *DataRe
 	)
 	d0 = &DataReply{}
 
-	payload, err := s.validateAndBuildPayload(d1)
+	payload, err := s.validateAndBuildPayload(ctx, d1)
 	if err != nil {
 		return d0, err
 	}
 
-	loc, _ := time.LoadLocation("Asia/Taipei")
+	loc := payload.Location()
 	now := time.Now().In(loc)
 
 	jobID := snowflake.GenerateString()
-	traceID := "trace-id-" + jobID
 
 	taskTime, err := decimal.NewFromString(payload.IntervalPattern)
 	if err == nil {
@@ -63,10 +88,11 @@ func (s *Server) AddJob(ctx context.Context, d1 *TaskPayloadRequest) (d0 *DataRe
 	payload.JobID = jobID
 	payload.Status = 1
 	payload.Register = now
+	payload.TraceParent = tracing.TraceParent(ctx)
 
 	logInfo := server.GetServerInstance().GetLogger().WithFields(map[string]interface{}{
 		"func":       "job_add_register",
-		"trace_id":   traceID,
+		"trace_id":   tracing.TraceID(ctx),
 		"group_name": payload.GroupName,
 		"job_name":   payload.Name,
 		"job_id":     payload.JobID,
@@ -76,22 +102,25 @@ func (s *Server) AddJob(ctx context.Context, d1 *TaskPayloadRequest) (d0 *DataRe
 		"run_params": payload.NsqMessage,
 	})
 
-	exists, err := ctl.AcquireLock(payload.GroupName, payload.Name, 0)
+	exists, err := ctl.AcquireLock(ctx, payload.GroupName, payload.Name, 0)
 	if !exists || err != nil {
 		logInfo.Debug("job already registered")
 		err = errors.New("group_name + name has already been registered")
 		return
 	}
 
-	payload.IntervalPattern, err = cronjob.Mgr.Parse(payload.IntervalPattern)
-	if err != nil {
-		ctl.ReleaseLock(payload.GroupName, payload.Name)
-		return
+	// 只由上游觸發的任務沒有排程表達式
+	if !payload.DependencyOnly() {
+		payload.IntervalPattern, err = cronjob.Mgr.Parse(payload.IntervalPattern, loc)
+		if err != nil {
+			ctl.ReleaseLock(ctx, payload.GroupName, payload.Name)
+			return
+		}
 	}
 
-	err = ctl.SetTaskPayload(payload, 0)
+	err = ctl.SetTaskPayload(ctx, payload, 0)
 	if err != nil {
-		ctl.ReleaseLock(payload.GroupName, payload.Name)
+		ctl.ReleaseLock(ctx, payload.GroupName, payload.Name)
 		cronjob.Mgr.DeleteJobMapping(jobID)
 		return
 	}
@@ -102,11 +131,22 @@ func (s *Server) AddJob(ctx context.Context, d1 *TaskPayloadRequest) (d0 *DataRe
 		// 過期馬上執行, 不需進入cronjob
 		_, err = ctl.EventForAddSchedule(payload)
 		if err != nil {
-			ctl.ReleaseLock(payload.GroupName, payload.Name)
+			ctl.ReleaseLock(ctx, payload.GroupName, payload.Name)
 			cronjob.Mgr.DeleteJobMapping(jobID)
 			return
 		}
 
+		// 通知叢集其他節點載入排程
+		err = ctl.BroadcastEvent(cronjob.PubJob{
+			Event:     "add",
+			JobID:     payload.JobID,
+			GroupName: payload.GroupName,
+			Name:      payload.Name,
+		})
+		if err != nil {
+			logInfo.WithField("err", err.Error()).Error("job add broadcast error")
+			err = nil
+		}
 	}
 
 	delay := calculateDelay(taskTime, now, loc)
@@ -116,12 +156,12 @@ func (s *Server) AddJob(ctx context.Context, d1 *TaskPayloadRequest) (d0 *DataRe
 	logInfo.WithField("delay_time", formattedDelay).Debug("job add success")
 
 	if payload.ExecRightNow || execRightNow {
-		payload.Run()
+		payload.RunNow()
 	} else if memoOnce {
 		if delay.Seconds() < 1.3 && delay.Seconds() > 0 {
 			// 修正bug - 執行時間太接近, 任務新增完成後就過了預訂時間
 			time.AfterFunc(delay, func() {
-				payload.Run()
+				payload.RunNow()
 			})
 		}
 	}
@@ -138,18 +178,40 @@ func calculateDelay(taskTime decimal.Decimal, now time.Time, loc *time.Location)
 	return execTime.Sub(now)
 }
 
-func (s *Server) validateAndBuildPayload(d1 *TaskPayloadRequest) (cronjob.TaskPayload, error) {
+func (s *Server) validateAndBuildPayload(ctx context.Context, d1 *TaskPayloadRequest) (cronjob.TaskPayload, error) {
 	payload := cronjob.TaskPayload{
 		GroupName:       d1.GroupName,
 		Name:            d1.Name,
 		ExecRightNow:    d1.ExecRightNow,
 		RequestUrl:      d1.RequestUrl,
+		RequestMethod:   strings.ToUpper(d1.RequestMethod),
+		RequestHeaders:  d1.RequestHeaders,
+		RequestBody:     d1.RequestBody,
 		Retry:           d1.Retry,
-		IntervalPattern: d1.IntervalPattern,
+		Timeout:         d1.Timeout,
+		OverlapPolicy:   strings.ToLower(d1.OverlapPolicy),
+		MisfirePolicy:   strings.ToLower(d1.MisfirePolicy),
+		MisfireLimit:    d1.MisfireLimit,
+		Timezone:        d1.Timezone,
+		IntervalPattern: strings.TrimSpace(d1.IntervalPattern),
+		DependsOn:       d1.DependsOn,
+		EndPolicy:       strings.ToLower(d1.EndPolicy),
+		MaxRuns:         d1.MaxRuns,
+		MaxRunsCount:    strings.ToLower(d1.MaxRunsCount),
+		MaxRunsPolicy:   strings.ToLower(d1.MaxRunsPolicy),
+		Calendars:       d1.Calendars,
+		Jitter:          d1.Jitter,
+		Notifiers:       d1.Notifiers,
 		Type:            strings.ToLower(d1.Type),
 		NsqTopic:        d1.NsqTopic,
 		NsqMessage:      d1.NsqMessage,
 	}
+	if d1.SuccessRule != nil {
+		payload.SuccessRule = *d1.SuccessRule
+	}
+	if d1.RetryPolicy != nil {
+		payload.RetryPolicy = *d1.RetryPolicy
+	}
 
 	if payload.GroupName == "" {
 		return payload, errors.New(ctl.EmptyGroupNameErrMsg)
@@ -167,6 +229,12 @@ func (s *Server) validateAndBuildPayload(d1 *TaskPayloadRequest) (cronjob.TaskPa
 		if _, err := url.ParseRequestURI(payload.RequestUrl); err != nil {
 			return payload, err
 		}
+		if err := validateHttpRequest(&payload); err != nil {
+			return payload, err
+		}
+		if err := payload.SuccessRule.Validate(); err != nil {
+			return payload, err
+		}
 	} else if payload.Type == cronjob.NsqMode {
 		if payload.NsqTopic == "" {
 			return payload, errors.New("nsq topic is empty")
@@ -179,5 +247,128 @@ func (s *Server) validateAndBuildPayload(d1 *TaskPayloadRequest) (cronjob.TaskPa
 		}
 	}
 
+	if err := payload.RetryPolicy.Validate(); err != nil {
+		return payload, err
+	}
+	if payload.Timeout < 0 {
+		return payload, errors.New("timeout must not be negative")
+	}
+	if !cronjob.IsOverlapPolicy(payload.OverlapPolicy) {
+		return payload, fmt.Errorf("overlap policy %s is not supported", payload.OverlapPolicy)
+	}
+	if !cronjob.IsMisfirePolicy(payload.MisfirePolicy) {
+		return payload, fmt.Errorf("misfire policy %s is not supported", payload.MisfirePolicy)
+	}
+	if payload.MisfireLimit < 0 || payload.MisfireLimit > cronjob.MaxMisfireLimit {
+		return payload, fmt.Errorf("misfire limit must be between 0 and %d", cronjob.MaxMisfireLimit)
+	}
+
+	// 未設定時區使用群組時區, 群組未設定則為伺服器預設時區
+	if payload.Timezone == "" {
+		payload.Timezone = ctl.GetGroupTimezone(ctx, payload.GroupName)
+	}
+	if payload.Timezone == "" {
+		payload.Timezone = cronjob.DefaultTimezone()
+	}
+	if _, err := cronjob.LoadLocation(payload.Timezone); err != nil {
+		return payload, err
+	}
+
+	// 有效期間以任務時區輸出
+	loc := payload.Location()
+	if d1.StartAt < 0 || d1.EndAt < 0 {
+		return payload, errors.New("start_at and end_at must not be negative")
+	}
+	if d1.StartAt > 0 {
+		payload.StartAt = time.Unix(d1.StartAt, 0).In(loc)
+	}
+	if d1.EndAt > 0 {
+		payload.EndAt = time.Unix(d1.EndAt, 0).In(loc)
+		if d1.EndAt <= d1.StartAt {
+			return payload, errors.New("end_at must be after start_at")
+		}
+		if payload.EndAt.Before(time.Now()) {
+			return payload, errors.New("end_at has already passed")
+		}
+	}
+	if !cronjob.IsEndPolicy(payload.EndPolicy) {
+		return payload, fmt.Errorf("end policy %s is not supported", payload.EndPolicy)
+	}
+	if payload.MaxRuns < 0 {
+		return payload, errors.New("max runs must not be negative")
+	}
+	if !cronjob.IsMaxRunsCount(payload.MaxRunsCount) {
+		return payload, fmt.Errorf("max runs count %s is not supported", payload.MaxRunsCount)
+	}
+	if !cronjob.IsMaxRunsPolicy(payload.MaxRunsPolicy) {
+		return payload, fmt.Errorf("max runs policy %s is not supported", payload.MaxRunsPolicy)
+	}
+
+	for _, name := range payload.Calendars {
+		if _, err := calendar.Get(ctx, name); err == calendar.ErrNotFound {
+			return payload, fmt.Errorf("calendar %s is not found", name)
+		} else if err != nil {
+			return payload, err
+		}
+	}
+
+	if err := notify.Exists(ctx, payload.Notifiers); err != nil {
+		return payload, err
+	}
+
+	// 未設定 jitter 使用群組設定
+	if payload.Jitter == 0 {
+		payload.Jitter = ctl.GetGroupJitter(ctx, payload.GroupName)
+	}
+	if payload.Jitter < 0 || payload.Jitter > cronjob.MaxJitter {
+		return payload, fmt.Errorf("jitter must be between 0 and %d", cronjob.MaxJitter)
+	}
+
+	if payload.IntervalPattern == "" && len(payload.DependsOn) == 0 {
+		return payload, errors.New("interval pattern is empty")
+	}
+	if err := ctl.ValidateDependencies(ctx, &payload); err != nil {
+		return payload, err
+	}
+
 	return payload, nil
 }
+
+// 檢查 http 任務的 method, header, body
+func validateHttpRequest(payload *cronjob.TaskPayload) error {
+	if payload.RequestMethod == "" {
+		payload.RequestMethod = http.MethodGet
+	}
+	if !httptarget.IsMethod(payload.RequestMethod) {
+		return fmt.Errorf("request method %s is not supported", payload.RequestMethod)
+	}
+
+	contentType := "application/json"
+	for k, v := range payload.RequestHeaders {
+		if strings.TrimSpace(k) == "" {
+			return errors.New("request header name is empty")
+		}
+		if strings.EqualFold(k, "Content-Type") {
+			contentType = strings.ToLower(v)
+		}
+	}
+
+	if payload.RequestBody == "" {
+		return nil
+	}
+	if payload.RequestMethod == http.MethodGet {
+		return errors.New("request body is not allowed for GET")
+	}
+	switch {
+	case strings.Contains(contentType, "json"):
+		if !json.Valid([]byte(payload.RequestBody)) {
+			return errors.New("request body is not json")
+		}
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		if _, err := url.ParseQuery(payload.RequestBody); err != nil {
+			return errors.New("request body is not form")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/ctl"
+	"dcron/internal/history"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 手動執行等待結果的上限, 超過時先回傳, 執行照常進行並寫入執行紀錄
+const runJobWait = 30 * time.Second
+
+// 手動執行時覆蓋的內容, 只影響本次執行
+type RunJobRequest struct {
+	NsqMessage  *string `protobuf:"bytes,1,opt,name=nsq_message,json=nsqMessage,proto3,oneof" json:"nsq_message,omitempty" example:"{\"game_name\":\"BBLT\"}"` // 覆蓋 nsq 訊息
+	RequestBody *string `protobuf:"bytes,2,opt,name=request_body,json=requestBody,proto3,oneof" json:"request_body,omitempty" example:"{\"id\":1}"`            // 覆蓋 http 請求內容
+}
+
+// 立即執行已註冊的任務, 不影響原本的排程
+func (s *Server) RunJob(ctx context.Context, groupName, jobID string, d1 *RunJobRequest) (*history.Record, error) {
+	if groupName == "" {
+		return nil, errors.New(ctl.EmptyGroupNameErrMsg)
+	}
+
+	payload := ctl.GetTaskPayload(ctx, groupName, jobID)
+	if payload.JobID == "" {
+		return nil, errors.New(ctl.JobIDGroupNameErrMsg)
+	}
+
+	if d1 != nil && d1.NsqMessage != nil {
+		if payload.Type != cronjob.NsqMode {
+			return nil, errors.New("nsq message override is only for nsq job")
+		}
+		if err := json.Unmarshal([]byte(*d1.NsqMessage), &map[string]interface{}{}); err != nil {
+			return nil, errors.New("nsq message is not json")
+		}
+		payload.NsqMessage = *d1.NsqMessage
+	}
+	if d1 != nil && d1.RequestBody != nil {
+		if payload.Type != cronjob.HttpMode {
+			return nil, errors.New("request body override is only for http job")
+		}
+		payload.RequestBody = *d1.RequestBody
+		if err := validateHttpRequest(&payload); err != nil {
+			return nil, err
+		}
+	}
+
+	// 執行不受請求取消影響, 最多等待 runJobWait
+	startedAt := time.Now()
+	result := make(chan *history.Record, 1)
+	go func() {
+		result <- payload.RunManual(ctx)
+	}()
+
+	timer := time.NewTimer(runJobWait)
+	defer timer.Stop()
+	select {
+	case rec := <-result:
+		if rec == nil {
+			return nil, errors.New("job is already triggered manually")
+		}
+		return rec, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	// 尚未結束, 回傳不含結果的紀錄, 結果由執行紀錄查詢
+	return &history.Record{
+		JobID:       payload.JobID,
+		GroupName:   payload.GroupName,
+		Name:        payload.Name,
+		Type:        payload.Type,
+		Trigger:     history.TriggerManual,
+		ScheduledAt: startedAt.In(payload.Location()),
+		StartedAt:   startedAt.In(payload.Location()),
+	}, nil
+}
@@ -2,13 +2,24 @@ package httpserver
 
 import (
 	"dcron/handler"
+	"dcron/internal/calendar"
 	"dcron/internal/cronjob"
 	"dcron/internal/ctl"
+	"dcron/internal/deadletter"
+	"dcron/internal/health"
+	"dcron/internal/history"
+	"dcron/internal/leader"
+	"dcron/internal/metrics"
+	"dcron/internal/notify"
+	"dcron/internal/shard"
 	"dcron/server"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/robfig/cron/v3"
@@ -35,11 +46,151 @@ func Ping(c *gin.Context) {
 // @Success 200 {object} DataRespSchema{data=[]string} "{"data":["test"],"errors":[]}"
 // @Router  /api/group/list [get]
 func ListGroup(c *gin.Context) {
-	records, _ := ctl.FetchGroupList()
+	records, _ := ctl.FetchGroupList(c.Request.Context())
 
 	c.Data(200, jsonContentType, DataResp(records))
 }
 
+// @Summary 查詢群組時區
+// @Tags 	CronJob Query
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Success 200 {object} DataRespSchema{data=GroupTimezone} "{"data":{"group_name":"test","timezone":"Asia/Taipei","is_default":true},"errors":[]}"
+// @Router  /api/group/timezone/{group} [get]
+func GetGroupTimezone(c *gin.Context) {
+	groupName := c.Param("group")
+
+	data := GroupTimezone{
+		GroupName: groupName,
+		Timezone:  ctl.GetGroupTimezone(c.Request.Context(), groupName),
+	}
+	if data.Timezone == "" {
+		data.Timezone = cronjob.DefaultTimezone()
+		data.IsDefault = true
+	}
+
+	c.Data(200, jsonContentType, DataResp(data))
+}
+
+// @Summary 設定群組時區
+// @Description 群組內未設定 timezone 的新任務使用此時區, 已註冊的任務不受影響; 空值為清除
+// @Tags 	CronJob Update
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Param 	timezone query string false "IANA 時區 ex.Europe/London"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/group/timezone/{group} [put]
+func SetGroupTimezone(c *gin.Context) {
+	groupName := c.Param("group")
+	timezone := c.Query("timezone")
+
+	if groupName == "" {
+		c.JSON(200, ErrorResponse(ctl.EmptyGroupNameErrMsg))
+		return
+	}
+	if _, err := cronjob.LoadLocation(timezone); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	if err := ctl.SetGroupTimezone(writeContext(c), groupName, timezone); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
+// @Summary 查詢群組 jitter
+// @Tags 	CronJob Query
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Success 200 {object} DataRespSchema{data=GroupJitter} "{"data":{"group_name":"test","jitter":60},"errors":[]}"
+// @Router  /api/group/jitter/{group} [get]
+func GetGroupJitter(c *gin.Context) {
+	groupName := c.Param("group")
+
+	data := GroupJitter{
+		GroupName: groupName,
+		Jitter:    ctl.GetGroupJitter(c.Request.Context(), groupName),
+	}
+
+	c.Data(200, jsonContentType, DataResp(data))
+}
+
+// @Summary 設定群組 jitter
+// @Description 群組內未設定 jitter 的新任務依 job_id 固定延後 0 ~ jitter-1 秒, 已註冊的任務不受影響; 0 為清除
+// @Tags 	CronJob Update
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Param 	jitter query int false "秒, 上限 3600"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/group/jitter/{group} [put]
+func SetGroupJitter(c *gin.Context) {
+	groupName := c.Param("group")
+
+	if groupName == "" {
+		c.JSON(200, ErrorResponse(ctl.EmptyGroupNameErrMsg))
+		return
+	}
+
+	var jitter int64
+	if v := c.Query("jitter"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(200, ErrorResponse(ctl.ParameterErrorMsg))
+			return
+		}
+		jitter = n
+	}
+	if jitter < 0 || jitter > cronjob.MaxJitter {
+		c.JSON(200, ErrorResponse(fmt.Sprintf("jitter must be between 0 and %d", cronjob.MaxJitter)))
+		return
+	}
+
+	if err := ctl.SetGroupJitter(writeContext(c), groupName, jitter); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
+// @Summary 預覽排程表達式
+// @Description 以註冊時相同的解析方式計算接下來的執行時間, timestamp 會轉換為一次性任務
+// @Tags 	CronJob Query
+// @Produce json
+// @Param 	pattern query string true "排程表達式 ex.`0 */5 * * * *` `@hourly` `1685935821`"
+// @Param 	tz query string false "IANA 時區, 預設為伺服器預設時區"
+// @Param 	count query int false "筆數, 預設5, 最大100"
+// @Success 200 {object} DataRespSchema{data=cronjob.Preview} "{"data":{"pattern":"0 */5 * * * *","description":"Every 5 minutes","next":[]},"errors":[]}"
+// @Router  /api/schedule/preview [get]
+func PreviewSchedule(c *gin.Context) {
+	pattern := c.Query("pattern")
+	if pattern == "" {
+		c.JSON(200, ErrorDataRes("pattern is empty"))
+		return
+	}
+
+	count := 0
+	if v := c.Query("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(200, ErrorDataRes(ctl.ParameterErrorMsg))
+			return
+		}
+		count = n
+	}
+
+	preview, err := cronjob.Mgr.Preview(pattern, c.Query("tz"), count, time.Now())
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(preview))
+}
+
 // @Summary 查詢已註冊排程任務清單 By Group
 // @Tags 	CronJob Tasks List
 // @Produce json
@@ -52,7 +203,7 @@ func ListJobByGroup(c *gin.Context) {
 		c.JSON(200, ErrorDataRes(ctl.EmptyGroupNameErrMsg))
 		return
 	}
-	list, _ := ctl.GetJobsByGroup(groupName)
+	list, _ := ctl.GetJobsByGroup(c.Request.Context(), groupName)
 
 	c.Data(200, jsonContentType, DataResp(list))
 }
@@ -75,7 +226,7 @@ func ListJobByMatch(c *gin.Context) {
 		c.JSON(200, ErrorDataRes(ctl.EmptyMatchErrMsg))
 		return
 	}
-	list, _ := ctl.GetJobsByMatch(groupName, match)
+	list, _ := ctl.GetJobsByMatch(c.Request.Context(), groupName, match)
 
 	c.Data(200, jsonContentType, DataResp(list))
 }
@@ -98,7 +249,7 @@ func ListJobByGame(c *gin.Context) {
 		c.JSON(200, ErrorDataRes(ctl.EmptyGameTypeErrMsg))
 		return
 	}
-	list, _ := ctl.GetJobsByGame(groupName, game_type)
+	list, _ := ctl.GetJobsByGame(c.Request.Context(), groupName, game_type)
 
 	c.Data(200, jsonContentType, DataResp(list))
 }
@@ -121,7 +272,7 @@ func JobInfo(c *gin.Context) {
 		c.JSON(200, ErrorDataRes(ctl.EmptyJobIDErrorMsg))
 		return
 	}
-	payload := ctl.GetTaskPayload(groupName, jobID)
+	payload := ctl.GetTaskPayload(c.Request.Context(), groupName, jobID)
 	if payload.JobID == "" {
 		c.JSON(200, ErrorDataRes("No information found"))
 		return
@@ -130,6 +281,385 @@ func JobInfo(c *gin.Context) {
 	c.Data(200, jsonContentType, DataResp(payload))
 }
 
+// 解析分頁查詢參數 start end limit cursor, 執行紀錄及 dead letter 共用
+func parsePageQuery(c *gin.Context) (pageQuery, error) {
+	var q pageQuery
+
+	if v := c.Query("start"); v != "" {
+		start, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return q, err
+		}
+		q.Start = time.Unix(start, 0)
+	}
+	if v := c.Query("end"); v != "" {
+		end, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return q, err
+		}
+		// 結束時間包含該秒內的紀錄
+		q.End = time.Unix(end, int64(time.Second-1))
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = limit
+	}
+	q.Cursor = c.Query("cursor")
+
+	return q, nil
+}
+
+// @Summary 查詢排程執行紀錄
+// @Tags 	CronJob Query
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Param 	id path string true "job_id"
+// @Param 	start query int false "預定執行時間(scheduled_at)起始 unix timestamp"
+// @Param 	end query int false "預定執行時間(scheduled_at)結束 unix timestamp, 包含該秒"
+// @Param 	limit query int false "每頁筆數, 預設20, 最大100"
+// @Param 	cursor query string false "上一頁回傳的 next_cursor"
+// @Success 200 {object} DataRespSchema{data=history.Page} "{"data":{"records":[],"next_cursor":""},"errors":[]}"
+// @Router  /api/job/history/{group}/{id} [get]
+func JobHistory(c *gin.Context) {
+	groupName := c.Param("group")
+	jobID := c.Param("id")
+
+	q, err := parsePageQuery(c)
+	if err != nil {
+		c.JSON(200, ErrorDataRes(ctl.ParameterErrorMsg))
+		return
+	}
+
+	page, err := history.Find(c.Request.Context(), groupName, jobID, history.Query(q))
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(page))
+}
+
+// @Summary 查詢群組的失敗紀錄 (dead letter)
+// @Tags 	DeadLetter
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Param 	start query int false "紀錄寫入(失敗)時間起始 unix timestamp, 不是 scheduled_at"
+// @Param 	end query int false "紀錄寫入(失敗)時間結束 unix timestamp, 包含該秒, 不是 scheduled_at"
+// @Param 	limit query int false "每頁筆數, 預設20, 最大100"
+// @Param 	cursor query string false "上一頁回傳的 next_cursor"
+// @Success 200 {object} DataRespSchema{data=deadletter.Page} "{"data":{"entries":[],"next_cursor":""},"errors":[]}"
+// @Router  /api/deadletter/{group} [get]
+func ListDeadLetter(c *gin.Context) {
+	groupName := c.Param("group")
+
+	q, err := parsePageQuery(c)
+	if err != nil {
+		c.JSON(200, ErrorDataRes(ctl.ParameterErrorMsg))
+		return
+	}
+
+	page, err := deadletter.Find(c.Request.Context(), groupName, deadletter.Query(q))
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(page))
+}
+
+// @Summary 查詢單筆失敗紀錄 (dead letter)
+// @Tags 	DeadLetter
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Param 	id path string true "dead letter id"
+// @Success 200 {object} DataRespSchema{data=deadletter.Entry} "{"data":{},"errors":[]}"
+// @Router  /api/deadletter/{group}/{id} [get]
+func GetDeadLetter(c *gin.Context) {
+	entry, err := deadletter.Get(c.Request.Context(), c.Param("group"), c.Param("id"))
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(entry))
+}
+
+// @Summary 重新執行失敗紀錄 (dead letter)
+// @Description 依失敗當下保存的任務內容重新執行一次(含重試策略), 成功後移除該筆紀錄
+// @Tags 	DeadLetter
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Param 	id path string true "dead letter id"
+// @Success 200 {object} DataRespSchema{data=history.Record} "{"data":{"outcome":"success"},"errors":[]}"
+// @Router  /api/deadletter/replay/{group}/{id} [post]
+func ReplayDeadLetter(c *gin.Context) {
+	rec, err := ctl.ReplayDeadLetter(writeContext(c), c.Param("group"), c.Param("id"))
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(rec))
+}
+
+// @Summary 刪除單筆失敗紀錄 (dead letter)
+// @Tags 	DeadLetter
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Param 	id path string true "dead letter id"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/deadletter/{group}/{id} [delete]
+func DeleteDeadLetter(c *gin.Context) {
+	if err := deadletter.Delete(writeContext(c), c.Param("group"), c.Param("id")); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
+// @Summary 清除群組所有失敗紀錄 (dead letter)
+// @Tags 	DeadLetter
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/deadletter/{group} [delete]
+func PurgeDeadLetter(c *gin.Context) {
+	if err := deadletter.Purge(writeContext(c), c.Param("group")); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
+// @Summary 查詢排除日曆清單
+// @Tags 	Calendar
+// @Produce json
+// @Success 200 {object} DataRespSchema{data=[]calendar.Calendar} "{"data":[],"errors":[]}"
+// @Router  /api/calendar/list [get]
+func ListCalendar(c *gin.Context) {
+	calendars, err := calendar.List(c.Request.Context())
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(calendars))
+}
+
+// @Summary 查詢排除日曆
+// @Tags 	Calendar
+// @Produce json
+// @Param 	name path string true "日曆名稱"
+// @Success 200 {object} DataRespSchema{data=calendar.Calendar} "{"data":{"name":"holiday","dates":["2024-01-01"]},"errors":[]}"
+// @Router  /api/calendar/{name} [get]
+func GetCalendar(c *gin.Context) {
+	cal, err := calendar.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(cal))
+}
+
+// @Summary 新增或更新排除日曆
+// @Description 引用此日曆的任務在日期或每週時段內的執行會被略過, 執行紀錄的 outcome 為 skipped; 更新後立即生效
+// @Tags 	Calendar
+// @Accept 	json
+// @Produce json
+// @Param 	name path string true "日曆名稱"
+// @Param 	data body calendar.Calendar true "日曆內容, name 以路徑為準"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/calendar/{name} [put]
+func SaveCalendar(c *gin.Context) {
+	var cal calendar.Calendar
+
+	data, _ := c.GetRawData()
+	if err := json.Unmarshal(data, &cal); err != nil {
+		c.JSON(200, ErrorResponse(ctl.ParameterErrorMsg))
+		return
+	}
+	cal.Name = c.Param("name")
+
+	if err := calendar.Save(c.Request.Context(), cal); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
+// @Summary 刪除排除日曆
+// @Description 引用此日曆的任務不再排除
+// @Tags 	Calendar
+// @Produce json
+// @Param 	name path string true "日曆名稱"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/calendar/{name} [delete]
+func DeleteCalendar(c *gin.Context) {
+	if err := calendar.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
+// @Summary 查詢通知管道清單
+// @Tags 	Notify
+// @Produce json
+// @Success 200 {object} DataRespSchema{data=[]notify.Channel} "{"data":[],"errors":[]}"
+// @Router  /api/notify/list [get]
+func ListNotify(c *gin.Context) {
+	channels, err := notify.List(c.Request.Context())
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(channels))
+}
+
+// @Summary 查詢通知管道
+// @Tags 	Notify
+// @Produce json
+// @Param 	name path string true "通知管道名稱"
+// @Success 200 {object} DataRespSchema{data=notify.Channel} "{"data":{"name":"oncall","type":"slack"},"errors":[]}"
+// @Router  /api/notify/{name} [get]
+func GetNotify(c *gin.Context) {
+	channel, err := notify.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(channel))
+}
+
+// @Summary 新增或更新通知管道
+// @Description 任務執行失敗、連續失敗達到 threshold 次及失敗後恢復時通知; 同一任務同一事件在 dedup 秒內只通知一次
+// @Tags 	Notify
+// @Accept 	json
+// @Produce json
+// @Param 	name path string true "通知管道名稱"
+// @Param 	data body notify.Channel true "通知管道內容, name 以路徑為準"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/notify/{name} [put]
+func SaveNotify(c *gin.Context) {
+	var channel notify.Channel
+
+	data, _ := c.GetRawData()
+	if err := json.Unmarshal(data, &channel); err != nil {
+		c.JSON(200, ErrorResponse(ctl.ParameterErrorMsg))
+		return
+	}
+	channel.Name = c.Param("name")
+
+	if err := notify.Save(c.Request.Context(), channel); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
+// @Summary 刪除通知管道
+// @Description 引用此通知管道的群組及任務不再通知
+// @Tags 	Notify
+// @Produce json
+// @Param 	name path string true "通知管道名稱"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/notify/{name} [delete]
+func DeleteNotify(c *gin.Context) {
+	if err := notify.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
+// @Summary 測試通知管道
+// @Description 立即送出一則 test 事件的通知, 不受 dedup 限制
+// @Tags 	Notify
+// @Produce json
+// @Param 	name path string true "通知管道名稱"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/notify/test/{name} [post]
+func TestNotify(c *gin.Context) {
+	channel, err := notify.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	ev := notify.Event{
+		Event:   notify.EventTest,
+		Channel: channel.Name,
+		Record: &history.Record{
+			GroupName: "test",
+			Name:      "test",
+			Outcome:   history.OutcomeSuccess,
+			StartedAt: time.Now(),
+		},
+	}
+	if err := notify.Send(channel, ev); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
+// @Summary 查詢群組通知管道
+// @Tags 	Notify
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Success 200 {object} DataRespSchema{data=GroupNotify} "{"data":{"group_name":"test","channels":["oncall"]},"errors":[]}"
+// @Router  /api/group/notify/{group} [get]
+func GetGroupNotify(c *gin.Context) {
+	groupName := c.Param("group")
+
+	data := GroupNotify{
+		GroupName: groupName,
+		Channels:  notify.GroupChannels(c.Request.Context(), groupName),
+	}
+	if data.Channels == nil {
+		data.Channels = make([]string, 0)
+	}
+
+	c.Data(200, jsonContentType, DataResp(data))
+}
+
+// @Summary 設定群組通知管道
+// @Description 群組內所有任務的執行結果都通知這些管道, 與任務的 notifiers 合併; 立即生效, 空值為清除
+// @Tags 	Notify
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Param 	channels query string false "通知管道名稱, 以逗號分隔"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router  /api/group/notify/{group} [put]
+func SetGroupNotify(c *gin.Context) {
+	groupName := c.Param("group")
+
+	if groupName == "" {
+		c.JSON(200, ErrorResponse(ctl.EmptyGroupNameErrMsg))
+		return
+	}
+
+	if err := notify.SetGroupChannels(c.Request.Context(), groupName, notify.Split(c.Query("channels"))); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
 // @Summary 註冊排程任務
 // @Tags 	CronJob Update
 // @Produce json
@@ -148,7 +678,7 @@ func AddJob(c *gin.Context) {
 	}
 
 	handlerServer := &handler.Server{}
-	resp, err := handlerServer.AddJob(c, &p)
+	resp, err := handlerServer.AddJob(writeContext(c), &p)
 	if err != nil {
 		c.JSON(200, ErrorResponse(err.Error()))
 
@@ -176,21 +706,21 @@ func ReplaceJob(c *gin.Context) {
 		return
 	}
 
-	items, _ := ctl.GetJobsByMatch(p.GroupName, p.Name)
+	items, _ := ctl.GetJobsByMatch(c.Request.Context(), p.GroupName, p.Name)
 	if len(items) > 0 && items[0].Name == p.Name {
 		delJob := items[0]
 		// 刪除redis中已註冊的 Job_id
-		ctl.DeleteJobFromRedis(delJob.GroupName, delJob.Name, delJob.JobID)
+		ctl.DeleteJobFromRedis(writeContext(c), delJob.GroupName, delJob.Name, delJob.JobID)
 
 		// 刪除目前排程中的 entry_id
-		ctl.EventHandling(cronjob.PubJob{
+		ctl.PublishEvent(cronjob.PubJob{
 			Event:     "delete",
 			JobID:     delJob.JobID,
 			GroupName: delJob.GroupName,
 		})
 	}
 	handlerServer := &handler.Server{}
-	resp, err := handlerServer.AddJob(c, &p)
+	resp, err := handlerServer.AddJob(writeContext(c), &p)
 	if err != nil {
 		c.JSON(200, ErrorResponse(err.Error()))
 
@@ -209,12 +739,12 @@ func ReplaceJob(c *gin.Context) {
 func DeleteJobs(c *gin.Context) {
 	group := c.Param("group")
 
-	jobIDs, _ := ctl.DeleteGroupJobsFromRedis(group)
+	jobIDs, _ := ctl.DeleteGroupJobsFromRedis(writeContext(c), group)
 
 	for _, jobID := range jobIDs {
 		// 刪除目前排程中的 entry_id
 		// ctl.RemoveJobFromSchedule(jobID)
-		ctl.EventHandling(cronjob.PubJob{
+		ctl.PublishEvent(cronjob.PubJob{
 			Event:     "delete",
 			JobID:     jobID,
 			GroupName: group,
@@ -240,18 +770,18 @@ func DeleteJob(c *gin.Context) {
 		return
 	}
 
-	payload := ctl.GetTaskPayload(groupName, jobID)
+	payload := ctl.GetTaskPayload(c.Request.Context(), groupName, jobID)
 	if payload.JobID == "" {
 		c.JSON(200, ErrorResponse(ctl.JobIDGroupNameErrMsg))
 		return
 	}
 
 	// 刪除redis中已註冊的 Job_id
-	ctl.DeleteJobFromRedis(payload.GroupName, payload.Name, payload.JobID)
+	ctl.DeleteJobFromRedis(writeContext(c), payload.GroupName, payload.Name, payload.JobID)
 
 	// 刪除目前排程中的 entry_id
 	// ctl.RemoveJobFromSchedule(payload.JobID)
-	ctl.EventHandling(cronjob.PubJob{
+	ctl.PublishEvent(cronjob.PubJob{
 		Event:     "delete",
 		JobID:     jobID,
 		GroupName: groupName,
@@ -278,15 +808,15 @@ func DeleteMatchJob(c *gin.Context) {
 		c.JSON(200, ErrorDataRes(ctl.EmptyMatchErrMsg))
 		return
 	}
-	exportedTasks, _ := ctl.GetJobsByMatch(groupName, match)
+	exportedTasks, _ := ctl.GetJobsByMatch(c.Request.Context(), groupName, match)
 
 	for _, payload := range exportedTasks {
 		// 刪除redis中已註冊的 Job_id
-		ctl.DeleteJobFromRedis(payload.GroupName, payload.Name, payload.JobID)
+		ctl.DeleteJobFromRedis(writeContext(c), payload.GroupName, payload.Name, payload.JobID)
 
 		// 刪除目前排程中的 entry_id
 		// ctl.RemoveJobFromSchedule(payload.JobID)
-		ctl.EventHandling(cronjob.PubJob{
+		ctl.PublishEvent(cronjob.PubJob{
 			Event:     "delete",
 			JobID:     payload.JobID,
 			GroupName: groupName,
@@ -312,13 +842,19 @@ func ActiveJob(c *gin.Context) {
 		return
 	}
 
-	payload := ctl.GetTaskPayload(groupName, jobID)
+	payload := ctl.GetTaskPayload(c.Request.Context(), groupName, jobID)
 	if payload.JobID == "" {
 		c.JSON(200, ErrorResponse(ctl.JobIDGroupNameErrMsg))
 		return
 	}
 
-	ctl.EventHandling(cronjob.PubJob{
+	// 達到 max_runs 停止的任務重新計算
+	if err := ctl.ResetStopped(writeContext(c), payload); err != nil {
+		c.JSON(200, ErrorResponse(err.Error()))
+		return
+	}
+
+	ctl.PublishEvent(cronjob.PubJob{
 		Event:     "active",
 		JobID:     jobID,
 		GroupName: groupName,
@@ -343,7 +879,7 @@ func PauseJob(c *gin.Context) {
 		return
 	}
 
-	ctl.EventHandling(cronjob.PubJob{
+	ctl.PublishEvent(cronjob.PubJob{
 		Event:     "pause",
 		JobID:     jobID,
 		GroupName: groupName,
@@ -352,6 +888,68 @@ func PauseJob(c *gin.Context) {
 	c.Data(200, jsonContentType, DataSuccess(true))
 }
 
+// @Summary 立即執行排程
+// @Description 依註冊的任務內容立即執行一次, 走一般的鎖、overlap policy 及執行紀錄, 不影響原本的排程; 可覆蓋本次的 nsq 訊息或 http 請求內容; 最多等待 30 秒, 超過時回傳的 id 及 outcome 為空值, 執行照常進行, 結果由執行紀錄查詢
+// @Tags 	CronJob Update
+// @Accept 	json
+// @Produce json
+// @Param 	group path string true "group_name"
+// @Param 	id path string true "job_id"
+// @Param 	data body handler.RunJobRequest false "覆蓋內容"
+// @Success 200 {object} DataRespSchema{data=history.Record} "{"data":{"trigger":"manual","outcome":"success"},"errors":[]}"
+// @Router /api/job/run/{group}/{id} [post]
+func RunJob(c *gin.Context) {
+	var p handler.RunJobRequest
+
+	if data, _ := c.GetRawData(); len(data) > 0 {
+		if err := json.Unmarshal(data, &p); err != nil {
+			c.JSON(200, ErrorDataRes(ctl.ParameterErrorMsg))
+			return
+		}
+	}
+
+	handlerServer := &handler.Server{}
+	rec, err := handlerServer.RunJob(c.Request.Context(), c.Param("group"), c.Param("id"), &p)
+	if err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+
+	c.Data(200, jsonContentType, DataResp(rec))
+}
+
+// @Summary 取消執行中的排程
+// @Description 通知所有節點取消該排程正在執行的請求, 不影響之後的排程
+// @Tags 	CronJob Update
+// @Produce json
+// @Param group path string true "group_name"
+// @Param id path string true "job_id"
+// @Success 200 {object} SuccessRes "{"success":true,"errors":[]}"
+// @Router /api/job/cancel/{group}/{id} [post]
+func CancelJob(c *gin.Context) {
+	groupName := c.Param("group")
+	jobID := c.Param("id")
+
+	if groupName == "" {
+		c.JSON(200, ErrorResponse(ctl.EmptyGroupNameErrMsg))
+		return
+	}
+
+	payload := ctl.GetTaskPayload(c.Request.Context(), groupName, jobID)
+	if payload.JobID == "" {
+		c.JSON(200, ErrorResponse(ctl.JobIDGroupNameErrMsg))
+		return
+	}
+
+	ctl.PublishEvent(cronjob.PubJob{
+		Event:     "cancel",
+		JobID:     jobID,
+		GroupName: groupName,
+	})
+
+	c.Data(200, jsonContentType, DataSuccess(true))
+}
+
 // @Summary 匯入任務
 // @Description Import a file using a form-data request
 // @Tags 	CronJob Import/export
@@ -388,7 +986,7 @@ func ImportHandler(c *gin.Context) {
 	}
 
 	for _, payload := range jobs {
-		exists, err := ctl.AcquireLock(payload.GroupName, payload.Name, 0)
+		exists, err := ctl.AcquireLock(writeContext(c), payload.GroupName, payload.Name, 0)
 		if !exists || err != nil {
 			server.GetServerInstance().GetLogger().WithFields(map[string]interface{}{
 				"func":       "job_import",
@@ -400,9 +998,9 @@ func ImportHandler(c *gin.Context) {
 			continue
 		}
 
-		err = ctl.SetTaskPayload(payload, 0)
+		err = ctl.SetTaskPayload(writeContext(c), payload, 0)
 		if err != nil {
-			ctl.ReleaseLock(payload.GroupName, payload.Name)
+			ctl.ReleaseLock(writeContext(c), payload.GroupName, payload.Name)
 
 			c.JSON(200, ErrorResponse(err.Error()))
 			return
@@ -421,7 +1019,7 @@ func ImportHandler(c *gin.Context) {
 func ExportGroupHandler(c *gin.Context) {
 	groupName := c.Param("group")
 
-	exportedTasks, _ := ctl.GetJobsByGroup(groupName)
+	exportedTasks, _ := ctl.GetJobsByGroup(c.Request.Context(), groupName)
 
 	ExportCronJob(c, exportedTasks)
 }
@@ -445,7 +1043,7 @@ func ExportMatchHandler(c *gin.Context) {
 		c.JSON(200, ErrorDataRes(ctl.EmptyMatchErrMsg))
 		return
 	}
-	exportedTasks, _ := ctl.GetJobsByMatch(groupName, match)
+	exportedTasks, _ := ctl.GetJobsByMatch(c.Request.Context(), groupName, match)
 
 	ExportCronJob(c, exportedTasks)
 }
@@ -457,7 +1055,7 @@ func ExportMatchHandler(c *gin.Context) {
 // @Success 200 {file} application/json
 // @Router /api/jobs/export [post]
 func ExportAllHandler(c *gin.Context) {
-	exportedTasks, _ := ctl.GetJobsByAll()
+	exportedTasks, _ := ctl.GetJobsByAll(c.Request.Context())
 
 	ExportCronJob(c, exportedTasks)
 }
@@ -492,7 +1090,7 @@ func ExportCronJob(c *gin.Context, exportedTasks interface{}) {
 // @Router  /api/job/query [get]
 func QueryHandler(c *gin.Context) {
 	entries := cronjob.Mgr.Entries()
-	tasks, _ := ctl.GetJobRecords()
+	tasks, _ := ctl.GetJobRecords(c.Request.Context())
 
 	type jobCase struct {
 		Running  int `json:"running"`
@@ -507,6 +1105,70 @@ func QueryHandler(c *gin.Context) {
 	c.Data(200, jsonContentType, DataResp(test))
 }
 
+// @Summary Prometheus 指標
+// @Description 執行次數、執行時間、排程延遲、鎖競爭略過、worker pool、Redis 及 NSQ 錯誤、任務數
+// @Tags 	Service
+// @Produce plain
+// @Success 200 {string} string "Prometheus text format"
+// @Router  /metrics [get]
+func Metrics(c *gin.Context) {
+	worker := server.GetServerInstance().GetWorker()
+	metrics.WorkerQueueLength.Set(float64(worker.JobQueueLen()))
+	metrics.WorkerWorkingJobs.Set(float64(worker.WorkingJobCount()))
+	metrics.WorkerCount.Set(float64(worker.WorkerCount()))
+
+	if registered, active, err := ctl.CountJobs(c.Request.Context()); err == nil {
+		metrics.JobsRegistered.Set(float64(registered))
+		metrics.JobsActive.Set(float64(active))
+	}
+	metrics.JobsScheduled.Set(float64(len(cronjob.Mgr.Entries())))
+
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(200)
+	metrics.Write(c.Writer)
+}
+
+// @Summary 健康檢查
+// @Description 檢查 Redis、NSQ、cron、啟動匯入及 worker pool, 任一項失敗回傳 503
+// @Tags 	Service
+// @Produce json
+// @Success 200 {object} health.Report "{"status":"ok","checks":[{"name":"redis","status":"ok","detail":"","duration_ms":1}]}"
+// @Failure 503 {object} health.Report "{"status":"fail","checks":[{"name":"redis","status":"fail","detail":"connection refused","duration_ms":1}]}"
+// @Router  /healthz [get]
+func Healthz(c *gin.Context) {
+	writeReport(c, health.Health(c.Request.Context()))
+}
+
+// @Summary 就緒檢查
+// @Description 供 readinessProbe 使用, 啟動完成、Redis 及 NSQ 可用、任務已匯入且 worker pool 未飽和才回傳 200
+// @Tags 	Service
+// @Produce json
+// @Success 200 {object} health.Report "{"status":"ok","checks":[]}"
+// @Failure 503 {object} health.Report "{"status":"fail","checks":[]}"
+// @Router  /readyz [get]
+func Readyz(c *gin.Context) {
+	writeReport(c, health.Ready(c.Request.Context()))
+}
+
+// @Summary 存活檢查
+// @Description 供 livenessProbe 使用, 只檢查 worker pool 是否仍在運作; 外部相依異常、cron 停止及 worker pool 忙碌都不影響
+// @Tags 	Service
+// @Produce json
+// @Success 200 {object} health.Report "{"status":"ok","checks":[]}"
+// @Failure 503 {object} health.Report "{"status":"fail","checks":[]}"
+// @Router  /livez [get]
+func Livez(c *gin.Context) {
+	writeReport(c, health.Live(c.Request.Context()))
+}
+
+func writeReport(c *gin.Context, report health.Report) {
+	statusCode := http.StatusOK
+	if report.Status != health.StatusOK {
+		statusCode = http.StatusServiceUnavailable
+	}
+	c.JSON(statusCode, report)
+}
+
 // @Summary 查詢排程狀態
 // @Tags 	CronJob Query
 // @Produce json
@@ -539,8 +1201,12 @@ func QueryJob(c *gin.Context) {
 // @Router  /api/service/cronjob/start [post]
 func StartCronJob(c *gin.Context) {
 
-	// cronjob.Mgr.Start()
-	ctl.EventHandling(cronjob.PubJob{
+	// 記錄在 redis, 重新選主或重啟後維持相同狀態
+	if err := cronjob.SetHalted(writeContext(c), false); err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+	ctl.PublishEvent(cronjob.PubJob{
 		Event: "start",
 	})
 
@@ -554,10 +1220,48 @@ func StartCronJob(c *gin.Context) {
 // @Router  /api/service/cronjob/stop [post]
 func StopCronJob(c *gin.Context) {
 
-	// cronjob.Mgr.Stop()
-	ctl.EventHandling(cronjob.PubJob{
+	// 記錄在 redis, 重新選主或重啟後維持相同狀態
+	if err := cronjob.SetHalted(writeContext(c), true); err != nil {
+		c.JSON(200, ErrorDataRes(err.Error()))
+		return
+	}
+	ctl.PublishEvent(cronjob.PubJob{
 		Event: "stop",
 	})
 
 	c.Data(200, jsonContentType, DataSuccess(true))
 }
+
+// @Summary 查詢選主狀態
+// @Tags 	Service
+// @Produce json
+// @Success 200 {object} DataRespSchema{data=leader.Status} "{"data":{"enabled":true,"host_name":"dcron-1","is_leader":true,"leader":"dcron-1","token":1,"lease_time":10},"errors":[]}"
+// @Router  /api/service/leader [get]
+func LeaderStatus(c *gin.Context) {
+	c.Data(200, jsonContentType, DataResp(leader.GetStatus()))
+}
+
+// @Summary 查詢分片狀態
+// @Tags 	Service
+// @Produce json
+// @Success 200 {object} DataRespSchema{data=shard.Status} "{"data":{"enabled":true,"host_name":"dcron-1","nodes":["dcron-1","dcron-2"]},"errors":[]}"
+// @Router  /api/service/shard [get]
+func ShardStatus(c *gin.Context) {
+	c.Data(200, jsonContentType, DataResp(shard.GetStatus()))
+}
+
+// @Summary 比對 redis 與排程並修正不一致
+// @Description 補回遺漏的排程、移除已刪除任務的排程, 清除殘留的 CK_ 及 TIME_; 回傳本節點的結果, 其他節點在背景執行
+// @Tags 	Service
+// @Produce json
+// @Success 200 {object} DataRespSchema{data=ctl.ReconcileReport} "{"data":{"host_name":"dcron-1","added":["500001"],"removed":[],"entries":[],"team_added":[],"team_removed":[],"locks":["CK_test_job02"],"times":[],"errors":[],"duration_ms":15},"errors":[]}"
+// @Router  /api/service/reconcile [post]
+func ReconcileJobs(c *gin.Context) {
+	report := ctl.Reconcile(writeContext(c))
+
+	if err := ctl.BroadcastEvent(cronjob.PubJob{Event: "reconcile"}); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	c.Data(200, jsonContentType, DataResp(report))
+}
@@ -0,0 +1,43 @@
+package httpserver
+
+import (
+	"context"
+	"dcron/internal/tracing"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API 請求的 span, 呼叫端帶 traceparent 時延續其 trace
+func Trace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := tracing.Extract(c.Request.Context(), c.GetHeader("traceparent"))
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route, tracing.KindServer,
+			tracing.String("http.method", c.Request.Method),
+			tracing.String("http.route", route),
+		)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(tracing.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(tracing.StatusError, http.StatusText(status))
+		} else if len(c.Errors) > 0 {
+			span.SetStatus(tracing.StatusError, c.Errors.String())
+		}
+		span.End()
+	}
+}
+
+// 多步驟寫入使用的 context, 只沿用請求的 span
+// 呼叫端中斷連線時不取消, 避免刪除後未新增或留下未釋放的鎖
+func writeContext(c *gin.Context) context.Context {
+	return tracing.ContextWithSpan(context.Background(), tracing.SpanFromContext(c.Request.Context()))
+}
@@ -3,6 +3,7 @@ package httpserver
 import (
 	"dcron/server"
 	"encoding/json"
+	"time"
 
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
@@ -12,6 +13,31 @@ type Pong struct {
 	Pong string `protobuf:"bytes,1,opt,name=pong,proto3" json:"pong,omitempty"`
 }
 
+type GroupTimezone struct {
+	GroupName string `json:"group_name"` // 群組名稱
+	Timezone  string `json:"timezone"`   // 群組時區, 未設定為伺服器預設時區
+	IsDefault bool   `json:"is_default"` // 是否為伺服器預設時區
+}
+
+type GroupJitter struct {
+	GroupName string `json:"group_name"` // 群組名稱
+	Jitter    int64  `json:"jitter"`     // 群組預設 jitter(秒), 0 為未設定
+}
+
+type GroupNotify struct {
+	GroupName string   `json:"group_name"` // 群組名稱
+	Channels  []string `json:"channels"`   // 群組的通知管道
+}
+
+// 分頁查詢參數, 欄位與 history.Query 及 deadletter.Query 相同
+// 時間範圍依紀錄寫入的時間(stream entry ID)篩選
+type pageQuery struct {
+	Start  time.Time
+	End    time.Time
+	Limit  int64
+	Cursor string
+}
+
 type SuccessRes struct {
 	Success bool          `json:"success"`
 	Errors  []interface{} `json:"errors"`
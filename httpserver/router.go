@@ -13,20 +13,28 @@ const jsonContentType = "application/json; charset=utf-8"
 func InitRouter(ginEngine *gin.Engine) (ginEngineDone *gin.Engine, err error) {
 
 	ginEngine.Use()
-	apiEngine := ginEngine.Group("/api")
+	apiEngine := ginEngine.Group("/api", Trace())
 	apiEngine.GET("/ping", Ping)
 	apiEngine.GET("/group/list", ListGroup)
+	apiEngine.GET("/group/timezone/:group", GetGroupTimezone)
+	apiEngine.GET("/group/jitter/:group", GetGroupJitter)
 	apiEngine.GET("/job/list", ListJobByGroup)
 	apiEngine.GET("/job/match/list", ListJobByMatch)
 	apiEngine.GET("/job/game/list", ListJobByGame)
 	apiEngine.GET("/job/info", JobInfo)
 	apiEngine.GET("/job/query", QueryHandler)
 	apiEngine.GET("/job/query/:id", QueryJob)
+	apiEngine.GET("/job/history/:group/:id", JobHistory)
+	apiEngine.GET("/schedule/preview", PreviewSchedule)
 
+	apiEngine.PUT("/group/timezone/:group", SetGroupTimezone)
+	apiEngine.PUT("/group/jitter/:group", SetGroupJitter)
 	apiEngine.POST("/job/add", AddJob)
 	apiEngine.POST("/job/replace", ReplaceJob)
 	apiEngine.PUT("/job/active/:group/:id", ActiveJob)
 	apiEngine.PUT("/job/pause/:group/:id", PauseJob)
+	apiEngine.POST("/job/run/:group/:id", RunJob)
+	apiEngine.POST("/job/cancel/:group/:id", CancelJob)
 	apiEngine.DELETE("/job/delete/:group/:id", DeleteJob)
 	apiEngine.DELETE("/jobs/delete/:group", DeleteJobs)
 	apiEngine.DELETE("/jobs/delete/:group/:match", DeleteMatchJob)
@@ -36,9 +44,35 @@ func InitRouter(ginEngine *gin.Engine) (ginEngineDone *gin.Engine, err error) {
 	apiEngine.POST("/jobs/export/:group/:match", ExportMatchHandler)
 	apiEngine.POST("/jobs/import", ImportHandler)
 
+	apiEngine.GET("/deadletter/:group", ListDeadLetter)
+	apiEngine.GET("/deadletter/:group/:id", GetDeadLetter)
+	apiEngine.POST("/deadletter/replay/:group/:id", ReplayDeadLetter)
+	apiEngine.DELETE("/deadletter/:group/:id", DeleteDeadLetter)
+	apiEngine.DELETE("/deadletter/:group", PurgeDeadLetter)
+
+	apiEngine.GET("/calendar/list", ListCalendar)
+	apiEngine.GET("/calendar/:name", GetCalendar)
+	apiEngine.PUT("/calendar/:name", SaveCalendar)
+	apiEngine.DELETE("/calendar/:name", DeleteCalendar)
+
+	apiEngine.GET("/notify/list", ListNotify)
+	apiEngine.GET("/notify/:name", GetNotify)
+	apiEngine.PUT("/notify/:name", SaveNotify)
+	apiEngine.DELETE("/notify/:name", DeleteNotify)
+	apiEngine.POST("/notify/test/:name", TestNotify)
+	apiEngine.GET("/group/notify/:group", GetGroupNotify)
+	apiEngine.PUT("/group/notify/:group", SetGroupNotify)
+
 	apiEngine.POST("/service/cronjob/stop", StopCronJob)
 	apiEngine.POST("/service/cronjob/start", StartCronJob)
+	apiEngine.GET("/service/leader", LeaderStatus)
+	apiEngine.GET("/service/shard", ShardStatus)
+	apiEngine.POST("/service/reconcile", ReconcileJobs)
 
+	ginEngine.GET("/metrics", Metrics)
+	ginEngine.GET("/healthz", Healthz)
+	ginEngine.GET("/readyz", Readyz)
+	ginEngine.GET("/livez", Livez)
 	ginEngine.GET("/docs/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 	ginEngineDone = ginEngine
 	return
@@ -0,0 +1,231 @@
+package calendar
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	calendarKey = "CALENDAR"
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+)
+
+var ErrNotFound = errors.New("calendar not found")
+
+/*
+ * 排除日曆, 落在日曆內的執行會被略過
+ * redis資料格式
+ *
+ * key: CALENDAR
+ * ["holiday"] = {"name":"holiday","timezone":"Asia/Taipei","dates":["2024-01-01"],"weekly":[]}
+ */
+type Calendar struct {
+	Name        string   `json:"name" example:"holiday"`                // 日曆名稱
+	Description string   `json:"description" example:"國定假日"`            // 說明
+	Timezone    string   `json:"timezone" example:"Asia/Taipei"`        // IANA 時區, 空值使用任務的時區
+	Dates       []string `json:"dates" example:"2024-01-01,2024-02-28"` // 整天排除的日期 `YYYY-MM-DD`
+	Weekly      []Weekly `json:"weekly"`                                // 每週排除的時段
+}
+
+// 每週排除的時段
+type Weekly struct {
+	Days  []int  `json:"days" example:"1,2,3,4,5"` // 星期, 0 為星期日
+	Start string `json:"start" example:"22:00"`    // 開始時間 `HH:MM`, 包含
+	End   string `json:"end" example:"23:30"`      // 結束時間 `HH:MM`, 不包含; 早於開始時間表示跨日
+}
+
+// 檢查日曆設定
+func (c *Calendar) Validate() error {
+	if c.Name == "" {
+		return errors.New("calendar name is empty")
+	}
+	// 任務以逗號分隔存放日曆名稱
+	if strings.Contains(c.Name, ",") {
+		return errors.New("calendar name must not contain comma")
+	}
+	if _, err := c.location(time.UTC); err != nil {
+		return err
+	}
+	for _, d := range c.Dates {
+		if _, err := time.Parse(dateLayout, d); err != nil {
+			return fmt.Errorf("calendar date %s is invalid", d)
+		}
+	}
+	for _, w := range c.Weekly {
+		if len(w.Days) == 0 {
+			return errors.New("calendar weekly days is empty")
+		}
+		for _, d := range w.Days {
+			if d < 0 || d > 6 {
+				return fmt.Errorf("calendar weekly day %d is invalid", d)
+			}
+		}
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return errors.New("calendar weekly start and end are the same")
+		}
+	}
+	if len(c.Dates) == 0 && len(c.Weekly) == 0 {
+		return errors.New("calendar has no dates or weekly ranges")
+	}
+	return nil
+}
+
+// t 是否落在日曆內, 未設定時區時以 t 的時區判斷
+func (c *Calendar) Contains(t time.Time) bool {
+	loc, err := c.location(t.Location())
+	if err != nil {
+		return false
+	}
+	t = t.In(loc)
+
+	date := t.Format(dateLayout)
+	for _, d := range c.Dates {
+		if d == date {
+			return true
+		}
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := int(t.Weekday())
+	prevDay := (day + 6) % 7
+	for _, w := range c.Weekly {
+		start, _ := parseClock(w.Start)
+		end, _ := parseClock(w.End)
+		if start < end {
+			if hasDay(w.Days, day) && minute >= start && minute < end {
+				return true
+			}
+			continue
+		}
+		// 跨日: 當天開始時間之後, 或前一天開始延續到今天結束時間之前
+		if hasDay(w.Days, day) && minute >= start {
+			return true
+		}
+		if hasDay(w.Days, prevDay) && minute < end {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Calendar) location(def *time.Location) (*time.Location, error) {
+	if c.Timezone == "" {
+		return def, nil
+	}
+	// Local 依主機設定而不同, 叢集內不一致
+	if strings.EqualFold(c.Timezone, "local") {
+		return nil, fmt.Errorf("timezone %s is not supported", c.Timezone)
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone %s is invalid", c.Timezone)
+	}
+	return loc, nil
+}
+
+// HH:MM 轉為當天的分鐘數
+func parseClock(s string) (int, error) {
+	t, err := time.Parse(clockLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("calendar time %s is invalid", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func hasDay(days []int, day int) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// 新增或更新日曆
+func Save(ctx context.Context, c Calendar) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return redisCacher.Conn.WithContext(ctx).HSet(calendarKey, map[string]interface{}{c.Name: string(b)}, 0)
+}
+
+// 取得日曆
+func Get(ctx context.Context, name string) (Calendar, error) {
+	var c Calendar
+
+	data, err := redisCacher.Conn.WithContext(ctx).HGet(calendarKey, name).Result()
+	if redisCacher.IsNil(err) || (err == nil && data == "") {
+		return c, ErrNotFound
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// 所有日曆, 依名稱排序
+func List(ctx context.Context) ([]Calendar, error) {
+	ret := make([]Calendar, 0)
+
+	data, err := redisCacher.Conn.WithContext(ctx).HGetAll(calendarKey)
+	if err != nil {
+		return ret, err
+	}
+	for _, v := range data {
+		var c Calendar
+		if err := json.Unmarshal([]byte(v), &c); err == nil {
+			ret = append(ret, c)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+
+	return ret, nil
+}
+
+// 刪除日曆, 引用的任務不再排除
+func Delete(ctx context.Context, name string) error {
+	if _, err := Get(ctx, name); err == ErrNotFound {
+		return err
+	}
+	return redisCacher.Conn.WithContext(ctx).HDel(calendarKey, name)
+}
+
+// t 落在哪個日曆內, 都不在時回傳空值; 不存在的日曆略過, 無法讀取時回傳錯誤
+func Match(ctx context.Context, names []string, t time.Time) (string, error) {
+	for _, name := range names {
+		c, err := Get(ctx, name)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("calendar %s: %w", name, err)
+		}
+		if c.Contains(t) {
+			return name, nil
+		}
+	}
+	return "", nil
+}
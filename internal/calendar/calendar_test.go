@@ -0,0 +1,127 @@
+package calendar
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cal  Calendar
+		err  string
+	}{
+		{"name", Calendar{Dates: []string{"2024-01-01"}}, "calendar name is empty"},
+		{"comma", Calendar{Name: "a,b", Dates: []string{"2024-01-01"}}, "calendar name must not contain comma"},
+		{"timezone", Calendar{Name: "c", Timezone: "Local", Dates: []string{"2024-01-01"}}, "timezone Local is not supported"},
+		{"date", Calendar{Name: "c", Dates: []string{"2024/01/01"}}, "calendar date 2024/01/01 is invalid"},
+		{"days", Calendar{Name: "c", Weekly: []Weekly{{Start: "22:00", End: "23:00"}}}, "calendar weekly days is empty"},
+		{"day", Calendar{Name: "c", Weekly: []Weekly{{Days: []int{7}, Start: "22:00", End: "23:00"}}}, "calendar weekly day 7 is invalid"},
+		{"clock", Calendar{Name: "c", Weekly: []Weekly{{Days: []int{1}, Start: "24:00", End: "23:00"}}}, "calendar time 24:00 is invalid"},
+		{"same", Calendar{Name: "c", Weekly: []Weekly{{Days: []int{1}, Start: "22:00", End: "22:00"}}}, "calendar weekly start and end are the same"},
+		{"empty", Calendar{Name: "c"}, "calendar has no dates or weekly ranges"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cal.Validate()
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	taipei, _ := time.LoadLocation("Asia/Taipei")
+	london, _ := time.LoadLocation("Europe/London")
+
+	cal := Calendar{
+		Name:  "maintenance",
+		Dates: []string{"2024-01-01"},
+		Weekly: []Weekly{
+			{Days: []int{3}, Start: "02:00", End: "04:00"},
+			{Days: []int{5}, Start: "22:00", End: "01:00"}, // 星期五跨日到星期六
+		},
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"date", time.Date(2024, 1, 1, 12, 0, 0, 0, taipei), true},
+		{"other date", time.Date(2024, 1, 2, 12, 0, 0, 0, taipei), false},
+		{"weekly start", time.Date(2024, 1, 3, 2, 0, 0, 0, taipei), true},
+		{"weekly end", time.Date(2024, 1, 3, 4, 0, 0, 0, taipei), false},
+		{"other day", time.Date(2024, 1, 4, 3, 0, 0, 0, taipei), false},
+		{"overnight", time.Date(2024, 1, 5, 23, 30, 0, 0, taipei), true},
+		{"overnight next day", time.Date(2024, 1, 6, 0, 30, 0, 0, taipei), true},
+		{"overnight ended", time.Date(2024, 1, 6, 1, 0, 0, 0, taipei), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cal.Contains(tt.t))
+		})
+	}
+
+	// 日曆時區優先於任務時區
+	cal.Timezone = "Europe/London"
+	assert.True(t, cal.Contains(time.Date(2024, 1, 1, 8, 0, 0, 0, taipei)))
+	assert.False(t, cal.Contains(time.Date(2024, 1, 1, 7, 0, 0, 0, taipei)))
+	assert.True(t, cal.Contains(time.Date(2024, 1, 3, 2, 30, 0, 0, london)))
+}
+
+func TestStore(t *testing.T) {
+	redisCacher.SetMiniredis()
+
+	holiday := Calendar{Name: "holiday", Dates: []string{"2024-01-01"}}
+	night := Calendar{Name: "night", Weekly: []Weekly{{Days: []int{0, 1, 2, 3, 4, 5, 6}, Start: "23:00", End: "06:00"}}}
+	assert.Nil(t, Save(context.Background(), night))
+	assert.Nil(t, Save(context.Background(), holiday))
+	assert.NotNil(t, Save(context.Background(), Calendar{Name: "broken"}))
+
+	cal, err := Get(context.Background(), "holiday")
+	assert.Nil(t, err)
+	assert.Equal(t, holiday, cal)
+
+	_, err = Get(context.Background(), "missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	list, err := List(context.Background())
+	assert.Nil(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "holiday", list[0].Name)
+		assert.Equal(t, "night", list[1].Name)
+	}
+
+	loc, _ := time.LoadLocation("Asia/Taipei")
+	names := []string{"missing", "holiday", "night"}
+	match := func(at time.Time) string {
+		name, err := Match(context.Background(), names, at)
+		assert.Nil(t, err)
+		return name
+	}
+	assert.Equal(t, "holiday", match(time.Date(2024, 1, 1, 12, 0, 0, 0, loc)))
+	assert.Equal(t, "night", match(time.Date(2024, 1, 2, 23, 0, 0, 0, loc)))
+	assert.Equal(t, "", match(time.Date(2024, 1, 2, 12, 0, 0, 0, loc)))
+
+	assert.Nil(t, Delete(context.Background(), "holiday"))
+	assert.Equal(t, ErrNotFound, Delete(context.Background(), "holiday"))
+	assert.Equal(t, "", match(time.Date(2024, 1, 1, 12, 0, 0, 0, loc)))
+
+	// 無法讀取日曆時回傳錯誤, 不視為不存在
+	assert.Nil(t, redisCacher.Conn.HSet(calendarKey, map[string]interface{}{"broken": "{"}, 0))
+	_, err = Match(context.Background(), []string{"broken"}, time.Now())
+	assert.NotNil(t, err)
+	assert.Nil(t, redisCacher.Conn.Del(calendarKey))
+	assert.Nil(t, redisCacher.Conn.Set(calendarKey, "1", 0))
+	_, err = Get(context.Background(), "holiday")
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrNotFound, err)
+	_, err = Match(context.Background(), names, time.Now())
+	assert.NotNil(t, err)
+}
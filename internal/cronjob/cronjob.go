@@ -3,7 +3,9 @@ package cronjob
 import (
 	"context"
 	"dcron/internal/goworker"
+	"dcron/internal/leader"
 	"dcron/internal/lib"
+	"dcron/internal/shard"
 	"dcron/server"
 	"strconv"
 	"strings"
@@ -20,27 +22,45 @@ const every = "@every "
 var (
 	logger             *logrus.Logger
 	Mgr                *CronManager
-	defaultLocation, _ = time.LoadLocation("Asia/Taipei")
+	defaultLocation, _ = time.LoadLocation(defaultTimezone)
+	legacyLocation, _  = time.LoadLocation(defaultTimezone)
 )
 
 type CronManager struct {
 	jobMap         sync.Map
+	delayed        sync.Map // 等待對齊時間才載入的 @every 任務
+	endTimers      sync.Map // 各任務的結束時間計時器
 	running        bool
 	cron           *cron.Cron
 	mutex          sync.Mutex
 	pingSuccessful bool
-	cronParser     cron.Parser
+	imported       bool
+	cronParser     quartzParser
+	misfires       []misfire
 }
 
 func ConfigInit() {
-	logger = server.GetServerInstance().GetLogger()
+	instance := server.GetServerInstance()
+	logger = instance.GetLogger()
+	env := instance.GetEnv()
+	if grace := env.MisfireGraceTime; grace > 0 {
+		misfireGrace = time.Duration(grace) * time.Second
+	}
+	if env.Timezone != "" {
+		loc, err := time.LoadLocation(env.Timezone)
+		if err != nil {
+			logger.Warnf("TIMEZONE %s is invalid, use %s", env.Timezone, defaultTimezone)
+		} else {
+			defaultLocation = loc
+		}
+	}
 
 	Mgr = NewCronManager()
 }
 
 func NewCronManager() *CronManager {
 	return &CronManager{
-		cronParser: cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor),
+		cronParser: newQuartzParser(),
 	}
 }
 
@@ -49,7 +69,12 @@ func (cm *CronManager) StartInit(ctx context.Context, testing bool) {
 	cm.cron = cron.New(cron.WithSeconds(), cron.WithLocation(defaultLocation), cron.WithParser(cm.cronParser))
 	cm.mutex.Unlock()
 
-	cm.Start()
+	if leader.Enabled() {
+		// 選主模式: 排程照常載入, 只有 leader 啟動 cron
+		go leader.Run(ctx, cm.Resume, cm.Stop)
+	} else {
+		cm.Resume()
+	}
 
 	logger.Info("Cronjob Start...")
 
@@ -79,6 +104,19 @@ func (cm *CronManager) GetPingSuccessful() bool {
 	return cm.pingSuccessful
 }
 
+// 啟動時的任務匯入已完成
+func (cm *CronManager) SetImported(imported bool) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	cm.imported = imported
+}
+
+func (cm *CronManager) GetImported() bool {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	return cm.imported
+}
+
 func (cm *CronManager) ImportJobs(jobs []TaskPayload) {
 	var wg sync.WaitGroup
 	now := time.Now().In(defaultLocation)
@@ -86,7 +124,8 @@ func (cm *CronManager) ImportJobs(jobs []TaskPayload) {
 	worker := server.GetServerInstance().GetWorker()
 
 	for _, job := range jobs {
-		if job.Status == 1 && job.JobID != "" {
+		// 分片模式只載入本節點負責的任務
+		if job.Status == 1 && job.JobID != "" && shard.Owns(job.JobID) {
 			wg.Add(1)
 			jobCopy := job
 			worker.JobQueue(goworker.DoJob(func(_ []interface{}) {
@@ -100,11 +139,30 @@ func (cm *CronManager) ImportJobs(jobs []TaskPayload) {
 }
 
 func (cm *CronManager) processJob(job TaskPayload, now time.Time) {
+	// 停機期間已超過結束時間
+	if job.afterEnd(now) {
+		job.expire()
+		return
+	}
+
+	// 只由上游觸發, 沒有排程也不補跑
+	if job.DependencyOnly() {
+		return
+	}
+
 	if lib.ShouldExecuteNow(job.Memo) {
+		// 一次性任務停機期間已過執行時間
+		if job.MisfirePolicy == MisfireSkip {
+			logger.WithField("job_id", job.JobID).Debug("job cronjob expired")
+			job.runOnce(context.Background())
+			return
+		}
 		job.Run()
 		return
 	}
 
+	cm.queueMisfire(job, cm.missedRuns(job, now))
+
 	if strings.HasPrefix(job.IntervalPattern, every) {
 		duration, err := time.ParseDuration(job.IntervalPattern[len(every):])
 		if err != nil {
@@ -115,7 +173,9 @@ func (cm *CronManager) processJob(job TaskPayload, now time.Time) {
 		timeNext := lib.CalculateNextRunTime(now, job.Next, duration)
 		delay := timeNext.Sub(now)
 		if delay > 0 {
+			cm.delayed.Store(job.JobID, true)
 			time.AfterFunc(delay, func() {
+				cm.delayed.Delete(job.JobID)
 				cm.ImportAddJobs(job)
 			})
 		}
@@ -125,23 +185,33 @@ func (cm *CronManager) processJob(job TaskPayload, now time.Time) {
 }
 
 func (cm *CronManager) ImportAddJobs(payload TaskPayload) {
+	// 延遲載入期間可能已重新分片
+	if !shard.Owns(payload.JobID) || payload.DependencyOnly() {
+		return
+	}
+
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
-	entryID, err := cm.cron.AddJob(payload.IntervalPattern, &payload)
+	// 延遲載入期間可能已被其他事件載入
+	if _, ok := cm.LoadJobMapping(payload.JobID); ok {
+		return
+	}
+	entryID, err := cm.AddTask(payload)
 	if err == nil {
 		// 儲存任務和 Cron Entry 的對應關係
 		cm.StoreJobMapping(payload.JobID, entryID)
 	}
 }
 
-func (cm *CronManager) FormatSchedule(cronSchedule string) string {
+// timestamp 轉為 loc 時區的 cron 表達式
+func (cm *CronManager) FormatSchedule(cronSchedule string, loc *time.Location) string {
 	n, err := decimal.NewFromString(cronSchedule)
 	if err != nil {
 		return cronSchedule
 	}
 
 	// 解析时间戳为时间
-	t := time.Unix(n.IntPart(), 0).In(defaultLocation)
+	t := time.Unix(n.IntPart(), 0).In(loc)
 
 	// 将时间转换为cron格式
 	cronParts := []string{
@@ -157,8 +227,8 @@ func (cm *CronManager) FormatSchedule(cronSchedule string) string {
 	return cronSchedule
 }
 
-func (cm *CronManager) Parse(cronSchedule string) (string, error) {
-	cronSchedule = cm.FormatSchedule(cronSchedule)
+func (cm *CronManager) Parse(cronSchedule string, loc *time.Location) (string, error) {
+	cronSchedule = cm.FormatSchedule(cronSchedule, loc)
 
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
@@ -183,6 +253,7 @@ func (cm *CronManager) Start() {
 	}
 	cm.running = true
 	cm.cron.Start()
+	cm.drainMisfires()
 }
 
 func (cm *CronManager) Stop() {
@@ -196,6 +267,9 @@ func (cm *CronManager) Stop() {
 }
 
 func (cm *CronManager) Remove(entryID cron.EntryID) {
+	if payload, ok := cm.cron.Entry(entryID).Job.(*TaskPayload); ok {
+		cm.stopEndTimer(payload.JobID, entryID)
+	}
 	cm.cron.Remove(entryID)
 }
 
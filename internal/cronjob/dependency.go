@@ -0,0 +1,96 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/history"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 上游任務完成後觸發下游的條件
+const (
+	DependOnSuccess = "success" // 上游成功, 預設
+	DependOnFailure = "failure" // 上游重試後仍失敗
+	DependOnAny     = "any"     // 上游成功或失敗, 略過及取消不觸發
+)
+
+// 同一次上游執行在 lockTTL 秒內只觸發一次下游
+const dependencyLockTTL = 60
+
+// 支援的觸發條件
+var DependConditions = []string{DependOnSuccess, DependOnFailure, DependOnAny}
+
+// 上游任務, 以群組及名稱指定, 上游重新註冊後仍有效
+type Dependency struct {
+	GroupName string `json:"group_name" example:"test"` // 上游群組名稱, 空值為同群組
+	Name      string `json:"name" example:"job01"`      // 上游排程名稱
+	On        string `json:"on" example:"success"`      // `success` `failure` `any`, 預設 `success`
+}
+
+// 上游完成時呼叫, 由 ctl 設定
+var completedHook func(ctx context.Context, j *TaskPayload, rec *history.Record)
+
+// 任務自動停止時呼叫, 由 ctl 設定
+var stoppedHook func(j *TaskPayload)
+
+// 是否為支援的觸發條件, 空值使用預設
+func IsDependCondition(on string) bool {
+	if on == "" {
+		return true
+	}
+	for _, c := range DependConditions {
+		if c == on {
+			return true
+		}
+	}
+	return false
+}
+
+// 上游執行結果是否符合觸發條件
+func (d Dependency) Match(outcome string) bool {
+	switch d.On {
+	case DependOnFailure:
+		return outcome == history.OutcomeFailed
+	case DependOnAny:
+		return outcome == history.OutcomeSuccess || outcome == history.OutcomeFailed
+	default:
+		return outcome == history.OutcomeSuccess
+	}
+}
+
+// 上游的識別名稱 group/name
+func (d Dependency) String() string {
+	return fmt.Sprintf("%s/%s", d.GroupName, d.Name)
+}
+
+// 設定上游完成時的處理, 用來觸發下游任務
+func OnCompleted(fn func(ctx context.Context, j *TaskPayload, rec *history.Record)) {
+	completedHook = fn
+}
+
+// 設定任務自動停止時的處理, 用來通知其他節點移出排程
+func OnStopped(fn func(j *TaskPayload)) {
+	stoppedHook = fn
+}
+
+// 沒有排程表達式, 只由上游觸發, 不註冊到 cron
+func (j *TaskPayload) DependencyOnly() bool {
+	return strings.TrimSpace(j.IntervalPattern) == "" && len(j.DependsOn) > 0
+}
+
+// 上游完成後觸發執行, 同一次上游執行在叢集內只會執行一次; 未執行時回傳 nil
+func (j *TaskPayload) RunTriggered(upstream history.Upstream) *history.Record {
+	return j.runAt(context.Background(), time.Now(), dependencyLockTTL, history.TriggerDependency, &upstream)
+}
+
+// 通知下游任務, 略過及取消的執行不觸發
+func (j *TaskPayload) completed(ctx context.Context, rec *history.Record) {
+	if completedHook == nil || rec.ID == "" {
+		return
+	}
+	if rec.Outcome != history.OutcomeSuccess && rec.Outcome != history.OutcomeFailed {
+		return
+	}
+	completedHook(ctx, j, rec)
+}
@@ -0,0 +1,37 @@
+package cronjob
+
+import (
+	"dcron/internal/history"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyMatch(t *testing.T) {
+	tests := []struct {
+		on      string
+		outcome string
+		want    bool
+	}{
+		{"", history.OutcomeSuccess, true},
+		{"", history.OutcomeFailed, false},
+		{DependOnSuccess, history.OutcomeSuccess, true},
+		{DependOnFailure, history.OutcomeFailed, true},
+		{DependOnFailure, history.OutcomeSuccess, false},
+		{DependOnAny, history.OutcomeSuccess, true},
+		{DependOnAny, history.OutcomeFailed, true},
+		{DependOnAny, history.OutcomeSkipped, false},
+		{DependOnAny, history.OutcomeCancelled, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Dependency{On: tt.on}.Match(tt.outcome), "%s %s", tt.on, tt.outcome)
+	}
+}
+
+func TestDependencyOnly(t *testing.T) {
+	deps := []Dependency{{GroupName: "test", Name: "job01"}}
+
+	assert.True(t, (&TaskPayload{DependsOn: deps}).DependencyOnly())
+	assert.False(t, (&TaskPayload{IntervalPattern: "0 0 * * * *", DependsOn: deps}).DependencyOnly())
+	assert.False(t, (&TaskPayload{}).DependencyOnly())
+}
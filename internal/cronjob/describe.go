@@ -0,0 +1,270 @@
+package cronjob
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 表達式各欄位的說明方式
+type descField struct {
+	unit  string   // 單位
+	names []string // 依值對應的名稱, 空值以數字顯示
+}
+
+var (
+	descSecond = descField{unit: "second"}
+	descMinute = descField{unit: "minute"}
+	descHour   = descField{unit: "hour"}
+	descDom    = descField{unit: "day"}
+	descMonth  = descField{unit: "month", names: []string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}}
+	descDow    = descField{unit: "weekday", names: []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}}
+	descYear   = descField{unit: "year"}
+)
+
+var ordinals = map[string]string{"1": "first", "2": "second", "3": "third", "4": "fourth", "5": "fifth"}
+
+// 內建表達式的說明
+var descriptors = map[string]string{
+	"@yearly":   "At 00:00:00 on January 1",
+	"@annually": "At 00:00:00 on January 1",
+	"@monthly":  "At 00:00:00 on day 1 of the month",
+	"@weekly":   "At 00:00:00 on Sunday",
+	"@daily":    "At 00:00:00 every day",
+	"@midnight": "At 00:00:00 every day",
+	"@hourly":   "At minute 0 of every hour",
+}
+
+// 將 cron 表達式轉為英文說明, ex. `0 30 9 * * 1-5` => At 09:30:00, on Monday through Friday
+func Describe(spec string) (string, error) {
+	spec = strings.TrimSpace(spec)
+	// 時區前綴不影響說明
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		if i := strings.Index(spec, " "); i >= 0 {
+			spec = strings.TrimSpace(spec[i:])
+		}
+	}
+
+	if strings.HasPrefix(spec, every) {
+		return "Every " + strings.TrimSpace(spec[len(every):]), nil
+	}
+	if desc, ok := descriptors[strings.ToLower(spec)]; ok {
+		return desc, nil
+	}
+
+	fields := strings.Fields(spec)
+	// 秒可省略, 省略時為 0
+	if len(fields) == 5 {
+		fields = append([]string{"0"}, fields...)
+	}
+	// 第 7 個欄位為年
+	year := "*"
+	if len(fields) == 7 {
+		year = fields[6]
+		fields = fields[:6]
+	}
+	if len(fields) != 6 {
+		return "", fmt.Errorf("expected 5 to 7 fields, found %d: %s", len(fields), spec)
+	}
+	sec, min, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]
+
+	parts := make([]string, 0, 6)
+	if isNumber(sec) && isNumber(min) && isNumber(hour) {
+		h, _ := strconv.Atoi(hour)
+		m, _ := strconv.Atoi(min)
+		sc, _ := strconv.Atoi(sec)
+		parts = append(parts, fmt.Sprintf("at %02d:%02d:%02d", h, m, sc))
+	} else {
+		if sec != "0" {
+			parts = append(parts, descTime(descSecond, sec, ""))
+		}
+		parts = append(parts, descTime(descMinute, min, sec))
+		parts = append(parts, descTime(descHour, hour, min))
+	}
+
+	domAny, dowAny := isAny(dom), isAny(dow)
+	switch {
+	case !domAny && !dowAny:
+		// 同時設定時符合任一即執行
+		parts = append(parts, descDay(descDom, dom)+" or "+descDay(descDow, dow))
+	case !domAny:
+		parts = append(parts, descDay(descDom, dom))
+	case !dowAny:
+		parts = append(parts, descDay(descDow, dow))
+	}
+	if !isAny(month) {
+		parts = append(parts, descDay(descMonth, month))
+	}
+	if !isAny(year) {
+		parts = append(parts, descDay(descYear, year))
+	}
+
+	desc := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			desc = append(desc, p)
+		}
+	}
+	if len(desc) == 0 {
+		return "Every second", nil
+	}
+	s := strings.Join(desc, ", ")
+	return strings.ToUpper(s[:1]) + s[1:], nil
+}
+
+// 秒、分、時; 下一層欄位為每次或間隔時, 本欄位的每次已隱含其中
+func descTime(f descField, expr, lower string) string {
+	if isAny(expr) {
+		if lower != "" && (isAny(lower) || strings.Contains(lower, "/")) {
+			return ""
+		}
+		return "every " + f.unit
+	}
+	return f.describe(expr, "at ")
+}
+
+// 日、月、星期、年
+func descDay(f descField, expr string) string {
+	prefix := "on "
+	if f.unit == descMonth.unit || f.unit == descYear.unit {
+		prefix = "in "
+	}
+	desc := f.describe(expr, prefix)
+	if f.unit == descDom.unit && !strings.HasPrefix(desc, "every") {
+		desc += " of the month"
+	}
+	return desc
+}
+
+// 說明單一欄位, 逗號分隔的各項以 and 連接
+func (f descField) describe(expr, prefix string) string {
+	items := strings.Split(expr, ",")
+
+	// 全部為單一值或範圍時共用單位
+	plain := true
+	for _, item := range items {
+		if strings.Contains(item, "/") || isAny(item) || f.special(item, "") != "" {
+			plain = false
+		}
+	}
+	if plain {
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			values = append(values, f.value(item))
+		}
+		label := ""
+		if f.names == nil {
+			label = f.unit + " "
+			if len(items) > 1 || strings.Contains(expr, "-") {
+				label = f.unit + "s "
+			}
+		}
+		return prefix + label + joinAnd(values)
+	}
+
+	descs := make([]string, 0, len(items))
+	for _, item := range items {
+		descs = append(descs, f.step(item, prefix))
+	}
+	return joinAnd(descs)
+}
+
+// 間隔, ex. `*/5` `10-40/5` `10/5`
+func (f descField) step(item, prefix string) string {
+	if desc := f.special(item, prefix); desc != "" {
+		return desc
+	}
+
+	base, step := item, ""
+	if i := strings.Index(item, "/"); i >= 0 {
+		base, step = item[:i], item[i+1:]
+	}
+	if step == "" {
+		if isAny(base) {
+			return "every " + f.unit
+		}
+		return f.describe(base, prefix)
+	}
+
+	desc := fmt.Sprintf("every %s %ss", step, f.unit)
+	if step == "1" {
+		desc = "every " + f.unit
+	}
+	switch {
+	case isAny(base):
+	case strings.Contains(base, "-"):
+		desc += " from " + f.value(base)
+	default:
+		desc += " starting at " + f.value(base)
+	}
+	return desc
+}
+
+// Quartz 擴充語法, ex. 日 `L` `LW` `L-3` `15W` 星期 `5L` `5#3`; 非擴充語法回傳空值
+func (f descField) special(item, prefix string) string {
+	item = strings.ToUpper(item)
+	switch f.unit {
+	case descDom.unit:
+		switch {
+		case item == "L":
+			return prefix + "the last day"
+		case item == "LW":
+			return prefix + "the last weekday"
+		case strings.HasPrefix(item, "L-"):
+			return item[2:] + " days before the last day"
+		case strings.HasSuffix(item, "W"):
+			return prefix + "the weekday nearest day " + strings.TrimSuffix(item, "W")
+		}
+	case descDow.unit:
+		switch {
+		case len(item) > 1 && strings.HasSuffix(item, "L"):
+			return prefix + "the last " + f.name(strings.TrimSuffix(item, "L")) + " of the month"
+		case strings.Contains(item, "#"):
+			parts := strings.SplitN(item, "#", 2)
+			return prefix + "the " + ordinals[parts[1]] + " " + f.name(parts[0]) + " of the month"
+		}
+	}
+	return ""
+}
+
+// 單一值或範圍的顯示
+func (f descField) value(v string) string {
+	if i := strings.Index(v, "-"); i >= 0 {
+		return f.name(v[:i]) + " through " + f.name(v[i+1:])
+	}
+	return f.name(v)
+}
+
+func (f descField) name(v string) string {
+	if f.names == nil {
+		return v
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n >= len(f.names) {
+		// 英文縮寫 ex. MON JAN
+		for _, name := range f.names {
+			if len(v) >= 3 && strings.HasPrefix(strings.ToLower(name), strings.ToLower(v)) {
+				return name
+			}
+		}
+		return v
+	}
+	return f.names[n]
+}
+
+func isAny(expr string) bool {
+	return expr == "*" || expr == "?"
+}
+
+func isNumber(expr string) bool {
+	_, err := strconv.Atoi(expr)
+	return err == nil
+}
+
+// a, b and c
+func joinAnd(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
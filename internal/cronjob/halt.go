@@ -0,0 +1,40 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+)
+
+// 操作者停止排程的紀錄, 重新選主或重啟後仍維持停止
+const haltKey = "CRON_HALTED"
+
+// 記錄操作者停止或恢復排程
+func SetHalted(ctx context.Context, halted bool) error {
+	if !halted {
+		return redisCacher.Conn.WithContext(ctx).Del(haltKey)
+	}
+	return redisCacher.Conn.WithContext(ctx).Set(haltKey, 1, 0)
+}
+
+// 排程是否由操作者停止
+func Halted(ctx context.Context) (bool, error) {
+	err := redisCacher.Conn.WithContext(ctx).Get(haltKey).Err()
+	if redisCacher.IsNil(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// 啟動 cron, 操作者已停止排程時維持停止
+// 讀取失敗時照常啟動, 避免 redis 短暫異常讓排程中斷
+func (cm *CronManager) Resume() {
+	halted, err := Halted(context.Background())
+	if err != nil {
+		logger.WithError(err).Warn("Cronjob halt state unavailable, start cron")
+	}
+	if halted {
+		logger.Info("Cronjob halted by operator, keep cron stopped")
+		return
+	}
+	cm.Start()
+}
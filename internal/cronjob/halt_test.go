@@ -0,0 +1,30 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResumeHalted(t *testing.T) {
+	redisCacher.SetMiniredis()
+	ConfigInit()
+	ctx := context.Background()
+
+	// 操作者停止後重啟或重新選主不應啟動 cron
+	assert.Nil(t, SetHalted(ctx, true))
+	Mgr.StartInit(ctx, true)
+	t.Cleanup(Mgr.Stop)
+	assert.False(t, Mgr.GetRunning())
+	Mgr.Resume()
+	assert.False(t, Mgr.GetRunning())
+
+	assert.Nil(t, SetHalted(ctx, false))
+	halted, err := Halted(ctx)
+	assert.Nil(t, err)
+	assert.False(t, halted)
+	Mgr.Resume()
+	assert.True(t, Mgr.GetRunning())
+}
@@ -0,0 +1,56 @@
+package cronjob
+
+import (
+	"dcron/internal/lib"
+	"hash/fnv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// jitter 上限(秒)
+const MaxJitter = 3600
+
+// 將排程固定延後 offset, 同一任務每次都落在相同的偏移
+type jitterSchedule struct {
+	cron.Schedule
+	offset time.Duration
+}
+
+func (s jitterSchedule) Next(t time.Time) time.Time {
+	next := s.Schedule.Next(t.Add(-s.offset))
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(s.offset)
+}
+
+// 依 jobID 計算 [0, window) 秒內的固定偏移
+func JitterOffset(jobID string, window int64) time.Duration {
+	if window <= 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(jobID))
+	return time.Duration(int64(h.Sum32())%window) * time.Second
+}
+
+// 任務的偏移, 一次性任務依指定時間執行不偏移
+func (j *TaskPayload) Offset() time.Duration {
+	if j.Jitter <= 0 || lib.IsMemoOnce(j.Memo) {
+		return 0
+	}
+	return JitterOffset(j.JobID, j.Jitter)
+}
+
+// 解析任務的排程, 設定 jitter 時加上偏移
+func (cm *CronManager) schedule(j *TaskPayload) (cron.Schedule, error) {
+	schedule, err := cm.cronParser.Parse(j.Spec())
+	if err != nil {
+		return nil, err
+	}
+	if offset := j.Offset(); offset > 0 {
+		return jitterSchedule{Schedule: schedule, offset: offset}, nil
+	}
+	return schedule, nil
+}
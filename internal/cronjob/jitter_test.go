@@ -0,0 +1,71 @@
+package cronjob
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitterOffset(t *testing.T) {
+	assert.Equal(t, time.Duration(0), JitterOffset("600001", 0))
+
+	// 同一任務固定偏移
+	assert.Equal(t, JitterOffset("600001", 60), JitterOffset("600001", 60))
+
+	offsets := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		offset := JitterOffset(fmt.Sprintf("6%05d", i), 60)
+		assert.GreaterOrEqual(t, offset, time.Duration(0))
+		assert.Less(t, offset, 60*time.Second)
+		offsets[offset] = true
+	}
+	assert.Greater(t, len(offsets), 30)
+}
+
+func TestJitterSchedule(t *testing.T) {
+	cm := NewCronManager()
+	from := time.Date(2024, 1, 1, 12, 0, 10, 0, defaultLocation)
+
+	job := TaskPayload{JobID: "600001", IntervalPattern: "0 0 * * * *", Timezone: defaultLocation.String(), Jitter: 600}
+	offset := job.Offset()
+	assert.Equal(t, JitterOffset(job.JobID, 600), offset)
+
+	schedule, err := cm.schedule(&job)
+	assert.Nil(t, err)
+	next := schedule.Next(from)
+	want := time.Date(2024, 1, 1, 12, 0, 0, 0, defaultLocation).Add(offset)
+	if !want.After(from) {
+		want = want.Add(time.Hour)
+	}
+	assert.Equal(t, want, next)
+	assert.Equal(t, next.Add(time.Hour), schedule.Next(next))
+
+	// 一次性任務不偏移
+	job.Memo = "1704085380@once"
+	assert.Equal(t, time.Duration(0), job.Offset())
+}
+
+func TestAddTaskJitter(t *testing.T) {
+	job := TaskPayload{
+		JobID:           "600002",
+		GroupName:       "test",
+		Name:            "jitter01",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            TestMode,
+		Jitter:          3600,
+	}
+	setWindowTest(t, job)
+
+	entryID, err := Mgr.AddTask(job)
+	assert.Nil(t, err)
+
+	now := time.Now().In(defaultLocation)
+	year := now.Year()
+	if !time.Date(year, 1, 1, 0, 0, 0, 0, defaultLocation).Add(job.Offset()).After(now) {
+		year++
+	}
+	want := time.Date(year, 1, 1, 0, 0, 0, 0, defaultLocation).Add(job.Offset())
+	assert.Eventually(t, func() bool { return Mgr.Entry(entryID).Next.Equal(want) }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,138 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/history"
+	"dcron/internal/redisCacher"
+	"fmt"
+)
+
+// max_runs 計算的執行
+const (
+	MaxRunsCountSuccess = "success" // 只計算成功的執行, 預設
+	MaxRunsCountAttempt = "attempt" // 計算每次執行, 不論結果, 略過的不計算
+)
+
+// 達到 max_runs 後的處理方式
+const (
+	MaxRunsPause  = "pause"  // 暫停任務, 預設
+	MaxRunsDelete = "delete" // 刪除任務
+)
+
+// 支援的計算方式
+var MaxRunsCounts = []string{MaxRunsCountSuccess, MaxRunsCountAttempt}
+
+// 支援的處理方式
+var MaxRunsPolicies = []string{MaxRunsPause, MaxRunsDelete}
+
+// 是否為支援的計算方式, 空值使用預設
+func IsMaxRunsCount(count string) bool {
+	if count == "" {
+		return true
+	}
+	for _, c := range MaxRunsCounts {
+		if c == count {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否為支援的處理方式, 空值使用預設
+func IsMaxRunsPolicy(policy string) bool {
+	if policy == "" {
+		return true
+	}
+	for _, p := range MaxRunsPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// 預留的執行次數在節點異常時保留的時間(秒)
+const runsPendingTTL = 60 * 60
+
+// 預留一次執行, 已完成及執行中的次數合計不超過 max_runs
+// 回傳 -1: 已達到 max_runs, 0: 剩餘次數都在執行中, 1: 預留成功
+const reserveRunScript = `
+local runs = tonumber(redis.call("GET", KEYS[1]) or "0")
+if runs >= tonumber(ARGV[1]) then
+	return -1
+end
+local pending = tonumber(redis.call("GET", KEYS[2]) or "0")
+if runs + pending >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("INCR", KEYS[2])
+redis.call("EXPIRE", KEYS[2], ARGV[2])
+return 1`
+
+// 釋放預留, ARGV[1] 為 1 時計入執行次數; 回傳已執行次數
+const commitRunScript = `
+if tonumber(redis.call("GET", KEYS[2]) or "0") > 0 then
+	redis.call("DECR", KEYS[2])
+end
+if ARGV[1] == "1" then
+	return redis.call("INCR", KEYS[1])
+end
+return tonumber(redis.call("GET", KEYS[1]) or "0")`
+
+// 叢集共用的執行次數
+func RunsKey(jobID string) string {
+	return fmt.Sprintf("RUNS_%s", jobID)
+}
+
+// 執行中預留的次數
+func RunsPendingKey(jobID string) string {
+	return fmt.Sprintf("RUNS_PENDING_%s", jobID)
+}
+
+// 執行前預留次數, 已達到時停止任務; 剩餘次數都在執行中時略過這次執行
+func (j *TaskPayload) checkMaxRuns() bool {
+	if j.MaxRuns <= 0 {
+		return true
+	}
+	keys := []string{RunsKey(j.JobID), RunsPendingKey(j.JobID)}
+	ret, err := redisCacher.Conn.Eval(reserveRunScript, keys, j.MaxRuns, runsPendingTTL)
+	if err != nil {
+		logger.WithField("job_id", j.JobID).Errorf("job max_runs reserve error: %v", err)
+		return false
+	}
+	switch n, _ := ret.(int64); n {
+	case 1:
+		return true
+	case -1:
+		j.maxRunsReached()
+	default:
+		logger.WithField("job_id", j.JobID).Debug("job max_runs reserved by running executions")
+	}
+	return false
+}
+
+// 執行後釋放預留, 略過的執行不計算; 達到 max_runs 時停止任務
+func (j *TaskPayload) countRun(ctx context.Context, rec *history.Record) {
+	if j.MaxRuns <= 0 {
+		return
+	}
+	counted := rec.Outcome != history.OutcomeSkipped
+	if j.MaxRunsCount != MaxRunsCountAttempt && rec.Outcome != history.OutcomeSuccess {
+		counted = false
+	}
+
+	flag := 0
+	if counted {
+		flag = 1
+	}
+	keys := []string{RunsKey(j.JobID), RunsPendingKey(j.JobID)}
+	ret, err := redisCacher.Conn.WithContext(ctx).Eval(commitRunScript, keys, flag)
+	if n, _ := ret.(int64); err == nil && counted && n >= int64(j.MaxRuns) {
+		j.maxRunsReached()
+	}
+}
+
+func (j *TaskPayload) maxRunsReached() {
+	reason := fmt.Sprintf("max_runs %d reached", j.MaxRuns)
+	j.stop(j.MaxRunsPolicy == MaxRunsDelete, StatusPaused, reason)
+}
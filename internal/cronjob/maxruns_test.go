@@ -0,0 +1,190 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/history"
+	"dcron/internal/redisCacher"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 已執行次數
+func (j *TaskPayload) runs() int64 {
+	n, _ := redisCacher.Conn.Get(RunsKey(j.JobID)).Int64()
+	return n
+}
+
+func TestMaxRunsPause(t *testing.T) {
+	job := TaskPayload{
+		JobID:           "600001",
+		GroupName:       "test",
+		Name:            "maxruns01",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            TestMode,
+		MaxRuns:         2,
+	}
+	taskKey := setWindowTest(t, job)
+	entryID, err := Mgr.AddTask(job)
+	assert.Nil(t, err)
+	Mgr.StoreJobMapping(job.JobID, entryID)
+
+	// 手動執行不計算
+	assert.NotNil(t, job.RunManual(context.Background()))
+	assert.Equal(t, int64(0), job.runs())
+
+	now := time.Now()
+	assert.NotNil(t, job.runAt(context.Background(), now, 5, history.TriggerSchedule, nil))
+	_, ok := Mgr.LoadJobMapping(job.JobID)
+	assert.True(t, ok)
+
+	assert.NotNil(t, job.runAt(context.Background(), now.Add(time.Second), 5, history.TriggerSchedule, nil))
+	assert.Equal(t, int64(2), job.runs())
+
+	_, ok = Mgr.LoadJobMapping(job.JobID)
+	assert.False(t, ok)
+	data, err := redisCacher.Conn.HGetAll(taskKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "0", data["status"])
+	assert.Equal(t, "max_runs 2 reached", data["stop_reason"])
+
+	// 其他節點仍在排程中也不會再執行
+	assert.Nil(t, job.runAt(context.Background(), now.Add(2*time.Second), 5, history.TriggerSchedule, nil))
+}
+
+func TestMaxRunsCount(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	job := TaskPayload{
+		JobID:           "600002",
+		GroupName:       "test",
+		Name:            "maxruns02",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            HttpMode,
+		RequestUrl:      srv.URL,
+		MaxRuns:         1,
+		MaxRunsPolicy:   MaxRunsDelete,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		taskKey := setWindowTest(t, job)
+
+		rec := job.runAt(context.Background(), time.Now(), 5, history.TriggerSchedule, nil)
+		if assert.NotNil(t, rec) {
+			assert.Equal(t, history.OutcomeFailed, rec.Outcome)
+		}
+		// 失敗不計算, 釋放預留的次數
+		assert.Equal(t, int64(0), job.runs())
+		assert.Equal(t, "0", redisCacher.Conn.Get(RunsPendingKey(job.JobID)).Val())
+
+		data, err := redisCacher.Conn.HGetAll(taskKey)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, data)
+	})
+
+	t.Run("attempt", func(t *testing.T) {
+		job.MaxRunsCount = MaxRunsCountAttempt
+		taskKey := setWindowTest(t, job)
+
+		assert.NotNil(t, job.runAt(context.Background(), time.Now(), 5, history.TriggerSchedule, nil))
+
+		data, err := redisCacher.Conn.HGetAll(taskKey)
+		assert.Nil(t, err)
+		assert.Empty(t, data)
+		assert.Equal(t, int64(0), job.runs())
+	})
+}
+
+func TestMaxRunsDeleteOtherNode(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	job := TaskPayload{
+		JobID:           "600003",
+		GroupName:       "test",
+		Name:            "maxruns03",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            HttpMode,
+		RequestUrl:      srv.URL,
+		MaxRuns:         1,
+		MaxRunsPolicy:   MaxRunsDelete,
+	}
+	taskKey := setWindowTest(t, job)
+
+	stopped := make([]string, 0)
+	OnStopped(func(j *TaskPayload) { stopped = append(stopped, j.JobID) })
+	t.Cleanup(func() { OnStopped(nil) })
+
+	// 節點 A 執行後達到 max_runs 刪除任務
+	now := time.Now()
+	assert.NotNil(t, job.runAt(context.Background(), now, 5, history.TriggerSchedule, nil))
+	assert.Equal(t, []string{job.JobID}, stopped)
+
+	// 節點 B 沒收到事件, 下一次觸發時仍有排程
+	entryID, err := Mgr.AddTask(job)
+	assert.Nil(t, err)
+	Mgr.StoreJobMapping(job.JobID, entryID)
+
+	assert.Nil(t, job.runAt(context.Background(), now.Add(time.Second), 5, history.TriggerSchedule, nil))
+	assert.Equal(t, int32(1), hits.Load())
+	_, ok := Mgr.LoadJobMapping(job.JobID)
+	assert.False(t, ok)
+
+	data, err := redisCacher.Conn.HGetAll(taskKey)
+	assert.Nil(t, err)
+	assert.Empty(t, data)
+	assert.Equal(t, int64(0), job.runs())
+}
+
+func TestMaxRunsReserve(t *testing.T) {
+	release := make(chan struct{})
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+	}))
+	defer srv.Close()
+
+	job := TaskPayload{
+		JobID:           "600004",
+		GroupName:       "test",
+		Name:            "maxruns04",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            HttpMode,
+		RequestUrl:      srv.URL,
+		Timeout:         5,
+		OverlapPolicy:   OverlapAllow,
+		MaxRuns:         1,
+	}
+	taskKey := setWindowTest(t, job)
+
+	// 第一次執行尚未結束時, 補跑的執行不能再使用同一個次數
+	now := time.Now()
+	done := make(chan *history.Record, 1)
+	go func() { done <- job.runAt(context.Background(), now, 5, history.TriggerSchedule, nil) }()
+	assert.Eventually(t, func() bool { return hits.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, job.runAt(context.Background(), now.Add(-time.Minute), 5, history.TriggerMisfire, nil))
+	assert.Equal(t, "1", redisCacher.Conn.Get(RunsPendingKey(job.JobID)).Val())
+	assert.Equal(t, "1", redisCacher.Conn.HGet(taskKey, "status").Val())
+
+	close(release)
+	rec := <-done
+	if assert.NotNil(t, rec) {
+		assert.Equal(t, history.OutcomeSuccess, rec.Outcome)
+	}
+	assert.Equal(t, int32(1), hits.Load())
+	assert.Equal(t, int64(1), job.runs())
+	assert.Equal(t, "0", redisCacher.Conn.Get(RunsPendingKey(job.JobID)).Val())
+	assert.Equal(t, "0", redisCacher.Conn.HGet(taskKey, "status").Val())
+}
@@ -0,0 +1,131 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/history"
+	"dcron/internal/leader"
+	"time"
+)
+
+// 停機期間錯過的執行的處理方式
+const (
+	MisfireSkip     = "skip"      // 不補跑, cron 任務預設
+	MisfireFireOnce = "fire_once" // 補跑一次, 一次性任務預設
+	MisfireFireAll  = "fire_all"  // 依序補跑每一次, 最多 misfire_limit 次
+
+	defaultMisfireGrace = 3 * time.Hour
+	defaultMisfireLimit = 10
+	MaxMisfireLimit     = 100
+)
+
+// 支援的 misfire policy
+var MisfirePolicies = []string{MisfireSkip, MisfireFireOnce, MisfireFireAll}
+
+// 只補跑 grace 內錯過的執行
+var misfireGrace = defaultMisfireGrace
+
+// 等待補跑的任務
+type misfire struct {
+	job   TaskPayload
+	times []time.Time
+}
+
+// 是否為支援的 misfire policy, 空值使用預設
+func IsMisfirePolicy(policy string) bool {
+	if policy == "" {
+		return true
+	}
+	for _, p := range MisfirePolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// 錯過的執行時間, 由上次執行時間 Prev 推算, 只保留 grace 內的, 由舊到新
+func (cm *CronManager) missedRuns(job TaskPayload, now time.Time) []time.Time {
+	limit := 0
+	switch job.MisfirePolicy {
+	case MisfireFireOnce:
+		limit = 1
+	case MisfireFireAll:
+		limit = job.MisfireLimit
+		if limit <= 0 {
+			limit = defaultMisfireLimit
+		}
+	}
+	if limit == 0 || job.Prev.IsZero() {
+		return nil
+	}
+
+	schedule, err := cm.schedule(&job)
+	if err != nil {
+		return nil
+	}
+
+	from := job.Prev.In(job.Location())
+	if cutoff := now.Add(-misfireGrace); from.Before(cutoff) {
+		from = cutoff.Add(-time.Second)
+	}
+
+	// 超過 limit 時保留最近的 limit 次
+	times := make([]time.Time, 0, limit)
+	for t := schedule.Next(from); !t.IsZero() && t.Before(now); t = schedule.Next(t) {
+		// 有效期間外的不補跑
+		if job.beforeStart(t) || job.afterEnd(t) {
+			continue
+		}
+		if len(times) == limit {
+			times = append(times[1:], t)
+		} else {
+			times = append(times, t)
+		}
+	}
+
+	return times
+}
+
+// 記錄需補跑的任務, cron 執行中馬上補跑, 否則等 Start 時再補跑
+func (cm *CronManager) queueMisfire(job TaskPayload, times []time.Time) {
+	if len(times) == 0 {
+		return
+	}
+
+	m := misfire{job: job, times: times}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	if cm.running {
+		go m.run()
+		return
+	}
+	cm.misfires = append(cm.misfires, m)
+}
+
+// 取出等待補跑的任務, 需持有 cm.mutex
+func (cm *CronManager) drainMisfires() {
+	for _, m := range cm.misfires {
+		go m.run()
+	}
+	cm.misfires = nil
+}
+
+// 依序補跑, 等待期間超過 grace 的不再執行
+func (m misfire) run() {
+	if !leader.Fence() {
+		return
+	}
+
+	for _, t := range m.times {
+		if time.Since(t) > misfireGrace {
+			continue
+		}
+		logger.WithFields(map[string]interface{}{
+			"job_id":       m.job.JobID,
+			"group_name":   m.job.GroupName,
+			"scheduled_at": t,
+		}).Info("job cronjob misfire catch up")
+		m.job.runAt(context.Background(), t, int64(misfireGrace/time.Second), history.TriggerMisfire, nil)
+	}
+}
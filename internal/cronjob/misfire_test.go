@@ -0,0 +1,82 @@
+package cronjob
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissedRuns(t *testing.T) {
+	cm := NewCronManager()
+	now := time.Date(2024, 1, 1, 12, 0, 30, 0, defaultLocation)
+
+	job := TaskPayload{
+		JobID:           "300001",
+		IntervalPattern: "0 * * * * *",
+		Prev:            now.Add(-5*time.Minute - 30*time.Second),
+	}
+	minute := func(m int) time.Time {
+		return time.Date(2024, 1, 1, 11, m, 0, 0, defaultLocation)
+	}
+
+	t.Run("default skip", func(t *testing.T) {
+		assert.Empty(t, cm.missedRuns(job, now))
+
+		job.MisfirePolicy = MisfireSkip
+		assert.Empty(t, cm.missedRuns(job, now))
+	})
+
+	t.Run("fire once", func(t *testing.T) {
+		job.MisfirePolicy = MisfireFireOnce
+		times := cm.missedRuns(job, now)
+		assert.Equal(t, []time.Time{time.Date(2024, 1, 1, 12, 0, 0, 0, defaultLocation)}, times)
+	})
+
+	t.Run("fire all", func(t *testing.T) {
+		job.MisfirePolicy = MisfireFireAll
+		times := cm.missedRuns(job, now)
+		assert.Len(t, times, 5)
+		assert.Equal(t, minute(56), times[0])
+
+		// 超過 limit 保留最近的
+		job.MisfireLimit = 2
+		times = cm.missedRuns(job, now)
+		assert.Equal(t, []time.Time{minute(59), time.Date(2024, 1, 1, 12, 0, 0, 0, defaultLocation)}, times)
+		job.MisfireLimit = 0
+	})
+
+	t.Run("grace", func(t *testing.T) {
+		misfireGrace = 2 * time.Minute
+		defer func() { misfireGrace = defaultMisfireGrace }()
+
+		job.MisfirePolicy = MisfireFireAll
+		times := cm.missedRuns(job, now)
+		assert.Equal(t, []time.Time{minute(59), time.Date(2024, 1, 1, 12, 0, 0, 0, defaultLocation)}, times)
+	})
+
+	t.Run("window", func(t *testing.T) {
+		job.MisfirePolicy = MisfireFireAll
+		job.StartAt = minute(57)
+		job.EndAt = minute(58)
+		defer func() { job.StartAt, job.EndAt = time.Time{}, time.Time{} }()
+
+		times := cm.missedRuns(job, now)
+		assert.Equal(t, []time.Time{minute(57), minute(58)}, times)
+	})
+
+	t.Run("every", func(t *testing.T) {
+		every := TaskPayload{
+			IntervalPattern: "@every 10m",
+			Prev:            now.Add(-25 * time.Minute),
+			MisfirePolicy:   MisfireFireAll,
+		}
+		times := cm.missedRuns(every, now)
+		assert.Len(t, times, 2)
+	})
+
+	t.Run("never ran", func(t *testing.T) {
+		job.Prev = time.Time{}
+		assert.Empty(t, cm.missedRuns(job, now))
+	})
+}
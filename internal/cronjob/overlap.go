@@ -0,0 +1,163 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// 上一次執行尚未結束時的處理方式
+const (
+	OverlapAllow = "allow" // 允許同時執行, 預設
+	OverlapSkip  = "skip"  // 略過本次執行
+	OverlapQueue = "queue" // 排隊等待上一次結束, 最多排一個, 其餘略過
+)
+
+const (
+	overlapLease = 30 // 執行鎖租約(秒), 執行期間持續延長, 節點異常時自動釋放
+	overlapPoll  = 200 * time.Millisecond
+)
+
+// 鎖仍屬於自己才延長
+const overlapRenewScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("EXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+// 鎖仍屬於自己才釋放
+const overlapReleaseScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+// 支援的 overlap policy
+var OverlapPolicies = []string{OverlapAllow, OverlapSkip, OverlapQueue}
+
+var ErrOverlapSkipped = errors.New("previous execution is still running")
+
+var overlapSeq uint64
+
+// 叢集共用的執行鎖, 隨執行結束釋放
+type overlapLock struct {
+	key   string
+	token string
+	stop  chan struct{}
+}
+
+// 是否為支援的 overlap policy, 空值為 allow
+func IsOverlapPolicy(policy string) bool {
+	if policy == "" {
+		return true
+	}
+	for _, p := range OverlapPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapRunningKey(jobID string) string {
+	return fmt.Sprintf("RUNNING_%s", jobID)
+}
+
+func overlapQueueKey(jobID string) string {
+	return fmt.Sprintf("QUEUED_%s", jobID)
+}
+
+func overlapToken() string {
+	return fmt.Sprintf("%s-%d", server.GetServerInstance().GetHostName(), atomic.AddUint64(&overlapSeq, 1))
+}
+
+// 依 policy 取得執行鎖, allow 不上鎖回傳 nil; 需略過時回傳 ErrOverlapSkipped
+func acquireOverlap(ctx context.Context, jobID, policy string) (*overlapLock, error) {
+	switch policy {
+	case OverlapSkip:
+		lock, err := tryOverlapLock(overlapRunningKey(jobID))
+		if err != nil {
+			return nil, err
+		}
+		if lock == nil {
+			return nil, ErrOverlapSkipped
+		}
+		return lock, nil
+	case OverlapQueue:
+		lock, err := tryOverlapLock(overlapRunningKey(jobID))
+		if err != nil || lock != nil {
+			return lock, err
+		}
+		return waitOverlap(ctx, jobID)
+	default:
+		return nil, nil
+	}
+}
+
+// 佔用排隊位置後等待上一次執行結束
+func waitOverlap(ctx context.Context, jobID string) (*overlapLock, error) {
+	queued, err := tryOverlapLock(overlapQueueKey(jobID))
+	if err != nil {
+		return nil, err
+	}
+	if queued == nil {
+		return nil, ErrOverlapSkipped
+	}
+	defer queued.Release()
+
+	ticker := time.NewTicker(overlapPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+			lock, err := tryOverlapLock(overlapRunningKey(jobID))
+			if err != nil || lock != nil {
+				return lock, err
+			}
+		}
+	}
+}
+
+// 嘗試上鎖, 已被佔用回傳 nil; 上鎖後持續延長租約直到 Release
+func tryOverlapLock(key string) (*overlapLock, error) {
+	token := overlapToken()
+	ok, err := redisCacher.Conn.SetNX(key, token, overlapLease)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	lock := &overlapLock{key: key, token: token, stop: make(chan struct{})}
+	go lock.keepAlive()
+
+	return lock, nil
+}
+
+func (l *overlapLock) keepAlive() {
+	ticker := time.NewTicker(overlapLease * time.Second / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			redisCacher.Conn.Eval(overlapRenewScript, []string{l.key}, l.token, overlapLease)
+		}
+	}
+}
+
+// 釋放執行鎖, nil 可安全呼叫
+func (l *overlapLock) Release() {
+	if l == nil {
+		return
+	}
+	close(l.stop)
+	redisCacher.Conn.Eval(overlapReleaseScript, []string{l.key}, l.token)
+}
@@ -0,0 +1,83 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireOverlap(t *testing.T) {
+	redisCacher.SetMiniredis()
+	ctx := context.Background()
+
+	t.Run("allow", func(t *testing.T) {
+		first, err := acquireOverlap(ctx, "200001", OverlapAllow)
+		assert.Nil(t, err)
+		assert.Nil(t, first)
+
+		second, err := acquireOverlap(ctx, "200001", "")
+		assert.Nil(t, err)
+		assert.Nil(t, second)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		first, err := acquireOverlap(ctx, "200002", OverlapSkip)
+		assert.Nil(t, err)
+		assert.NotNil(t, first)
+
+		_, err = acquireOverlap(ctx, "200002", OverlapSkip)
+		assert.ErrorIs(t, err, ErrOverlapSkipped)
+
+		first.Release()
+		again, err := acquireOverlap(ctx, "200002", OverlapSkip)
+		assert.Nil(t, err)
+		again.Release()
+	})
+
+	t.Run("queue one", func(t *testing.T) {
+		first, err := acquireOverlap(ctx, "200003", OverlapQueue)
+		assert.Nil(t, err)
+
+		queued := make(chan *overlapLock, 1)
+		go func() {
+			lock, err := acquireOverlap(ctx, "200003", OverlapQueue)
+			assert.Nil(t, err)
+			queued <- lock
+		}()
+
+		// 已有一個在排隊, 其餘略過
+		assert.Eventually(t, func() bool {
+			v, _ := redisCacher.Conn.Get(overlapQueueKey("200003")).Result()
+			return v != ""
+		}, time.Second, 10*time.Millisecond)
+		_, err = acquireOverlap(ctx, "200003", OverlapQueue)
+		assert.ErrorIs(t, err, ErrOverlapSkipped)
+
+		first.Release()
+		select {
+		case lock := <-queued:
+			assert.NotNil(t, lock)
+			lock.Release()
+		case <-time.After(2 * time.Second):
+			t.Fatal("queued execution not started")
+		}
+	})
+
+	t.Run("queue cancelled", func(t *testing.T) {
+		first, err := acquireOverlap(ctx, "200004", OverlapQueue)
+		assert.Nil(t, err)
+		defer first.Release()
+
+		cancelCtx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+		defer cancel()
+		_, err = acquireOverlap(cancelCtx, "200004", OverlapQueue)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// 取消後釋放排隊位置
+		v, _ := redisCacher.Conn.Get(overlapQueueKey("200004")).Result()
+		assert.Equal(t, "", v)
+	})
+}
@@ -0,0 +1,79 @@
+package cronjob
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultPreviewCount = 5
+	MaxPreviewCount     = 100
+)
+
+// 表達式預覽
+type Preview struct {
+	Pattern     string      `json:"pattern" example:"0 */5 * * * *"`          // 輸入的表達式
+	Expression  string      `json:"expression" example:"0 */5 * * * *"`       // 實際註冊的表達式, timestamp 會轉換為 cron 表達式
+	Timezone    string      `json:"timezone" example:"Asia/Taipei"`           // 計算使用的時區
+	Description string      `json:"description" example:"Every 5 minutes"`    // 表達式說明
+	Once        bool        `json:"once" example:"false"`                     // true: timestamp 一次性任務
+	Next        []time.Time `json:"next" example:"2024-01-01T12:05:00+08:00"` // 接下來的執行時間
+}
+
+// 以註冊時相同的解析方式, 計算 from 之後 count 次的執行時間
+func (cm *CronManager) Preview(pattern, timezone string, count int, from time.Time) (Preview, error) {
+	if count <= 0 {
+		count = defaultPreviewCount
+	}
+	if count > MaxPreviewCount {
+		count = MaxPreviewCount
+	}
+
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	preview := Preview{
+		Pattern:  pattern,
+		Timezone: loc.String(),
+		Next:     make([]time.Time, 0, count),
+	}
+
+	expression, err := cm.Parse(pattern, loc)
+	if err != nil {
+		return preview, err
+	}
+	preview.Expression = expression
+
+	// timestamp 只執行一次, 轉換後的表達式每年都會符合
+	if n, err := decimal.NewFromString(pattern); err == nil {
+		t := time.Unix(n.IntPart(), 0).In(loc)
+		preview.Once = true
+		preview.Description = "Once at " + t.Format("2006-01-02 15:04:05 -07:00")
+		if t.After(from) {
+			preview.Next = append(preview.Next, t)
+		}
+		return preview, nil
+	}
+
+	job := TaskPayload{IntervalPattern: expression, Timezone: loc.String()}
+	schedule, err := cm.cronParser.Parse(job.Spec())
+	if err != nil {
+		return preview, err
+	}
+	for t := from; len(preview.Next) < count; {
+		if t = schedule.Next(t); t.IsZero() {
+			break
+		}
+		preview.Next = append(preview.Next, t.In(loc))
+	}
+
+	preview.Description, err = Describe(expression)
+	if err != nil {
+		return preview, err
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,104 @@
+package cronjob
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"* * * * * *", "Every second"},
+		{"*/10 * * * * *", "Every 10 seconds"},
+		{"0 */5 * * * *", "Every 5 minutes"},
+		{"0 0/15 * * * *", "Every 15 minutes starting at 0"},
+		{"30 * * * * *", "At second 30, every minute"},
+		{"0 0 * * * *", "At minute 0, every hour"},
+		{"0 30 9 * * 1-5", "At 09:30:00, on Monday through Friday"},
+		{"5 4 * * sun", "At 04:05:00, on Sunday"},
+		{"0 15,45 8-18 * * MON-FRI", "At minutes 15 and 45, at hours 8 through 18, on Monday through Friday"},
+		{"0 0 9-17/2 * JAN-MAR *", "At minute 0, every 2 hours from 9 through 17, in January through March"},
+		{"0 0 0 1,15 * *", "At 00:00:00, on days 1 and 15 of the month"},
+		{"0 0 0 1 * 1", "At 00:00:00, on day 1 of the month or on Monday"},
+		{"CRON_TZ=Asia/Tokyo 0 0 9 * * *", "At 09:00:00"},
+		{"0 0 0 L * ?", "At 00:00:00, on the last day of the month"},
+		{"0 0 0 L-3 * ?", "At 00:00:00, 3 days before the last day of the month"},
+		{"0 0 18 LW * ?", "At 18:00:00, on the last weekday of the month"},
+		{"0 0 9 15W * ?", "At 09:00:00, on the weekday nearest day 15 of the month"},
+		{"0 0 9 ? * 5#3", "At 09:00:00, on the third Friday of the month"},
+		{"0 0 9 ? * FRIL", "At 09:00:00, on the last Friday of the month"},
+		{"0 0 0 1 1 ? 2025", "At 00:00:00, on day 1 of the month, in January, in year 2025"},
+		{"@hourly", "At minute 0 of every hour"},
+		{"@every 10m", "Every 10m"},
+	}
+	for _, tt := range tests {
+		desc, err := Describe(tt.spec)
+		assert.Nil(t, err, tt.spec)
+		assert.Equal(t, tt.want, desc, tt.spec)
+	}
+
+	_, err := Describe("0 0 *")
+	assert.NotNil(t, err)
+}
+
+func TestPreview(t *testing.T) {
+	cm := NewCronManager()
+	from := time.Date(2024, 1, 1, 12, 3, 0, 0, defaultLocation)
+
+	t.Run("cron", func(t *testing.T) {
+		preview, err := cm.Preview("0 */5 * * * *", "", 3, from)
+		assert.Nil(t, err)
+		assert.Equal(t, defaultLocation.String(), preview.Timezone)
+		assert.Equal(t, "Every 5 minutes", preview.Description)
+		assert.Equal(t, []time.Time{
+			time.Date(2024, 1, 1, 12, 5, 0, 0, defaultLocation),
+			time.Date(2024, 1, 1, 12, 10, 0, 0, defaultLocation),
+			time.Date(2024, 1, 1, 12, 15, 0, 0, defaultLocation),
+		}, preview.Next)
+	})
+
+	t.Run("timezone", func(t *testing.T) {
+		london, _ := time.LoadLocation("Europe/London")
+		preview, err := cm.Preview("0 0 9 * * *", "Europe/London", 1, from)
+		assert.Nil(t, err)
+		assert.Equal(t, "Europe/London", preview.Timezone)
+		assert.Equal(t, []time.Time{time.Date(2024, 1, 1, 9, 0, 0, 0, london)}, preview.Next)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		preview, err := cm.Preview("@hourly", "", 0, from)
+		assert.Nil(t, err)
+		assert.Len(t, preview.Next, defaultPreviewCount)
+
+		preview, err = cm.Preview("@every 1s", "", 1000, from)
+		assert.Nil(t, err)
+		assert.Len(t, preview.Next, MaxPreviewCount)
+	})
+
+	t.Run("once", func(t *testing.T) {
+		at := from.Add(time.Hour)
+		preview, err := cm.Preview("1704085380", "", 5, from)
+		assert.Nil(t, err)
+		assert.True(t, preview.Once)
+		assert.Equal(t, "0 3 13 1 1 *", preview.Expression)
+		assert.Equal(t, "Once at 2024-01-01 13:03:00 +08:00", preview.Description)
+		assert.Equal(t, []time.Time{at}, preview.Next)
+
+		// 已過期不會再執行
+		preview, err = cm.Preview("1704085380", "", 5, at)
+		assert.Nil(t, err)
+		assert.Empty(t, preview.Next)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := cm.Preview("0 0 25 * * *", "", 5, from)
+		assert.NotNil(t, err)
+
+		_, err = cm.Preview("0 0 * * * *", "Mars/Base", 5, from)
+		assert.NotNil(t, err)
+	})
+}
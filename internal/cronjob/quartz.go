@@ -0,0 +1,353 @@
+package cronjob
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+/*
+ * Quartz 擴充語法, 其餘表達式仍由 robfig/cron 解析
+ *
+ * 日:   `L` 最後一天 `L-3` 最後一天前 3 天 `LW` 最後一個平日 `15W` 最接近 15 號的平日, 不跨月
+ * 星期: `5L` 當月最後一個星期五 `5#3` 當月第三個星期五, 星期與 robfig/cron 相同 0 為星期日
+ * 年:   第 7 個欄位, 1970-2099, 需同時設定秒
+ */
+const (
+	minYear = 1970
+	maxYear = 2099
+)
+
+var dowNames = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+
+type quartzParser struct {
+	std cron.Parser
+}
+
+func newQuartzParser() quartzParser {
+	return quartzParser{
+		std: cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor),
+	}
+}
+
+func (p quartzParser) Parse(spec string) (cron.Schedule, error) {
+	prefix, fields := splitSpec(spec)
+	if !isQuartz(fields) {
+		return p.std.Parse(spec)
+	}
+
+	// 秒可省略, 年可省略
+	if len(fields) == 5 {
+		fields = append([]string{"0"}, fields...)
+	}
+	if len(fields) == 6 {
+		fields = append(fields, "*")
+	}
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("expected 5 to 7 fields, found %d: %s", len(fields), spec)
+	}
+
+	var loc *time.Location
+	if prefix != "" {
+		tz := strings.TrimSpace(prefix[strings.Index(prefix, "=")+1:])
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("provided bad location %s: %v", tz, err)
+		}
+		loc = l
+	}
+
+	// 秒、分、時、月交給 robfig/cron, 日、星期、年另外判斷
+	base, err := p.std.Parse(prefix + strings.Join([]string{fields[0], fields[1], fields[2], "*", fields[4], "*"}, " "))
+	if err != nil {
+		return nil, err
+	}
+	dom, err := parseDom(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	dow, err := parseDow(fields[5])
+	if err != nil {
+		return nil, err
+	}
+	years, err := parseYears(fields[6])
+	if err != nil {
+		return nil, err
+	}
+
+	return &quartzSchedule{base: base, loc: loc, dom: dom, dow: dow, years: years}, nil
+}
+
+// 拆出時區前綴及各欄位
+func splitSpec(spec string) (string, []string) {
+	spec = strings.TrimSpace(spec)
+	prefix := ""
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		i := strings.Index(spec, " ")
+		if i < 0 {
+			return spec, nil
+		}
+		prefix, spec = spec[:i+1], spec[i+1:]
+	}
+	return prefix, strings.Fields(spec)
+}
+
+// 是否使用擴充語法; 星期名稱不含 L 及 #, 日只能是數字
+func isQuartz(fields []string) bool {
+	n := len(fields)
+	if n == 0 || strings.HasPrefix(fields[0], "@") {
+		return false
+	}
+	if n == 7 {
+		return true
+	}
+	if n != 5 && n != 6 {
+		return false
+	}
+	dom, dow := strings.ToUpper(fields[n-3]), strings.ToUpper(fields[n-1])
+	return strings.ContainsAny(dom, "LW") || strings.ContainsAny(dow, "L#")
+}
+
+// 日或星期欄位, 任一項符合即符合
+type dayField struct {
+	star     bool // `*` 或 `?`
+	matchers []func(t time.Time) bool
+}
+
+func (f dayField) match(t time.Time) bool {
+	for _, m := range f.matchers {
+		if m(t) {
+			return true
+		}
+	}
+	return false
+}
+
+type quartzSchedule struct {
+	base  cron.Schedule
+	loc   *time.Location // 時區前綴, nil 為 t 的時區
+	dom   dayField
+	dow   dayField
+	years []bool // nil 為不限制
+}
+
+func (s *quartzSchedule) Next(t time.Time) time.Time {
+	loc := s.loc
+	if loc == nil {
+		loc = t.Location()
+	}
+
+	for next := s.base.Next(t); !next.IsZero(); {
+		local := next.In(loc)
+		if local.Year() > maxYear {
+			break
+		}
+		if s.years != nil && !s.years[local.Year()] {
+			next = s.base.Next(time.Date(local.Year()+1, 1, 1, 0, 0, 0, 0, loc).Add(-time.Second))
+			continue
+		}
+		if s.dayMatches(local) {
+			return next.In(t.Location())
+		}
+		next = s.base.Next(time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc).Add(-time.Second))
+	}
+
+	return time.Time{}
+}
+
+// 與 robfig/cron 相同, 日與星期都有設定時符合任一即可
+func (s *quartzSchedule) dayMatches(t time.Time) bool {
+	if s.dom.star || s.dow.star {
+		return (s.dom.star || s.dom.match(t)) && (s.dow.star || s.dow.match(t))
+	}
+	return s.dom.match(t) || s.dow.match(t)
+}
+
+func parseDom(field string) (dayField, error) {
+	if isAny(field) {
+		return dayField{star: true}, nil
+	}
+
+	var f dayField
+	for _, item := range strings.Split(strings.ToUpper(field), ",") {
+		switch {
+		case item == "L":
+			f.matchers = append(f.matchers, func(t time.Time) bool {
+				return t.Day() == lastDay(t)
+			})
+		case item == "LW":
+			f.matchers = append(f.matchers, func(t time.Time) bool {
+				return t.Day() == nearestWeekday(t, lastDay(t))
+			})
+		case strings.HasPrefix(item, "L-"):
+			n, err := strconv.Atoi(item[2:])
+			if err != nil || n < 0 || n > 30 {
+				return f, fmt.Errorf("invalid day of month: %s", item)
+			}
+			f.matchers = append(f.matchers, func(t time.Time) bool {
+				return t.Day() == lastDay(t)-n
+			})
+		case strings.HasSuffix(item, "W"):
+			n, err := strconv.Atoi(strings.TrimSuffix(item, "W"))
+			if err != nil || n < 1 || n > 31 {
+				return f, fmt.Errorf("invalid day of month: %s", item)
+			}
+			f.matchers = append(f.matchers, func(t time.Time) bool {
+				return n <= lastDay(t) && t.Day() == nearestWeekday(t, n)
+			})
+		default:
+			values, err := parseValues(item, 1, 31, nil)
+			if err != nil {
+				return f, err
+			}
+			f.matchers = append(f.matchers, func(t time.Time) bool {
+				return values[t.Day()]
+			})
+		}
+	}
+	return f, nil
+}
+
+func parseDow(field string) (dayField, error) {
+	if isAny(field) {
+		return dayField{star: true}, nil
+	}
+
+	var f dayField
+	for _, item := range strings.Split(strings.ToUpper(field), ",") {
+		switch {
+		case len(item) > 1 && strings.HasSuffix(item, "L"):
+			day, err := parseValue(strings.TrimSuffix(item, "L"), 0, 6, dowNames)
+			if err != nil {
+				return f, err
+			}
+			f.matchers = append(f.matchers, func(t time.Time) bool {
+				return int(t.Weekday()) == day && t.Day()+7 > lastDay(t)
+			})
+		case strings.Contains(item, "#"):
+			parts := strings.SplitN(item, "#", 2)
+			day, err := parseValue(parts[0], 0, 6, dowNames)
+			if err != nil {
+				return f, err
+			}
+			nth, err := strconv.Atoi(parts[1])
+			if err != nil || nth < 1 || nth > 5 {
+				return f, fmt.Errorf("invalid day of week: %s", item)
+			}
+			f.matchers = append(f.matchers, func(t time.Time) bool {
+				return int(t.Weekday()) == day && (t.Day()-1)/7+1 == nth
+			})
+		default:
+			values, err := parseValues(item, 0, 6, dowNames)
+			if err != nil {
+				return f, err
+			}
+			f.matchers = append(f.matchers, func(t time.Time) bool {
+				return values[t.Weekday()]
+			})
+		}
+	}
+	return f, nil
+}
+
+func parseYears(field string) ([]bool, error) {
+	if isAny(field) {
+		return nil, nil
+	}
+
+	years := make([]bool, maxYear+1)
+	for _, item := range strings.Split(field, ",") {
+		values, err := parseValues(item, minYear, maxYear, nil)
+		if err != nil {
+			return nil, err
+		}
+		for y, ok := range values {
+			years[y] = years[y] || ok
+		}
+	}
+	return years, nil
+}
+
+// 解析 `*` `a` `a-b` 及 `/step`, 回傳以值為索引的集合
+func parseValues(item string, min, max int, names map[string]int) ([]bool, error) {
+	rangeExpr, step := item, 1
+	if i := strings.Index(item, "/"); i >= 0 {
+		n, err := strconv.Atoi(item[i+1:])
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid step: %s", item)
+		}
+		rangeExpr, step = item[:i], n
+	}
+
+	start, end := min, max
+	switch {
+	case isAny(rangeExpr):
+	case strings.Contains(rangeExpr, "-"):
+		parts := strings.SplitN(rangeExpr, "-", 2)
+		var err error
+		if start, err = parseValue(parts[0], min, max, names); err != nil {
+			return nil, err
+		}
+		if end, err = parseValue(parts[1], min, max, names); err != nil {
+			return nil, err
+		}
+	default:
+		var err error
+		if start, err = parseValue(rangeExpr, min, max, names); err != nil {
+			return nil, err
+		}
+		// `a/step` 為 a 到最大值
+		if step == 1 {
+			end = start
+		}
+	}
+	if start > end {
+		return nil, fmt.Errorf("beginning of range (%d) beyond end of range (%d): %s", start, end, item)
+	}
+
+	values := make([]bool, max+1)
+	for v := start; v <= end; v += step {
+		values[v] = true
+	}
+	return values, nil
+}
+
+func parseValue(expr string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(expr)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse int from %s", expr)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value (%d) out of range [%d, %d]", v, min, max)
+	}
+	return v, nil
+}
+
+// 當月最後一天
+func lastDay(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// 最接近當月 day 號的平日, 不跨月
+func nearestWeekday(t time.Time, day int) int {
+	last := lastDay(t)
+	switch time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, t.Location()).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == last {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
@@ -0,0 +1,82 @@
+package cronjob
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuartzParse(t *testing.T) {
+	p := newQuartzParser()
+
+	// 原有表達式仍由 robfig/cron 解析
+	for _, spec := range []string{"0 0 * * * *", "0 0 * * *", "0 0 9 ? * WED", "CRON_TZ=Asia/Tokyo 0 30 9 * * 1-5"} {
+		schedule, err := p.Parse(spec)
+		assert.Nil(t, err, spec)
+		assert.IsType(t, &cron.SpecSchedule{}, schedule, spec)
+	}
+	for _, spec := range []string{"@hourly", "@every 10m"} {
+		_, err := p.Parse(spec)
+		assert.Nil(t, err, spec)
+	}
+
+	for _, spec := range []string{"0 0 0 32W * ?", "0 0 0 L-31 * ?", "0 0 0 ? * 5#6", "0 0 0 ? * 7L", "0 0 0 * * * 1969", "0 0 0 * * * 2026-2024", "0 0 0 * * * * *"} {
+		_, err := p.Parse(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestQuartzNext(t *testing.T) {
+	p := newQuartzParser()
+	loc := defaultLocation
+	date := func(y int, m time.Month, d, h int) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want []time.Time
+	}{
+		{"last day", "0 0 0 L * ?", date(2024, 2, 10, 0), []time.Time{date(2024, 2, 29, 0), date(2024, 3, 31, 0), date(2024, 4, 30, 0)}},
+		{"last day offset", "0 0 0 L-2 * ?", date(2024, 4, 1, 0), []time.Time{date(2024, 4, 28, 0), date(2024, 5, 29, 0)}},
+		{"last weekday", "0 0 18 LW * ?", date(2024, 8, 1, 0), []time.Time{date(2024, 8, 30, 18), date(2024, 9, 30, 18)}},
+		{"nearest weekday", "0 0 9 15W * ?", date(2024, 6, 1, 0), []time.Time{date(2024, 6, 14, 9), date(2024, 7, 15, 9), date(2024, 8, 15, 9), date(2024, 9, 16, 9)}},
+		{"nearest weekday in month", "0 0 9 1W * ?", date(2024, 5, 15, 0), []time.Time{date(2024, 6, 3, 9)}},
+		{"nth weekday", "0 0 9 ? * 5#3", date(2024, 1, 1, 0), []time.Time{date(2024, 1, 19, 9), date(2024, 2, 16, 9)}},
+		{"nth weekday name", "0 0 9 ? * fri#3", date(2024, 1, 1, 0), []time.Time{date(2024, 1, 19, 9)}},
+		{"last weekday of month", "0 0 9 ? * 5L", date(2024, 1, 1, 0), []time.Time{date(2024, 1, 26, 9), date(2024, 2, 23, 9)}},
+		{"dom or dow", "0 0 0 L * 1", date(2024, 1, 27, 0), []time.Time{date(2024, 1, 29, 0), date(2024, 1, 31, 0), date(2024, 2, 5, 0)}},
+		{"year", "0 0 0 1 1 ? 2030", date(2024, 1, 1, 0), []time.Time{date(2030, 1, 1, 0), {}}},
+		{"year step", "0 0 0 L 2 ? 2024/4", date(2024, 1, 1, 0), []time.Time{date(2024, 2, 29, 0), date(2028, 2, 29, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := p.Parse(tt.spec)
+			if !assert.Nil(t, err) {
+				return
+			}
+			got := make([]time.Time, 0, len(tt.want))
+			for next := tt.from; len(got) < len(tt.want); {
+				next = schedule.Next(next)
+				got = append(got, next)
+				if next.IsZero() {
+					break
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("timezone", func(t *testing.T) {
+		ny, _ := time.LoadLocation("America/New_York")
+		schedule, err := p.Parse("CRON_TZ=America/New_York 0 0 9 L * ?")
+		assert.Nil(t, err)
+		next := schedule.Next(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+		assert.Equal(t, time.Date(2024, 3, 31, 9, 0, 0, 0, ny), next.In(ny))
+		assert.Equal(t, time.UTC, next.Location())
+	})
+}
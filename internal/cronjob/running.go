@@ -0,0 +1,57 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/server"
+	"sync"
+)
+
+// 本節點正在執行的任務, 用來取消執行中的任務
+var (
+	runningMutex sync.Mutex
+	runningSeq   uint64
+	runningMap   = make(map[string]map[uint64]context.CancelFunc)
+)
+
+// 執行任務的 context, 服務關閉時一併取消
+func baseContext() context.Context {
+	if ctx := server.GetServerInstance().GetGracefulCtx(); ctx != nil {
+		return *ctx
+	}
+	return context.Background()
+}
+
+// 登記執行中的任務, 結束時需呼叫回傳的 done
+func startRunning(jobID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(baseContext())
+
+	runningMutex.Lock()
+	runningSeq++
+	seq := runningSeq
+	if runningMap[jobID] == nil {
+		runningMap[jobID] = make(map[uint64]context.CancelFunc)
+	}
+	runningMap[jobID][seq] = cancel
+	runningMutex.Unlock()
+
+	return ctx, func() {
+		runningMutex.Lock()
+		delete(runningMap[jobID], seq)
+		if len(runningMap[jobID]) == 0 {
+			delete(runningMap, jobID)
+		}
+		runningMutex.Unlock()
+		cancel()
+	}
+}
+
+// 取消本節點該任務所有執行中的 goroutine, 回傳取消的數量
+func CancelRunning(jobID string) int {
+	runningMutex.Lock()
+	defer runningMutex.Unlock()
+
+	for _, cancel := range runningMap[jobID] {
+		cancel()
+	}
+	return len(runningMap[jobID])
+}
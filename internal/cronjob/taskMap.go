@@ -12,6 +12,7 @@ func (cm *CronManager) StoreJobMapping(jobID string, entryID cron.EntryID) {
 func (cm *CronManager) DeleteJobMapping(jobID string) {
 	// 儲存任務和 Cron Entry 的對應關係
 	cm.jobMap.Delete(jobID)
+	cm.stopEndTimer(jobID, 0)
 }
 
 func (cm *CronManager) LoadJobMapping(jobID string) (cron.EntryID, bool) {
@@ -22,3 +23,19 @@ func (cm *CronManager) LoadJobMapping(jobID string) (cron.EntryID, bool) {
 	}
 	return 0, ok
 }
+
+// 所有任務和 Cron Entry 的對應關係
+func (cm *CronManager) JobMappings() map[string]cron.EntryID {
+	ret := make(map[string]cron.EntryID)
+	cm.jobMap.Range(func(key, value interface{}) bool {
+		ret[key.(string)] = value.(cron.EntryID)
+		return true
+	})
+	return ret
+}
+
+// 是否等待延遲載入, 載入前不在 cron 中
+func (cm *CronManager) Delayed(jobID string) bool {
+	_, ok := cm.delayed.Load(jobID)
+	return ok
+}
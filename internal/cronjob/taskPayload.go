@@ -2,12 +2,24 @@ package cronjob
 
 import (
 	"context"
+	"dcron/internal/calendar"
+	"dcron/internal/deadletter"
+	"dcron/internal/history"
 	"dcron/internal/httptarget"
+	"dcron/internal/leader"
 	"dcron/internal/lib"
+	"dcron/internal/metrics"
+	"dcron/internal/notify"
 	"dcron/internal/nsqtarget"
 	"dcron/internal/redisCacher"
+	"dcron/internal/retry"
+	"dcron/internal/tracing"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 const (
@@ -16,47 +28,121 @@ const (
 	TestMode = "test"
 )
 
+// 任務仍存在才更新狀態, 避免重建已刪除的任務
+const stopScript = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HSET", KEYS[1], "status", ARGV[1], "stop_reason", ARGV[2])
+end
+return 0`
+
 type PubJob struct {
-	JobID     string `json:"job_id"`     // 排程ID
-	GroupName string `json:"group_name"` // 群組名稱
-	Name      string `json:"name"`       // 排程名稱
-	Event     string `json:"event"`
-	HostName  string `json:"host_name"`
+	JobID     string            `json:"job_id"`     // 排程ID
+	GroupName string            `json:"group_name"` // 群組名稱
+	Name      string            `json:"name"`       // 排程名稱
+	Event     string            `json:"event"`
+	HostName  string            `json:"host_name"`
+	Upstream  *history.Upstream `json:"upstream,omitempty"` // trigger 事件的上游執行
 }
 
 type TaskPayloadReq struct {
-	GroupName       string `json:"group_name" validate:"required" example:"test"`              // 群組名稱
-	Name            string `json:"name" validate:"required" example:"job01"`                   // 排程名稱
-	ExecRightNow    bool   `json:"exec_right_now" example:"false"`                             // true: 馬上執行
-	RequestUrl      string `json:"request_url" example:"http://127.0.0.1/api/ping"`            // 網址
-	Retry           bool   `json:"retry" example:"false"`                                      // true: http失敗重新執行
-	IntervalPattern string `json:"interval_pattern" validate:"required" example:"0 * * * * *"` // 支援 `0 0 * * * *` `@hourly` `1685935821`
-	Type            string `json:"type" validate:"required" example:"http"`                    // `nsq` `http`
-	NsqTopic        string `json:"nsq_topic" example:""`                                       // type選擇nsq,topic不能為空
-	NsqMessage      string `json:"nsq_message" example:""`                                     // nsq回傳的訊息 ex.{"game_name": "BBLT","draw_mode":1,"open_timestamp": 1686116327,"close_timestamp": 1686116387}
+	GroupName       string                 `json:"group_name" validate:"required" example:"test"`   // 群組名稱
+	Name            string                 `json:"name" validate:"required" example:"job01"`        // 排程名稱
+	ExecRightNow    bool                   `json:"exec_right_now" example:"false"`                  // true: 馬上執行
+	RequestUrl      string                 `json:"request_url" example:"http://127.0.0.1/api/ping"` // 網址
+	RequestMethod   string                 `json:"request_method" example:"GET"`                    // `GET` `POST` `PUT` `PATCH` `DELETE`, 預設 `GET`
+	RequestHeaders  map[string]string      `json:"request_headers"`                                 // 自訂 header ex.{"Authorization": "Bearer token"}
+	RequestBody     string                 `json:"request_body" example:""`                         // 請求內容, 依 Content-Type 檢查 json 或 form 格式
+	SuccessRule     httptarget.SuccessRule `json:"success_rule"`                                    // http 成功條件, 未設定為狀態碼 200
+	RetryPolicy     retry.Policy           `json:"retry_policy"`                                    // 重試策略, 未設定 max_attempts 時 retry=true 為最多 3 次間隔 100ms
+	Timeout         int64                  `json:"timeout" example:"20"`                            // 單次執行逾時(秒), 0 為預設 20 秒
+	OverlapPolicy   string                 `json:"overlap_policy" example:"allow"`                  // 上一次尚未結束時: `allow` 同時執行 `skip` 略過 `queue` 排隊一次, 預設 `allow`
+	MisfirePolicy   string                 `json:"misfire_policy" example:"skip"`                   // 停機期間錯過的執行: `skip` 不補跑 `fire_once` 補跑一次 `fire_all` 依序補跑, 預設 cron 任務 `skip` 一次性任務 `fire_once`
+	MisfireLimit    int                    `json:"misfire_limit" example:"10"`                      // `fire_all` 最多補跑次數, 預設 10, 上限 100
+	Retry           bool                   `json:"retry" example:"false"`                           // true: http失敗重新執行
+	IntervalPattern string                 `json:"interval_pattern" example:"0 * * * * *"`          // 支援 `0 0 * * * *` `0 0 0 L * ?` `@hourly` `1685935821`, 設定 depends_on 時可為空, 只由上游觸發
+	DependsOn       []Dependency           `json:"depends_on"`                                      // 上游任務, 任一上游完成且符合條件時觸發
+	StartAt         int64                  `json:"start_at" example:"1685935821"`                   // 開始時間 unix timestamp, 之前不執行, 0 為不限制
+	EndAt           int64                  `json:"end_at" example:"1688527821"`                     // 結束時間 unix timestamp, 之後移出排程, 0 為不限制
+	EndPolicy       string                 `json:"end_policy" example:"finish"`                     // 超過結束時間: `finish` 標記為已結束 `delete` 刪除任務, 預設 `finish`
+	MaxRuns         int                    `json:"max_runs" example:"0"`                            // 最多執行次數, 達到後依 max_runs_policy 停止, 0 為不限制
+	MaxRunsCount    string                 `json:"max_runs_count" example:"success"`                // 計算方式: `success` 只計算成功 `attempt` 每次執行都計算, 預設 `success`
+	MaxRunsPolicy   string                 `json:"max_runs_policy" example:"pause"`                 // 達到 max_runs: `pause` 暫停任務 `delete` 刪除任務, 預設 `pause`
+	Calendars       []string               `json:"calendars" example:"holiday"`                     // 排除日曆名稱, 落在任一日曆內的執行會被略過
+	Jitter          int64                  `json:"jitter" example:"0"`                              // 錯開執行的範圍(秒), 依 job_id 固定延後, 0 使用群組設定
+	Notifiers       []string               `json:"notifiers" example:"oncall"`                      // 通知管道名稱, 與群組的通知管道合併
+	Timezone        string                 `json:"timezone" example:"Asia/Taipei"`                  // IANA 時區 ex.`Europe/London`, 未設定使用群組時區, 再未設定使用伺服器預設時區
+	Type            string                 `json:"type" validate:"required" example:"http"`         // `nsq` `http`
+	NsqTopic        string                 `json:"nsq_topic" example:""`                            // type選擇nsq,topic不能為空
+	NsqMessage      string                 `json:"nsq_message" example:""`                          // nsq回傳的訊息 ex.{"game_name": "BBLT","draw_mode":1,"open_timestamp": 1686116327,"close_timestamp": 1686116387}
 }
 
 type TaskPayload struct {
-	JobID           string    `json:"job_id"`                 // 排程ID
-	GroupName       string    `json:"group_name"`             // 群組名稱
-	Name            string    `json:"name"`                   // 排程名稱
-	ExecRightNow    bool      `json:"exec_right_now"`         // true: 馬上執行
-	RequestUrl      string    `json:"request_url"`            // 網址
-	Retry           bool      `json:"retry"`                  // true: http失敗重新執行
-	IntervalPattern string    `json:"interval_pattern"`       // 支援 `0 0 * * * *` `@hourly` `1685935821`
-	Type            string    `json:"type"`                   // `nsq` `http`
-	Status          int       `json:"status"`                 // 1:執行中
-	NsqTopic        string    `json:"nsq_topic"`              // type選擇nsq,topic不能為空
-	NsqMessage      string    `json:"nsq_message" example:""` // nsq回傳的訊息
-	Register        time.Time `json:"register"`               // 註冊時間
-	Next            time.Time `json:"next"`                   // 下次執行時間
-	Prev            time.Time `json:"prev"`                   // 上次執行時間
-	Memo            string    `json:"memo"`                   // 備註
+	JobID           string                 `json:"job_id"`                 // 排程ID
+	GroupName       string                 `json:"group_name"`             // 群組名稱
+	Name            string                 `json:"name"`                   // 排程名稱
+	ExecRightNow    bool                   `json:"exec_right_now"`         // true: 馬上執行
+	RequestUrl      string                 `json:"request_url"`            // 網址
+	RequestMethod   string                 `json:"request_method"`         // `GET` `POST` `PUT` `PATCH` `DELETE`
+	RequestHeaders  map[string]string      `json:"request_headers"`        // 自訂 header
+	RequestBody     string                 `json:"request_body"`           // 請求內容
+	SuccessRule     httptarget.SuccessRule `json:"success_rule"`           // http 成功條件
+	RetryPolicy     retry.Policy           `json:"retry_policy"`           // 重試策略
+	Timeout         int64                  `json:"timeout"`                // 單次執行逾時(秒)
+	OverlapPolicy   string                 `json:"overlap_policy"`         // `allow` `skip` `queue`
+	MisfirePolicy   string                 `json:"misfire_policy"`         // `skip` `fire_once` `fire_all`
+	MisfireLimit    int                    `json:"misfire_limit"`          // `fire_all` 最多補跑次數
+	Retry           bool                   `json:"retry"`                  // true: http失敗重新執行
+	IntervalPattern string                 `json:"interval_pattern"`       // 支援 `0 0 * * * *` `@hourly` `1685935821`
+	DependsOn       []Dependency           `json:"depends_on"`             // 上游任務
+	StartAt         time.Time              `json:"start_at"`               // 開始時間, 零值為不限制
+	EndAt           time.Time              `json:"end_at"`                 // 結束時間, 零值為不限制
+	EndPolicy       string                 `json:"end_policy"`             // `finish` `delete`
+	MaxRuns         int                    `json:"max_runs"`               // 最多執行次數, 0 為不限制
+	MaxRunsCount    string                 `json:"max_runs_count"`         // `success` `attempt`
+	MaxRunsPolicy   string                 `json:"max_runs_policy"`        // `pause` `delete`
+	Calendars       []string               `json:"calendars"`              // 排除日曆
+	Jitter          int64                  `json:"jitter"`                 // 錯開執行的範圍(秒), 依 job_id 固定偏移
+	Notifiers       []string               `json:"notifiers"`              // 通知管道
+	Runs            int64                  `json:"runs"`                   // 已執行次數, 只有設定 max_runs 時計算
+	StopReason      string                 `json:"stop_reason"`            // 自動停止的原因
+	Timezone        string                 `json:"timezone"`               // IANA 時區, 空值為伺服器預設時區
+	Type            string                 `json:"type"`                   // `nsq` `http`
+	Status          int                    `json:"status"`                 // 0:暫停 1:執行中 2:已結束
+	NsqTopic        string                 `json:"nsq_topic"`              // type選擇nsq,topic不能為空
+	NsqMessage      string                 `json:"nsq_message" example:""` // nsq回傳的訊息
+	Register        time.Time              `json:"register"`               // 註冊時間
+	TraceParent     string                 `json:"trace_parent"`           // 註冊時的 traceparent, 執行的 span 以 link 關聯
+	Next            time.Time              `json:"next"`                   // 下次執行時間
+	Prev            time.Time              `json:"prev"`                   // 上次執行時間
+	Memo            string                 `json:"memo"`                   // 備註
 }
 
+// 排程觸發, 選主模式下只有 leader 會執行
 func (j *TaskPayload) Run() {
-	loc, _ := time.LoadLocation("Asia/Taipei")
+	j.observeLag()
+	if !leader.Fence() {
+		return
+	}
+	j.RunNow()
+}
+
+// 直接執行, 不經過選主檢查
+func (j *TaskPayload) RunNow() {
+	j.runAt(context.Background(), time.Now(), 5, history.TriggerSchedule, nil)
+}
+
+// 手動執行, 走一般的鎖、overlap policy 及執行紀錄, 不影響排程; 同一秒重複觸發時回傳 nil
+// parent 只用來延續追蹤, 執行不受其取消影響
+func (j *TaskPayload) RunManual(parent context.Context) *history.Record {
+	return j.runAt(parent, time.Now(), 5, history.TriggerManual, nil)
+}
+
+// 執行預定於 scheduled 的任務, 同一個預定時間在 lockTTL 秒內只會有一個節點執行; 未執行時回傳 nil
+// parent 只用來延續追蹤, 執行不受其取消影響
+func (j *TaskPayload) runAt(parent context.Context, scheduled time.Time, lockTTL int64, trigger string, upstream *history.Upstream) *history.Record {
+	loc := j.Location()
 	currentTime := time.Now().In(loc)
+	scheduled = scheduled.In(loc)
 	t1 := currentTime.UnixMilli()
 
 	logInfo := logger.WithFields(map[string]interface{}{
@@ -72,54 +158,373 @@ func (j *TaskPayload) Run() {
 		"exec_time":  currentTime,
 	})
 
-	isOnce := lib.IsMemoOnce(j.Memo)
+	// 有效期間外不執行, 手動執行不受限制
+	if trigger != history.TriggerManual {
+		if j.beforeStart(scheduled) {
+			logInfo.Debug("job cronjob not started")
+			return nil
+		}
+		if j.afterEnd(scheduled) {
+			j.expire()
+			return nil
+		}
+	}
+
+	// 手動執行及上游觸發不影響一次性任務的排程
+	manual := trigger == history.TriggerManual || trigger == history.TriggerDependency
+	isOnce := lib.IsMemoOnce(j.Memo) && !manual
 	if isOnce {
-		isWithinExec := j.ExecRightNow || lib.ShouldExecuteThreeHours(j.Memo)
+		isWithinExec := j.ExecRightNow || lib.ShouldExecuteWithin(j.Memo, misfireGrace)
 		if !isWithinExec {
 			logInfo.Debug("job cronjob expired")
-			j.runOnce()
-			return
+			j.runOnce(context.Background())
+			return nil
 		}
 		if !j.acquireLock(fmt.Sprintf("LOCK_ONCE_%s", j.JobID), "once", 30) {
-			return
+			return nil
+		}
+	} else if upstream != nil {
+		if !j.acquireLock(fmt.Sprintf("LOCK_DEP_%s_%s", j.JobID, upstream.HistoryID), 60, lockTTL) {
+			return nil
+		}
+	} else if manual {
+		if !j.acquireLock(fmt.Sprintf("LOCK_MANUAL_%s_%d", j.JobID, scheduled.Unix()), 60, lockTTL) {
+			return nil
 		}
 	} else {
-		if !j.acquireLock(fmt.Sprintf("LOCK_%s_%d", j.JobID, currentTime.Unix()), 60, 5) {
-			return
+		if !j.acquireLock(fmt.Sprintf("LOCK_%s_%d", j.JobID, scheduled.Unix()), 60, lockTTL) {
+			return nil
 		}
 	}
 
+	// 其他節點已刪除或停止的任務不再執行, 手動執行不受限制
+	if trigger != history.TriggerManual && !j.active() {
+		logInfo.Debug("job cronjob inactive")
+		return nil
+	}
+
+	// 達到 max_runs 後不再執行, 手動執行不計算
+	if trigger != history.TriggerManual && !j.checkMaxRuns() {
+		return nil
+	}
+
 	logInfo.Debug("job cronjob run")
 
-	switch j.Type {
-	case HttpMode:
-		j.runHttp()
-	case NsqMode:
-		j.runNsq()
-	case TestMode:
-		j.runTest()
+	ctx, done := startRunning(j.JobID)
+	defer done()
+
+	rec := &history.Record{
+		JobID:       j.JobID,
+		GroupName:   j.GroupName,
+		Name:        j.Name,
+		Type:        j.Type,
+		Trigger:     trigger,
+		Upstream:    upstream,
+		ScheduledAt: j.scheduledAt(scheduled),
+		StartedAt:   currentTime,
+	}
+	ctx, span := j.startSpan(tracing.ContextWithSpan(ctx, tracing.SpanFromContext(parent)), rec)
+	if traceID := span.TraceID(); traceID != "" {
+		logInfo = logInfo.WithField("trace_id", traceID)
+	}
+	// 執行取消後仍需寫入紀錄, 只沿用 span
+	saveCtx := tracing.ContextWithSpan(context.Background(), span)
+
+	// 落在排除日曆內略過, 手動執行不受限制; 無法讀取日曆時無法確認, 同樣略過
+	if name, err := j.blackout(saveCtx, scheduled, trigger); err != nil {
+		logInfo.WithField("err", err).Error("job cronjob calendar unavailable")
+		rec.Outcome = history.OutcomeSkipped
+		rec.Error = fmt.Sprintf("calendar unavailable: %v", err)
+	} else if name != "" {
+		rec.Outcome = history.OutcomeSkipped
+		rec.Error = fmt.Sprintf("blackout by calendar %s", name)
+	} else {
+		j.executeWithOverlap(ctx, rec)
+	}
+
+	switch rec.Outcome {
+	case history.OutcomeFailed:
+		j.logFailure(rec)
+	case history.OutcomeCancelled:
+		logInfo.WithField("err", rec.Error).Warn("job cronjob cancelled")
+	case history.OutcomeSkipped:
+		logInfo.WithField("err", rec.Error).Warn("job cronjob skipped")
+	}
+
+	rec.Duration = time.Since(currentTime).Milliseconds()
+	j.observe(rec)
+	if err := history.Save(saveCtx, rec); err != nil {
+		logInfo.WithField("err", err.Error()).Error("job history save error")
+	}
+
+	// 重試後仍失敗, 保留完整任務內容供 replay
+	if rec.Outcome == history.OutcomeFailed {
+		if err := j.deadLetter(saveCtx, rec); err != nil {
+			logInfo.WithField("err", err.Error()).Error("job dead letter save error")
+		}
+	}
+	notify.Result(saveCtx, rec, j.Notifiers)
+
+	if !manual {
+		j.runOnce(saveCtx)
+	}
+
+	if trigger != history.TriggerManual {
+		j.countRun(saveCtx, rec)
+	}
+	endSpan(span, rec)
+
+	j.completed(saveCtx, rec)
+
+	return rec
+}
+
+// 上一次執行尚未結束時依 overlap policy 略過或排隊
+func (j *TaskPayload) executeWithOverlap(ctx context.Context, rec *history.Record) {
+	lock, err := acquireOverlap(ctx, j.JobID, j.OverlapPolicy)
+	switch {
+	case err == nil:
+		j.execute(ctx, rec)
+		lock.Release()
+	case ctx.Err() != nil:
+		rec.Outcome = history.OutcomeCancelled
+		rec.Error = err.Error()
+	default:
+		rec.Outcome = history.OutcomeSkipped
+		rec.Error = err.Error()
+	}
+}
+
+// scheduled 落在哪個排除日曆內, 不需排除時回傳空值
+func (j *TaskPayload) blackout(ctx context.Context, scheduled time.Time, trigger string) (string, error) {
+	if len(j.Calendars) == 0 || trigger == history.TriggerManual {
+		return "", nil
+	}
+	return calendar.Match(ctx, j.Calendars, scheduled)
+}
+
+// 重新執行 dead letter 的任務內容, 不經過鎖及 overlap policy, 失敗不再寫入 dead letter
+// parent 只用來延續追蹤, 執行不受其取消影響
+func (j *TaskPayload) Replay(parent context.Context, scheduledAt time.Time) *history.Record {
+	loc := j.Location()
+	currentTime := time.Now().In(loc)
+
+	ctx, done := startRunning(j.JobID)
+	defer done()
+
+	rec := &history.Record{
+		JobID:       j.JobID,
+		GroupName:   j.GroupName,
+		Name:        j.Name,
+		Type:        j.Type,
+		Trigger:     history.TriggerReplay,
+		ScheduledAt: scheduledAt.In(loc),
+		StartedAt:   currentTime,
+	}
+	ctx, span := j.startSpan(tracing.ContextWithSpan(ctx, tracing.SpanFromContext(parent)), rec)
+	j.execute(ctx, rec)
+
+	rec.Duration = time.Since(currentTime).Milliseconds()
+	j.observe(rec)
+	if err := history.Save(tracing.ContextWithSpan(context.Background(), span), rec); err != nil {
+		logger.WithField("job_id", j.JobID).Errorf("job history save error: %v", err)
+	}
+	endSpan(span, rec)
+
+	return rec
+}
+
+func (j *TaskPayload) deadLetter(ctx context.Context, rec *history.Record) error {
+	payload, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	return deadletter.Save(ctx, &deadletter.Entry{
+		JobID:       j.JobID,
+		GroupName:   j.GroupName,
+		Name:        j.Name,
+		Type:        j.Type,
+		ScheduledAt: rec.ScheduledAt,
+		FailedAt:    time.Now().In(j.Location()),
+		Attempts:    rec.Attempts,
+		Code:        rec.Code,
+		Error:       rec.Error,
+		HistoryID:   rec.ID,
+		Payload:     payload,
+	})
+}
+
+// 依重試策略執行, 結果寫入 rec
+func (j *TaskPayload) execute(ctx context.Context, rec *history.Record) {
+	policy := j.retryPolicy()
+	for attempt := 1; ; attempt++ {
+		attemptStart := time.Now()
+
+		attemptCtx, cancel := context.WithTimeout(ctx, j.timeout())
+		switch j.Type {
+		case HttpMode:
+			j.runHttp(attemptCtx, rec)
+		case NsqMode:
+			j.runNsq(attemptCtx, rec)
+		case TestMode:
+			j.runTest(attemptCtx, rec)
+		}
+		cancel()
+
+		rec.Attempts = attempt
+		rec.AttemptLog = append(rec.AttemptLog, history.Attempt{
+			StartedAt: attemptStart,
+			Duration:  time.Since(attemptStart).Milliseconds(),
+			Code:      rec.Code,
+			Error:     rec.Error,
+		})
+
+		// 被取消或服務關閉時不再重試
+		if ctx.Err() != nil {
+			rec.Outcome = history.OutcomeCancelled
+			rec.Error = ctx.Err().Error()
+			break
+		}
+		if rec.Outcome != history.OutcomeFailed || !policy.ShouldRetry(attempt, rec.Code) {
+			break
+		}
+		if !retry.Wait(ctx, policy.Delay(attempt)) {
+			rec.Outcome = history.OutcomeCancelled
+			rec.Error = ctx.Err().Error()
+			break
+		}
 	}
 }
 
+// 重試策略, 未設定嘗試次數時沿用舊版 retry 欄位, 避免 retry=true 只執行一次
+func (j *TaskPayload) retryPolicy() retry.Policy {
+	if j.RetryPolicy.MaxAttempts == 0 && j.Retry {
+		return retry.Legacy
+	}
+	return j.RetryPolicy
+}
+
+// 單次嘗試的逾時時間
+func (j *TaskPayload) timeout() time.Duration {
+	if j.Timeout <= 0 {
+		return httptarget.DefaultTimeout
+	}
+	return time.Duration(j.Timeout) * time.Second
+}
+
+// 預定執行時間, 一次性任務為註冊的 timestamp, 其餘為觸發或補跑的當秒
+func (j *TaskPayload) scheduledAt(scheduled time.Time) time.Time {
+	if lib.IsMemoOnce(j.Memo) {
+		taskTime, err := decimal.NewFromString(strings.TrimSuffix(j.Memo, "@once"))
+		if err == nil {
+			return time.Unix(taskTime.IntPart(), 0).In(scheduled.Location())
+		}
+	}
+	return scheduled.Truncate(time.Second)
+}
+
+// 取得執行鎖, 已被其他節點或觸發取得時記錄略過次數
 func (j *TaskPayload) acquireLock(key string, value interface{}, expiration int64) bool {
 	exists, err := redisCacher.Conn.SetNX(key, value, expiration)
+	if err == nil && !exists {
+		metrics.LockSkips.Inc(j.GroupName)
+	}
 	return exists && err == nil
 }
 
-func (j *TaskPayload) runTest() {
+// 記錄執行結果的指標, 略過的執行不計算執行時間
+func (j *TaskPayload) observe(rec *history.Record) {
+	metrics.Executions.Inc(j.GroupName, j.Type, rec.Outcome)
+	if rec.Outcome != history.OutcomeSkipped {
+		metrics.ExecutionDuration.Observe(float64(rec.Duration)/1000, j.GroupName, j.Type)
+	}
+}
+
+// 排程延遲, cron 觸發時 entry 的 Prev 為這次預定的時間
+func (j *TaskPayload) observeLag() {
+	entryID, ok := Mgr.LoadJobMapping(j.JobID)
+	if !ok {
+		return
+	}
+	if prev := Mgr.Entry(entryID).Prev; !prev.IsZero() {
+		metrics.ScheduleLag.Observe(time.Since(prev).Seconds(), j.GroupName)
+	}
+}
+
+// 每次執行一個 span, 註冊時的 trace 以 link 關聯
+func (j *TaskPayload) startSpan(ctx context.Context, rec *history.Record) (context.Context, *tracing.Span) {
+	// 關聯註冊任務時的 trace
+	return tracing.StartWithLinks(ctx, "cronjob.run", tracing.KindInternal, []string{j.TraceParent},
+		tracing.String("job.id", j.JobID),
+		tracing.String("job.group", j.GroupName),
+		tracing.String("job.name", j.Name),
+		tracing.String("job.type", j.Type),
+		tracing.String("job.trigger", rec.Trigger),
+	)
+}
+
+func endSpan(span *tracing.Span, rec *history.Record) {
+	span.SetAttributes(
+		tracing.String("job.outcome", rec.Outcome),
+		tracing.Int("job.attempts", rec.Attempts),
+	)
+	if rec.Outcome == history.OutcomeFailed {
+		span.SetStatus(tracing.StatusError, rec.Error)
+	}
+	span.End()
+}
+
+func (j *TaskPayload) runTest(ctx context.Context, rec *history.Record) {
+	if err := ctx.Err(); err != nil {
+		setOutcome(rec, err)
+		return
+	}
 	key := fmt.Sprintf("TestCheck_%s", j.Name)
-	redisCacher.Conn.Set(key, "test_ok", 20)
-	j.runOnce()
+	err := redisCacher.Conn.WithContext(ctx).Set(key, "test_ok", 20)
+	setOutcome(rec, err)
+}
+
+func (j *TaskPayload) runHttp(ctx context.Context, rec *history.Record) {
+	req := httptarget.Request{
+		Method:  j.RequestMethod,
+		Url:     j.RequestUrl,
+		Headers: j.RequestHeaders,
+		Body:    j.RequestBody,
+		Timeout: j.timeout(),
+	}
+	res := httptarget.NewEntryScan(ctx, req, j.SuccessRule).Scan()
+
+	rec.Code = res.Code
+	rec.Body = res.Body
+	setOutcome(rec, res.Err)
 }
 
-func (j *TaskPayload) runHttp() {
-	maxCount := 0
-	if j.Retry {
-		maxCount = 3
+// nsq 發送不支援取消, 只在發送前檢查是否已取消
+func (j *TaskPayload) runNsq(ctx context.Context, rec *history.Record) {
+	if err := ctx.Err(); err != nil {
+		setOutcome(rec, err)
+		return
 	}
+	err := nsqtarget.Publish(ctx, j.NsqTopic, j.NsqMessage)
+	setOutcome(rec, err)
+}
+
+// 依本次嘗試的錯誤設定結果
+func setOutcome(rec *history.Record, err error) {
+	if err != nil {
+		rec.Outcome = history.OutcomeFailed
+		rec.Error = err.Error()
+		return
+	}
+	rec.Outcome = history.OutcomeSuccess
+	rec.Error = ""
+}
 
-	res := httptarget.NewEntryScan(context.Background(), j.RequestUrl, maxCount).Scan()
-	if res.Code != 200 && res.Err != nil {
+// 重試完仍失敗才記錄錯誤
+func (j *TaskPayload) logFailure(rec *history.Record) {
+	switch j.Type {
+	case HttpMode:
 		logger.WithFields(map[string]interface{}{
 			"func":       "payload_run",
 			"step":       "payload_run_http",
@@ -127,18 +532,13 @@ func (j *TaskPayload) runHttp() {
 			"job_name":   j.Name,
 			"job_id":     j.JobID,
 			"url":        j.RequestUrl,
+			"method":     j.RequestMethod,
 			"cron_type":  j.Type,
-			"res_code":   res.Code,
-			"res_count":  res.Count,
-			"res_error":  res.Err.Error(),
+			"res_code":   rec.Code,
+			"res_count":  rec.Attempts,
+			"res_error":  rec.Error,
 		}).Error("http error")
-	}
-	j.runOnce()
-}
-
-func (j *TaskPayload) runNsq() {
-	err := nsqtarget.Publish(j.NsqTopic, j.NsqMessage)
-	if err != nil {
+	case NsqMode:
 		logger.WithFields(map[string]interface{}{
 			"func":       "payload_run",
 			"step":       "payload_run_nsq",
@@ -148,15 +548,15 @@ func (j *TaskPayload) runNsq() {
 			"cron_type":  j.Type,
 			"nsq_topic":  j.NsqTopic,
 			"memo":       j.Memo,
-			"err":        err.Error(),
+			"res_count":  rec.Attempts,
+			"err":        rec.Error,
 		}).Error("nsq error")
 	}
-	j.runOnce()
 }
 
-func (j *TaskPayload) runOnce() {
+func (j *TaskPayload) runOnce(ctx context.Context) {
 	if lib.IsMemoOnce(j.Memo) {
-		j.cleanUpOnce()
+		j.cleanUp()
 		return
 	}
 
@@ -168,14 +568,32 @@ func (j *TaskPayload) runOnce() {
 			"prev": dataEntry.Prev,
 		}
 		key := fmt.Sprintf("TIME_%s_%s", j.GroupName, j.JobID)
-		redisCacher.Conn.HSet(key, values, 2*24*60*60)
+		redisCacher.Conn.WithContext(ctx).HSet(key, values, 2*24*60*60)
+	}
+}
+
+// 任務仍註冊且啟用中; 已刪除或非啟用時移出本節點的排程, redis 錯誤時不執行
+func (j *TaskPayload) active() bool {
+	status, err := redisCacher.Conn.HGet(fmt.Sprintf("TASK_%s_%s", j.GroupName, j.JobID), "status").Int()
+	if err == nil && status == StatusActive {
+		return true
 	}
+	if err == nil || redisCacher.IsNil(err) {
+		if entryID, ok := Mgr.LoadJobMapping(j.JobID); ok {
+			Mgr.Remove(entryID)
+			Mgr.DeleteJobMapping(j.JobID)
+		}
+	}
+	return false
 }
 
-func (j *TaskPayload) cleanUpOnce() {
+// 移除任務, 一次性任務執行後及自動停止時使用
+func (j *TaskPayload) cleanUp() {
 	redisCacher.Conn.HDel(fmt.Sprintf("TEAM_%s", j.GroupName), j.Name)
 	redisCacher.Conn.Del(fmt.Sprintf("CK_%s_%s", j.GroupName, j.Name))
 	redisCacher.Conn.Del(fmt.Sprintf("TASK_%s_%s", j.GroupName, j.JobID))
+	redisCacher.Conn.Del(RunsKey(j.JobID))
+	redisCacher.Conn.Del(RunsPendingKey(j.JobID))
 
 	entryID, ok := Mgr.LoadJobMapping(j.JobID)
 	if ok {
@@ -183,3 +601,31 @@ func (j *TaskPayload) cleanUpOnce() {
 		Mgr.DeleteJobMapping(j.JobID)
 	}
 }
+
+// 自動停止任務, 移出本節點的排程後刪除任務或更新狀態, 並記錄原因
+func (j *TaskPayload) stop(remove bool, status int, reason string) {
+	if entryID, ok := Mgr.LoadJobMapping(j.JobID); ok {
+		Mgr.Remove(entryID)
+		Mgr.DeleteJobMapping(j.JobID)
+	}
+
+	key := fmt.Sprintf("TASK_%s_%s", j.GroupName, j.JobID)
+	if remove {
+		// 已被刪除或重新註冊時不再清除
+		if jobID, _ := redisCacher.Conn.HGet(key, "job_id").Result(); jobID == j.JobID {
+			j.cleanUp()
+		}
+	} else {
+		redisCacher.Conn.Eval(stopScript, []string{key}, status, reason)
+	}
+	if stoppedHook != nil {
+		stoppedHook(j)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"job_id":     j.JobID,
+		"group_name": j.GroupName,
+		"removed":    remove,
+		"reason":     reason,
+	}).Info("job cronjob stopped")
+}
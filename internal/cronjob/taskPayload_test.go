@@ -0,0 +1,213 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/calendar"
+	"dcron/internal/history"
+	"dcron/internal/metrics"
+	"dcron/internal/redisCacher"
+	"dcron/internal/retry"
+	"dcron/internal/tracing"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunManual(t *testing.T) {
+	redisCacher.SetMiniredis()
+	ConfigInit()
+
+	// 已過期的一次性任務, 手動執行不會被清除
+	job := TaskPayload{
+		JobID:           "400001",
+		GroupName:       "test",
+		Name:            "job01",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            TestMode,
+		Memo:            "1630660501@once",
+	}
+	taskKey := fmt.Sprintf("TASK_%s_%s", job.GroupName, job.JobID)
+	assert.Nil(t, redisCacher.Conn.Set(taskKey, 1, 0))
+
+	// 請求已取消時照常執行
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := job.RunManual(ctx)
+	if assert.NotNil(t, rec) {
+		assert.Equal(t, history.TriggerManual, rec.Trigger)
+		assert.Equal(t, history.OutcomeSuccess, rec.Outcome)
+	}
+
+	v, err := redisCacher.Conn.Get(fmt.Sprintf("TestCheck_%s", job.Name)).Result()
+	assert.Nil(t, err)
+	assert.Equal(t, "test_ok", v)
+
+	v, err = redisCacher.Conn.Get(taskKey).Result()
+	assert.Nil(t, err)
+	assert.Equal(t, "1", v)
+
+	page, err := history.Find(context.Background(), job.GroupName, job.JobID, history.Query{})
+	assert.Nil(t, err)
+	if assert.Len(t, page.Records, 1) {
+		assert.Equal(t, history.TriggerManual, page.Records[0].Trigger)
+	}
+}
+
+func TestRunBlackout(t *testing.T) {
+	job := TaskPayload{
+		JobID:           "400002",
+		GroupName:       "test",
+		Name:            "job02",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            TestMode,
+		Calendars:       []string{"always"},
+	}
+	setWindowTest(t, job)
+
+	always := calendar.Calendar{
+		Name:   "always",
+		Weekly: []calendar.Weekly{{Days: []int{0, 1, 2, 3, 4, 5, 6}, Start: "00:00", End: "23:59"}, {Days: []int{0, 1, 2, 3, 4, 5, 6}, Start: "23:59", End: "00:00"}},
+	}
+	assert.Nil(t, calendar.Save(context.Background(), always))
+
+	rec := job.runAt(context.Background(), time.Now(), 5, history.TriggerSchedule, nil)
+	if assert.NotNil(t, rec) {
+		assert.Equal(t, history.OutcomeSkipped, rec.Outcome)
+		assert.Equal(t, "blackout by calendar always", rec.Error)
+		assert.Equal(t, 0, rec.Attempts)
+	}
+	_, err := redisCacher.Conn.Get(fmt.Sprintf("TestCheck_%s", job.Name)).Result()
+	assert.NotNil(t, err)
+
+	// 手動執行不受限制
+	rec = job.RunManual(context.Background())
+	if assert.NotNil(t, rec) {
+		assert.Equal(t, history.OutcomeSuccess, rec.Outcome)
+	}
+
+	// 無法讀取日曆時略過
+	assert.Nil(t, redisCacher.Conn.Del(fmt.Sprintf("TestCheck_%s", job.Name)))
+	assert.Nil(t, redisCacher.Conn.HSet("CALENDAR", map[string]interface{}{"always": "{"}, 0))
+	rec = job.runAt(context.Background(), time.Now().Add(time.Second), 5, history.TriggerSchedule, nil)
+	if assert.NotNil(t, rec) {
+		assert.Equal(t, history.OutcomeSkipped, rec.Outcome)
+		assert.Contains(t, rec.Error, "calendar unavailable")
+	}
+	_, err = redisCacher.Conn.Get(fmt.Sprintf("TestCheck_%s", job.Name)).Result()
+	assert.NotNil(t, err)
+
+	// 刪除日曆後不再排除
+	assert.Nil(t, calendar.Delete(context.Background(), "always"))
+	rec = job.runAt(context.Background(), time.Now().Add(2*time.Second), 5, history.TriggerSchedule, nil)
+	if assert.NotNil(t, rec) {
+		assert.Equal(t, history.OutcomeSuccess, rec.Outcome)
+	}
+}
+
+func TestRunMetrics(t *testing.T) {
+	job := TaskPayload{
+		JobID:           "400003",
+		GroupName:       "metrics",
+		Name:            "job03",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            TestMode,
+	}
+	setWindowTest(t, job)
+
+	success := metrics.Executions.Value(job.GroupName, TestMode, history.OutcomeSuccess)
+	durations := metrics.ExecutionDuration.Count(job.GroupName, TestMode)
+	skips := metrics.LockSkips.Value(job.GroupName)
+
+	upstream := history.Upstream{JobID: "400000", HistoryID: "1-0", Outcome: history.OutcomeSuccess}
+	assert.NotNil(t, job.RunTriggered(upstream))
+	assert.Equal(t, success+1, metrics.Executions.Value(job.GroupName, TestMode, history.OutcomeSuccess))
+	assert.Equal(t, durations+1, metrics.ExecutionDuration.Count(job.GroupName, TestMode))
+
+	// 同一次上游執行已取得鎖
+	assert.Nil(t, job.RunTriggered(upstream))
+	assert.Equal(t, skips+1, metrics.LockSkips.Value(job.GroupName))
+}
+
+func TestRunTracing(t *testing.T) {
+	job := TaskPayload{
+		JobID:           "400004",
+		GroupName:       "tracing",
+		Name:            "job04",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            TestMode,
+		TraceParent:     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	setWindowTest(t, job)
+
+	path := filepath.Join(t.TempDir(), "traces.json")
+	assert.Nil(t, tracing.Configure(tracing.Config{Exporter: tracing.ExporterFile, File: path}))
+	defer tracing.Shutdown(context.Background())
+
+	assert.NotNil(t, job.RunManual(context.Background()))
+	assert.Nil(t, tracing.Shutdown(context.Background()))
+
+	// file exporter 每行一個 span
+	type spanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	type fileSpan struct {
+		Name        string
+		SpanContext spanContext
+		Parent      spanContext
+		Links       []struct {
+			SpanContext spanContext
+		}
+	}
+	var spans []fileSpan
+	b, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var span fileSpan
+		assert.Nil(t, json.Unmarshal([]byte(line), &span))
+		spans = append(spans, span)
+	}
+
+	// 執行的 span 關聯註冊時的 trace, test 模式及寫入執行紀錄的 redis 指令為其子 span
+	if !assert.True(t, len(spans) > 2) {
+		return
+	}
+	run := spans[len(spans)-1]
+	assert.Equal(t, "cronjob.run", run.Name)
+	names := make([]string, 0, len(spans)-1)
+	for _, span := range spans[:len(spans)-1] {
+		names = append(names, span.Name)
+		assert.Equal(t, run.SpanContext.TraceID, span.SpanContext.TraceID)
+		assert.Equal(t, run.SpanContext.SpanID, span.Parent.SpanID)
+	}
+	assert.Contains(t, names, "redis set")
+	assert.Contains(t, names, "redis xadd")
+	if assert.Len(t, run.Links, 1) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", run.Links[0].SpanContext.TraceID)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	job := TaskPayload{}
+	assert.Equal(t, retry.Policy{}, job.retryPolicy())
+
+	job.Retry = true
+	assert.Equal(t, retry.Legacy, job.retryPolicy())
+
+	// 未設定嘗試次數時沿用舊版重試
+	job.RetryPolicy = retry.Policy{InitialDelay: 500, RetryOn: "5xx"}
+	assert.Equal(t, retry.Legacy, job.retryPolicy())
+
+	job.RetryPolicy.MaxAttempts = 5
+	assert.Equal(t, 5, job.retryPolicy().Attempts())
+
+	job.Retry = false
+	job.RetryPolicy.MaxAttempts = 0
+	assert.Equal(t, 1, job.retryPolicy().Attempts())
+}
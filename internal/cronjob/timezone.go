@@ -0,0 +1,61 @@
+package cronjob
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTimezone = "Asia/Taipei"
+
+// 已載入的時區, 避免每次執行都讀取 tzdata
+var locations sync.Map
+
+// 伺服器預設時區
+func DefaultTimezone() string {
+	return defaultLocation.String()
+}
+
+// 載入時區, 空值為伺服器預設時區
+func LoadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return defaultLocation, nil
+	}
+	if loc, ok := locations.Load(timezone); ok {
+		return loc.(*time.Location), nil
+	}
+
+	// Local 依主機設定而不同, 叢集內不一致
+	if strings.EqualFold(timezone, "local") {
+		return nil, fmt.Errorf("timezone %s is not supported", timezone)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone %s is invalid", timezone)
+	}
+	locations.Store(timezone, loc)
+
+	return loc, nil
+}
+
+// 任務的時區, 無法載入時為伺服器預設時區
+// 新增任務時一律寫入時區, 未設定的為加入時區設定前註冊的任務, 沿用當時固定的 Asia/Taipei
+func (j *TaskPayload) Location() *time.Location {
+	if j.Timezone == "" {
+		return legacyLocation
+	}
+	loc, err := LoadLocation(j.Timezone)
+	if err != nil {
+		return defaultLocation
+	}
+	return loc
+}
+
+// 註冊到 cron 的表達式, 加上 CRON_TZ 依任務時區計算, 含日光節約時間
+func (j *TaskPayload) Spec() string {
+	if strings.HasPrefix(j.IntervalPattern, "TZ=") || strings.HasPrefix(j.IntervalPattern, "CRON_TZ=") {
+		return j.IntervalPattern
+	}
+	return fmt.Sprintf("CRON_TZ=%s %s", j.Location(), j.IntervalPattern)
+}
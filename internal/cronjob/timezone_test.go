@@ -0,0 +1,78 @@
+package cronjob
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := LoadLocation("")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultTimezone(), loc.String())
+
+	loc, err = LoadLocation("Europe/London")
+	assert.Nil(t, err)
+	assert.Equal(t, "Europe/London", loc.String())
+
+	_, err = LoadLocation("Mars/Olympus")
+	assert.Error(t, err)
+
+	_, err = LoadLocation("Local")
+	assert.Error(t, err)
+}
+
+func TestTaskPayloadSpec(t *testing.T) {
+	job := TaskPayload{IntervalPattern: "0 0 9 * * *"}
+	assert.Equal(t, "CRON_TZ=Asia/Taipei 0 0 9 * * *", job.Spec())
+
+	job.Timezone = "America/New_York"
+	assert.Equal(t, "CRON_TZ=America/New_York 0 0 9 * * *", job.Spec())
+
+	job.IntervalPattern = "TZ=UTC 0 0 9 * * *"
+	assert.Equal(t, "TZ=UTC 0 0 9 * * *", job.Spec())
+}
+
+func TestLegacyTimezone(t *testing.T) {
+	london, _ := time.LoadLocation("Europe/London")
+	origin := defaultLocation
+	defaultLocation = london
+	defer func() {
+		defaultLocation = origin
+	}()
+
+	// 未設定時區的舊任務不受 TIMEZONE 影響
+	job := TaskPayload{IntervalPattern: "0 0 9 * * *"}
+	assert.Equal(t, "Asia/Taipei", job.Location().String())
+	assert.Equal(t, "CRON_TZ=Asia/Taipei 0 0 9 * * *", job.Spec())
+
+	// 無法載入的時區使用伺服器預設時區
+	job.Timezone = "Mars/Olympus"
+	assert.Equal(t, "Europe/London", job.Location().String())
+}
+
+func TestTimezoneSchedule(t *testing.T) {
+	cm := NewCronManager()
+	ny, _ := LoadLocation("America/New_York")
+
+	// 美東 2024-03-10 02:00 進入夏令時間, 09:00 仍依當地時間執行
+	job := TaskPayload{IntervalPattern: "0 0 9 * * *", Timezone: "America/New_York"}
+	schedule, err := cm.cronParser.Parse(job.Spec())
+	assert.Nil(t, err)
+
+	from := time.Date(2024, 3, 9, 20, 0, 0, 0, time.UTC)
+	next := schedule.Next(from)
+	assert.Equal(t, time.Date(2024, 3, 10, 9, 0, 0, 0, ny), next.In(ny))
+	assert.Equal(t, time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC), next.UTC())
+
+	next = schedule.Next(time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 3, 8, 14, 0, 0, 0, time.UTC), next.UTC())
+
+	// timestamp 依任務時區轉為 cron 表達式
+	ts := time.Date(2024, 3, 10, 9, 30, 0, 0, ny).Unix()
+	spec, err := cm.Parse(strconv.FormatInt(ts, 10), ny)
+	assert.Nil(t, err)
+	assert.Equal(t, "0 30 9 10 3 *", spec)
+}
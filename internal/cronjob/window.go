@@ -0,0 +1,94 @@
+package cronjob
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 超過結束時間後的處理方式
+const (
+	EndFinish = "finish" // 移出排程並標記為已結束, 預設
+	EndDelete = "delete" // 移出排程並刪除任務
+)
+
+// 任務狀態
+const (
+	StatusPaused   = 0
+	StatusActive   = 1
+	StatusFinished = 2 // 超過結束時間
+)
+
+// 支援的 end policy
+var EndPolicies = []string{EndFinish, EndDelete}
+
+// 是否為支援的 end policy, 空值使用預設
+func IsEndPolicy(policy string) bool {
+	if policy == "" {
+		return true
+	}
+	for _, p := range EndPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// t 是否早於開始時間
+func (j *TaskPayload) beforeStart(t time.Time) bool {
+	return !j.StartAt.IsZero() && t.Before(j.StartAt)
+}
+
+// t 是否晚於結束時間
+func (j *TaskPayload) afterEnd(t time.Time) bool {
+	return !j.EndAt.IsZero() && t.After(j.EndAt)
+}
+
+// 註冊到 cron, 設定 jitter 時延後固定偏移; 有結束時間時到期自動移出排程
+func (cm *CronManager) AddTask(payload TaskPayload) (cron.EntryID, error) {
+	schedule, err := cm.schedule(&payload)
+	if err != nil {
+		return 0, err
+	}
+	entryID := cm.cron.Schedule(schedule, &payload)
+
+	if !payload.EndAt.IsZero() {
+		cm.setEndTimer(payload.JobID, entryID, time.AfterFunc(time.Until(payload.EndAt), payload.expire))
+	}
+
+	return entryID, nil
+}
+
+// 任務結束時間的計時器, 記錄所屬的 entry, 避免移除舊 entry 時停止新 entry 的計時器
+type endTimer struct {
+	entryID cron.EntryID
+	timer   *time.Timer
+}
+
+// 每個任務只保留一個計時器, 重新載入時停止舊的
+func (cm *CronManager) setEndTimer(jobID string, entryID cron.EntryID, timer *time.Timer) {
+	if prev, loaded := cm.endTimers.Swap(jobID, endTimer{entryID: entryID, timer: timer}); loaded {
+		prev.(endTimer).timer.Stop()
+	}
+}
+
+// 停止任務的結束計時器, entryID 為 0 時不檢查所屬的 entry
+func (cm *CronManager) stopEndTimer(jobID string, entryID cron.EntryID) {
+	value, ok := cm.endTimers.Load(jobID)
+	if !ok {
+		return
+	}
+	t := value.(endTimer)
+	if entryID != 0 && t.entryID != entryID {
+		return
+	}
+	if cm.endTimers.CompareAndDelete(jobID, t) {
+		t.timer.Stop()
+	}
+}
+
+// 超過結束時間, 依 end policy 結束或刪除任務
+func (j *TaskPayload) expire() {
+	j.stop(j.EndPolicy == EndDelete, StatusFinished, "end_at reached")
+}
@@ -0,0 +1,172 @@
+package cronjob
+
+import (
+	"context"
+	"dcron/internal/history"
+	"dcron/internal/redisCacher"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setWindowTest(t *testing.T, job TaskPayload) string {
+	redisCacher.SetMiniredis()
+	ConfigInit()
+	Mgr.StartInit(context.Background(), true)
+	t.Cleanup(Mgr.Stop)
+
+	redisCacher.Conn.HSet(fmt.Sprintf("TEAM_%s", job.GroupName), map[string]interface{}{job.Name: job.JobID}, 0)
+	taskKey := fmt.Sprintf("TASK_%s_%s", job.GroupName, job.JobID)
+	redisCacher.Conn.HSet(taskKey, map[string]interface{}{"job_id": job.JobID, "status": StatusActive}, 0)
+
+	return taskKey
+}
+
+func TestRunBeforeStart(t *testing.T) {
+	job := TaskPayload{
+		JobID:           "500001",
+		GroupName:       "test",
+		Name:            "window01",
+		IntervalPattern: "* * * * * *",
+		Type:            TestMode,
+		StartAt:         time.Now().Add(time.Hour),
+	}
+	setWindowTest(t, job)
+
+	job.RunNow()
+
+	_, err := redisCacher.Conn.Get(fmt.Sprintf("TestCheck_%s", job.Name)).Result()
+	assert.NotNil(t, err)
+	page, err := history.Find(context.Background(), job.GroupName, job.JobID, history.Query{})
+	assert.Nil(t, err)
+	assert.Empty(t, page.Records)
+
+	// 手動執行不受限制
+	rec := job.RunManual(context.Background())
+	if assert.NotNil(t, rec) {
+		assert.Equal(t, history.OutcomeSuccess, rec.Outcome)
+	}
+}
+
+func TestRunAfterEnd(t *testing.T) {
+	job := TaskPayload{
+		JobID:           "500002",
+		GroupName:       "test",
+		Name:            "window02",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            TestMode,
+		EndAt:           time.Now().Add(-time.Minute),
+	}
+
+	t.Run("finish", func(t *testing.T) {
+		taskKey := setWindowTest(t, job)
+		entryID, err := Mgr.AddJob(job.Spec(), &job)
+		assert.Nil(t, err)
+		Mgr.StoreJobMapping(job.JobID, entryID)
+
+		job.RunNow()
+
+		_, ok := Mgr.LoadJobMapping(job.JobID)
+		assert.False(t, ok)
+		status, err := redisCacher.Conn.HGet(taskKey, "status").Result()
+		assert.Nil(t, err)
+		assert.Equal(t, "2", status)
+
+		page, err := history.Find(context.Background(), job.GroupName, job.JobID, history.Query{})
+		assert.Nil(t, err)
+		assert.Empty(t, page.Records)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		job.EndPolicy = EndDelete
+		taskKey := setWindowTest(t, job)
+
+		job.RunNow()
+
+		data, err := redisCacher.Conn.HGetAll(taskKey)
+		assert.Nil(t, err)
+		assert.Empty(t, data)
+		jobID, _ := redisCacher.Conn.HGet(fmt.Sprintf("TEAM_%s", job.GroupName), job.Name).Result()
+		assert.Empty(t, jobID)
+	})
+
+	t.Run("finish deleted job", func(t *testing.T) {
+		job.EndPolicy = EndFinish
+		taskKey := setWindowTest(t, job)
+		assert.Nil(t, redisCacher.Conn.Del(taskKey))
+
+		job.RunNow()
+
+		data, err := redisCacher.Conn.HGetAll(taskKey)
+		assert.Nil(t, err)
+		assert.Empty(t, data)
+	})
+}
+
+func TestAddTaskEnd(t *testing.T) {
+	job := TaskPayload{
+		JobID:           "500003",
+		GroupName:       "test",
+		Name:            "window03",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            TestMode,
+		EndAt:           time.Now().Add(100 * time.Millisecond),
+	}
+	taskKey := setWindowTest(t, job)
+
+	entryID, err := Mgr.AddTask(job)
+	assert.Nil(t, err)
+	Mgr.StoreJobMapping(job.JobID, entryID)
+
+	assert.Eventually(t, func() bool {
+		_, ok := Mgr.LoadJobMapping(job.JobID)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	status, err := redisCacher.Conn.HGet(taskKey, "status").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, "2", status)
+}
+
+func TestAddTaskEndTimer(t *testing.T) {
+	job := TaskPayload{
+		JobID:           "500004",
+		GroupName:       "test",
+		Name:            "window04",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            TestMode,
+		EndAt:           time.Now().Add(time.Hour),
+	}
+	setWindowTest(t, job)
+
+	endTimers := func() int {
+		n := 0
+		Mgr.endTimers.Range(func(_, _ interface{}) bool {
+			n++
+			return true
+		})
+		return n
+	}
+
+	// 重新載入只保留最後一個計時器
+	first, err := Mgr.AddTask(job)
+	assert.Nil(t, err)
+	second, err := Mgr.AddTask(job)
+	assert.Nil(t, err)
+	Mgr.StoreJobMapping(job.JobID, second)
+	assert.Equal(t, 1, endTimers())
+
+	// 移除舊的 entry 不影響目前的計時器
+	Mgr.Remove(first)
+	assert.Equal(t, 1, endTimers())
+
+	Mgr.Remove(second)
+	assert.Equal(t, 0, endTimers())
+
+	third, err := Mgr.AddTask(job)
+	assert.Nil(t, err)
+	Mgr.StoreJobMapping(job.JobID, third)
+	Mgr.DeleteJobMapping(job.JobID)
+	assert.Equal(t, 0, endTimers())
+}
@@ -1,9 +1,14 @@
 package ctl
 
 import (
+	"context"
 	"dcron/internal/cronjob"
+	"dcron/internal/httptarget"
 	"dcron/internal/lib"
+	"dcron/internal/notify"
 	"dcron/internal/redisCacher"
+	"dcron/internal/retry"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"sort"
@@ -12,19 +17,20 @@ import (
 	"time"
 )
 
-func AcquireLock(groupName, name string, ttl int64) (bool, error) {
+// 註冊鎖, 值為取得時間, 對帳時用來判斷是否為註冊中途失敗留下的鎖
+func AcquireLock(ctx context.Context, groupName, name string, ttl int64) (bool, error) {
 	key := fmt.Sprintf("CK_%s_%s", groupName, name)
-	return redisCacher.Conn.SetNX(key, 1, ttl)
+	return redisCacher.Conn.WithContext(ctx).SetNX(key, time.Now().Unix(), ttl)
 }
 
-func ReleaseLock(groupName, name string) {
+func ReleaseLock(ctx context.Context, groupName, name string) {
 	key := fmt.Sprintf("CK_%s_%s", groupName, name)
-	redisCacher.Conn.Del(key)
+	redisCacher.Conn.WithContext(ctx).Del(key)
 }
 
 // 取出所有註冊的group
-func FetchGroupList() ([]string, error) {
-	records, err := redisCacher.Conn.Scan("TEAM_*")
+func FetchGroupList(ctx context.Context) ([]string, error) {
+	records, err := redisCacher.Conn.WithContext(ctx).Scan("TEAM_*")
 	if err != nil {
 		return nil, err
 	}
@@ -49,12 +55,12 @@ func FetchGroupList() ([]string, error) {
  * ["pojob_014"] = job01
  * ["pojob_015"] = job02
  */
-func SetJobGroup(groupName, name, jobID string) error {
+func SetJobGroup(ctx context.Context, groupName, name, jobID string) error {
 	fields := make(map[string]interface{})
 	fields[name] = jobID
 	// 各個group_name底下的JobID
 	key := fmt.Sprintf("TEAM_%s", groupName)
-	return redisCacher.Conn.HSet(key, fields, 0)
+	return redisCacher.Conn.WithContext(ctx).HSet(key, fields, 0)
 }
 
 /*
@@ -66,9 +72,9 @@ func SetJobGroup(groupName, name, jobID string) error {
  * ["request_url"] = http://127.0.0.1/api/ping
  * ["interval_pattern"] = 0 * * * * *
  */
-func SetTaskPayload(payload cronjob.TaskPayload, ttl int64) error {
+func SetTaskPayload(ctx context.Context, payload cronjob.TaskPayload, ttl int64) error {
 
-	if err := SetJobGroup(payload.GroupName, payload.Name, payload.JobID); err != nil {
+	if err := SetJobGroup(ctx, payload.GroupName, payload.Name, payload.JobID); err != nil {
 		return err
 	}
 
@@ -78,13 +84,35 @@ func SetTaskPayload(payload cronjob.TaskPayload, ttl int64) error {
 		"name":             payload.Name,
 		"exec_right_now":   payload.ExecRightNow,
 		"request_url":      payload.RequestUrl,
+		"request_method":   payload.RequestMethod,
+		"request_headers":  marshalHeaders(payload.RequestHeaders),
+		"request_body":     payload.RequestBody,
+		"success_rule":     marshalSuccessRule(payload.SuccessRule),
+		"retry_policy":     marshalRetryPolicy(payload.RetryPolicy),
 		"retry":            payload.Retry,
+		"timeout":          payload.Timeout,
+		"overlap_policy":   payload.OverlapPolicy,
+		"misfire_policy":   payload.MisfirePolicy,
+		"misfire_limit":    payload.MisfireLimit,
+		"timezone":         payload.Timezone,
 		"interval_pattern": payload.IntervalPattern,
+		"depends_on":       marshalDependencies(payload.DependsOn),
+		"start_at":         payload.StartAt.Format(time.RFC3339),
+		"end_at":           payload.EndAt.Format(time.RFC3339),
+		"end_policy":       payload.EndPolicy,
+		"max_runs":         payload.MaxRuns,
+		"max_runs_count":   payload.MaxRunsCount,
+		"max_runs_policy":  payload.MaxRunsPolicy,
+		"stop_reason":      payload.StopReason,
+		"calendars":        strings.Join(payload.Calendars, ","),
+		"jitter":           payload.Jitter,
+		"notifiers":        strings.Join(payload.Notifiers, ","),
 		"type":             payload.Type,
 		"status":           payload.Status,
 		"nsq_topic":        payload.NsqTopic,
 		"nsq_message":      payload.NsqMessage,
 		"register":         payload.Register.Format(time.RFC3339),
+		"trace_parent":     payload.TraceParent,
 		"prev":             payload.Prev.Format(time.RFC3339),
 		"next":             payload.Next.Format(time.RFC3339),
 		"memo":             payload.Memo,
@@ -93,11 +121,15 @@ func SetTaskPayload(payload cronjob.TaskPayload, ttl int64) error {
 	// 任務註冊表
 	key := fmt.Sprintf("TASK_%s_%s", payload.GroupName, payload.JobID)
 
-	return redisCacher.Conn.HSet(key, params, ttl)
+	if err := redisCacher.Conn.WithContext(ctx).HSet(key, params, ttl); err != nil {
+		return err
+	}
+
+	return setDependents(ctx, payload)
 }
 
 // 將 map[string]string 映射到 cronjob.TaskPayload 結構中
-func MapTaskPayload(data map[string]string) cronjob.TaskPayload {
+func MapTaskPayload(ctx context.Context, data map[string]string) cronjob.TaskPayload {
 	var prev, next time.Time
 
 	execRightNow, err := strconv.ParseBool(data["exec_right_now"])
@@ -110,6 +142,26 @@ func MapTaskPayload(data map[string]string) cronjob.TaskPayload {
 		retry = false
 	}
 
+	timeout, err := strconv.ParseInt(data["timeout"], 10, 64)
+	if err != nil {
+		timeout = 0
+	}
+
+	misfireLimit, err := strconv.Atoi(data["misfire_limit"])
+	if err != nil {
+		misfireLimit = 0
+	}
+
+	maxRuns, err := strconv.Atoi(data["max_runs"])
+	if err != nil {
+		maxRuns = 0
+	}
+
+	jitter, err := strconv.ParseInt(data["jitter"], 10, 64)
+	if err != nil {
+		jitter = 0
+	}
+
 	status, err := strconv.Atoi(data["status"])
 	if err != nil {
 		status = 0
@@ -125,8 +177,18 @@ func MapTaskPayload(data map[string]string) cronjob.TaskPayload {
 		prev = time.Time{}
 	}
 
+	startAt, err := time.Parse(time.RFC3339, data["start_at"])
+	if err != nil {
+		startAt = time.Time{}
+	}
+
+	endAt, err := time.Parse(time.RFC3339, data["end_at"])
+	if err != nil {
+		endAt = time.Time{}
+	}
+
 	key := fmt.Sprintf("TIME_%s_%s", data["group_name"], data["job_id"])
-	timeMaps, err := redisCacher.Conn.HGetAll(key)
+	timeMaps, err := redisCacher.Conn.WithContext(ctx).HGetAll(key)
 	if err == nil {
 		if value, ok := timeMaps["prev"]; ok {
 			prev, err = time.Parse(time.RFC3339, value)
@@ -142,28 +204,158 @@ func MapTaskPayload(data map[string]string) cronjob.TaskPayload {
 		}
 	}
 
-	return cronjob.TaskPayload{
+	payload := cronjob.TaskPayload{
 		JobID:           data["job_id"],
 		GroupName:       data["group_name"],
 		Name:            data["name"],
 		ExecRightNow:    execRightNow,
 		RequestUrl:      data["request_url"],
+		RequestMethod:   data["request_method"],
+		RequestHeaders:  unmarshalHeaders(data["request_headers"]),
+		RequestBody:     data["request_body"],
+		SuccessRule:     unmarshalSuccessRule(data["success_rule"]),
+		RetryPolicy:     unmarshalRetryPolicy(data["retry_policy"]),
 		Retry:           retry,
+		Timeout:         timeout,
+		OverlapPolicy:   data["overlap_policy"],
+		MisfirePolicy:   data["misfire_policy"],
+		MisfireLimit:    misfireLimit,
+		Timezone:        data["timezone"],
 		IntervalPattern: data["interval_pattern"],
+		DependsOn:       unmarshalDependencies(data["depends_on"]),
+		StartAt:         startAt,
+		EndAt:           endAt,
+		EndPolicy:       data["end_policy"],
+		MaxRuns:         maxRuns,
+		MaxRunsCount:    data["max_runs_count"],
+		MaxRunsPolicy:   data["max_runs_policy"],
+		StopReason:      data["stop_reason"],
+		Calendars:       splitCalendars(data["calendars"]),
+		Jitter:          jitter,
+		Notifiers:       notify.Split(data["notifiers"]),
 		Type:            data["type"],
 		Status:          status,
 		NsqTopic:        data["nsq_topic"],
 		NsqMessage:      data["nsq_message"],
 		Register:        register,
+		TraceParent:     data["trace_parent"],
 		Prev:            prev,
 		Next:            next,
 		Memo:            data["memo"],
 	}
+
+	if payload.MaxRuns > 0 {
+		payload.Runs, _ = redisCacher.Conn.WithContext(ctx).Get(cronjob.RunsKey(payload.JobID)).Int64()
+	}
+
+	// 時間以任務時區輸出
+	loc := payload.Location()
+	payload.Register = payload.Register.In(loc)
+	payload.Prev = payload.Prev.In(loc)
+	payload.Next = payload.Next.In(loc)
+	payload.StartAt = payload.StartAt.In(loc)
+	payload.EndAt = payload.EndAt.In(loc)
+
+	return payload
+}
+
+// header 以 json 字串存入 redis
+func marshalHeaders(headers map[string]string) string {
+	if len(headers) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(headers)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func unmarshalHeaders(data string) map[string]string {
+	if data == "" {
+		return nil
+	}
+	headers := make(map[string]string)
+	if err := json.Unmarshal([]byte(data), &headers); err != nil {
+		return nil
+	}
+	return headers
+}
+
+// 成功條件以 json 字串存入 redis, 預設條件存空值
+func marshalSuccessRule(rule httptarget.SuccessRule) string {
+	if rule.IsZero() {
+		return ""
+	}
+	b, err := json.Marshal(rule)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func unmarshalSuccessRule(data string) httptarget.SuccessRule {
+	var rule httptarget.SuccessRule
+	if data != "" {
+		json.Unmarshal([]byte(data), &rule)
+	}
+	return rule
+}
+
+// 重試策略以 json 字串存入 redis, 未設定存空值
+func marshalRetryPolicy(policy retry.Policy) string {
+	if policy.IsZero() {
+		return ""
+	}
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func unmarshalRetryPolicy(data string) retry.Policy {
+	var policy retry.Policy
+	if data != "" {
+		json.Unmarshal([]byte(data), &policy)
+	}
+	return policy
+}
+
+// 排除日曆以逗號分隔存入 redis
+func splitCalendars(data string) []string {
+	if data == "" {
+		return nil
+	}
+	return strings.Split(data, ",")
+}
+
+// 上游任務以 json 字串存入 redis, 未設定存空值
+func marshalDependencies(deps []cronjob.Dependency) string {
+	if len(deps) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(deps)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func unmarshalDependencies(data string) []cronjob.Dependency {
+	if data == "" {
+		return nil
+	}
+	var deps []cronjob.Dependency
+	if err := json.Unmarshal([]byte(data), &deps); err != nil {
+		return nil
+	}
+	return deps
 }
 
 // 取得所有註冊清單名字,"TASK_*" 鍵進行掃描獲取
-func GetJobRecords() ([]string, error) {
-	records, err := redisCacher.Conn.Scan("TASK_*")
+func GetJobRecords(ctx context.Context) ([]string, error) {
+	records, err := redisCacher.Conn.WithContext(ctx).Scan("TASK_*")
 	if err != nil {
 		return records, err
 	}
@@ -172,17 +364,36 @@ func GetJobRecords() ([]string, error) {
 	return records, nil
 }
 
+// 註冊的任務數及啟用中的任務數
+func CountJobs(ctx context.Context) (registered, active int, err error) {
+	records, err := GetJobRecords(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	// scrape 時任務數可能很多, 以 pipeline 一次取回狀態
+	statuses, err := redisCacher.Conn.WithContext(ctx).HGetKeys(records, "status")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, status := range statuses {
+		if status == strconv.Itoa(cronjob.StatusActive) {
+			active++
+		}
+	}
+	return len(records), active, nil
+}
+
 // 取得所有註冊資料,獲取 "TASK_*"
-func GetJobsByAll() ([]cronjob.TaskPayload, error) {
+func GetJobsByAll(ctx context.Context) ([]cronjob.TaskPayload, error) {
 	ret := make([]cronjob.TaskPayload, 0)
-	records, err := GetJobRecords()
+	records, err := GetJobRecords(ctx)
 	if err != nil {
 		return ret, err
 	}
 	for _, v := range records {
-		data, err := redisCacher.Conn.HGetAll(v)
+		data, err := redisCacher.Conn.WithContext(ctx).HGetAll(v)
 		if err == nil {
-			TaskPayload := MapTaskPayload(data)
+			TaskPayload := MapTaskPayload(ctx, data)
 			if TaskPayload.JobID != "" {
 				ret = append(ret, TaskPayload)
 			}
@@ -192,11 +403,11 @@ func GetJobsByAll() ([]cronjob.TaskPayload, error) {
 }
 
 // 取得"符合"註冊資料 - By Game
-func GetJobsByGame(groupName, gameType string) ([]cronjob.TaskPayload, error) {
+func GetJobsByGame(ctx context.Context, groupName, gameType string) ([]cronjob.TaskPayload, error) {
 	TaskPayloads := make([]cronjob.TaskPayload, 0)
 
 	key := fmt.Sprintf("TEAM_%s", groupName)
-	groupJobs, err := redisCacher.Conn.HGetAll(key)
+	groupJobs, err := redisCacher.Conn.WithContext(ctx).HGetAll(key)
 	if err != nil {
 		return nil, err
 	}
@@ -214,18 +425,18 @@ func GetJobsByGame(groupName, gameType string) ([]cronjob.TaskPayload, error) {
 
 	if len(jobIDs) > 0 {
 		sort.Strings(jobIDs)
-		TaskPayloads = GetTaskPayloads(groupName, jobIDs)
+		TaskPayloads = GetTaskPayloads(ctx, groupName, jobIDs)
 	}
 
 	return TaskPayloads, nil
 }
 
 // 取得"符合"註冊資料 - By match
-func GetJobsByMatch(groupName, match string) ([]cronjob.TaskPayload, error) {
+func GetJobsByMatch(ctx context.Context, groupName, match string) ([]cronjob.TaskPayload, error) {
 	TaskPayloads := make([]cronjob.TaskPayload, 0)
 
 	key := fmt.Sprintf("TEAM_%s", groupName)
-	groupJobs, err := redisCacher.Conn.HGetAll(key)
+	groupJobs, err := redisCacher.Conn.WithContext(ctx).HGetAll(key)
 	if err != nil {
 		return nil, err
 	}
@@ -239,32 +450,32 @@ func GetJobsByMatch(groupName, match string) ([]cronjob.TaskPayload, error) {
 
 	if len(jobIDs) > 0 {
 		sort.Strings(jobIDs)
-		TaskPayloads = GetTaskPayloads(groupName, jobIDs)
+		TaskPayloads = GetTaskPayloads(ctx, groupName, jobIDs)
 	}
 
 	return TaskPayloads, nil
 }
 
 // 取得"group"註冊資料
-func GetJobsByGroup(groupName string) ([]cronjob.TaskPayload, error) {
+func GetJobsByGroup(ctx context.Context, groupName string) ([]cronjob.TaskPayload, error) {
 	TaskPayloads := make([]cronjob.TaskPayload, 0)
 
 	key := fmt.Sprintf("TEAM_%s", groupName)
-	groupJobs, err := redisCacher.Conn.HGetAll(key)
+	groupJobs, err := redisCacher.Conn.WithContext(ctx).HGetAll(key)
 	if err != nil {
 		return TaskPayloads, err
 	}
 
 	if len(groupJobs) > 0 {
 		jobIDs := lib.SortMapByValue(groupJobs)
-		TaskPayloads = GetTaskPayloads(groupName, jobIDs)
+		TaskPayloads = GetTaskPayloads(ctx, groupName, jobIDs)
 	}
 
 	return TaskPayloads, nil
 }
 
 // 取得定時任務資訊
-func GetTaskPayloads(groupName string, jobIDs []string) []cronjob.TaskPayload {
+func GetTaskPayloads(ctx context.Context, groupName string, jobIDs []string) []cronjob.TaskPayload {
 	var ret []cronjob.TaskPayload
 
 	for _, jobID := range jobIDs {
@@ -275,14 +486,16 @@ func GetTaskPayloads(groupName string, jobIDs []string) []cronjob.TaskPayload {
 		builder.WriteString(jobID)
 		v := builder.String()
 
-		data, err := redisCacher.Conn.HGetAll(v)
+		data, err := redisCacher.Conn.WithContext(ctx).HGetAll(v)
 		if err == nil {
-			job := MapTaskPayload(data)
+			job := MapTaskPayload(ctx, data)
 
 			entryID, ok := cronjob.Mgr.LoadJobMapping(job.JobID)
 			if ok {
-				dataEntry := cronjob.Mgr.Entry(entryID)
-				job.Next = dataEntry.Next
+				// 選主模式下 follower 的 cron 未啟動, 沒有 Next
+				if dataEntry := cronjob.Mgr.Entry(entryID); !dataEntry.Next.IsZero() {
+					job.Next = dataEntry.Next.In(job.Location())
+				}
 			}
 			if job.JobID != "" {
 				ret = append(ret, job)
@@ -294,49 +507,94 @@ func GetTaskPayloads(groupName string, jobIDs []string) []cronjob.TaskPayload {
 }
 
 // 取得定時任務資訊
-func GetTaskPayload(groupName, jobID string) cronjob.TaskPayload {
+func GetTaskPayload(ctx context.Context, groupName, jobID string) cronjob.TaskPayload {
 	var payload cronjob.TaskPayload
 
 	key := fmt.Sprintf("TASK_%s_%s", groupName, jobID)
-	data, err := redisCacher.Conn.HGetAll(key)
+	data, err := redisCacher.Conn.WithContext(ctx).HGetAll(key)
 	if err != nil || len(data) == 0 {
 		return payload
 	}
 
-	payload = MapTaskPayload(data)
+	payload = MapTaskPayload(ctx, data)
 
 	entryID, ok := cronjob.Mgr.LoadJobMapping(payload.JobID)
 	if ok {
-		dataEntry := cronjob.Mgr.Entry(entryID)
-		payload.Next = dataEntry.Next
+		if dataEntry := cronjob.Mgr.Entry(entryID); !dataEntry.Next.IsZero() {
+			payload.Next = dataEntry.Next.In(payload.Location())
+		}
 	}
 
 	return payload
 }
 
+// 群組預設時區, 未設定回傳空值
+func GetGroupTimezone(ctx context.Context, groupName string) string {
+	timezone, _ := redisCacher.Conn.WithContext(ctx).HGet("GROUP_TIMEZONE", groupName).Result()
+	return timezone
+}
+
+// 設定群組預設時區, 空值為清除; 只影響之後新增的任務
+func SetGroupTimezone(ctx context.Context, groupName, timezone string) error {
+	if timezone == "" {
+		return redisCacher.Conn.WithContext(ctx).HDel("GROUP_TIMEZONE", groupName)
+	}
+	return redisCacher.Conn.WithContext(ctx).HSet("GROUP_TIMEZONE", map[string]interface{}{groupName: timezone}, 0)
+}
+
+// 群組預設 jitter(秒), 未設定回傳 0
+func GetGroupJitter(ctx context.Context, groupName string) int64 {
+	jitter, _ := redisCacher.Conn.WithContext(ctx).HGet("GROUP_JITTER", groupName).Int64()
+	return jitter
+}
+
+// 設定群組預設 jitter, 0 為清除; 只影響之後新增的任務
+func SetGroupJitter(ctx context.Context, groupName string, jitter int64) error {
+	if jitter == 0 {
+		return redisCacher.Conn.WithContext(ctx).HDel("GROUP_JITTER", groupName)
+	}
+	return redisCacher.Conn.WithContext(ctx).HSet("GROUP_JITTER", map[string]interface{}{groupName: jitter}, 0)
+}
+
+// 重新啟用自動停止的任務前, 清除停止原因及執行次數
+func ResetStopped(ctx context.Context, payload cronjob.TaskPayload) error {
+	if payload.StopReason == "" {
+		return nil
+	}
+	if err := redisCacher.Conn.WithContext(ctx).Del(cronjob.RunsKey(payload.JobID)); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("TASK_%s_%s", payload.GroupName, payload.JobID)
+	return redisCacher.Conn.WithContext(ctx).HSet(key, map[string]interface{}{"stop_reason": ""}, 0)
+}
+
 // 更新定時任務狀態
-func UpdateJobStatus(groupName, JobID string, status int) error {
+func UpdateJobStatus(ctx context.Context, groupName, JobID string, status int) error {
 	key := fmt.Sprintf("TASK_%s_%s", groupName, JobID)
-	return redisCacher.Conn.HSet(key, map[string]interface{}{"status": status}, 0)
+	return redisCacher.Conn.WithContext(ctx).HSet(key, map[string]interface{}{"status": status}, 0)
 }
 
 // 刪除相關的單一定時任務
-func DeleteJobFromRedis(groupName, name, JobID string) {
+func DeleteJobFromRedis(ctx context.Context, groupName, name, JobID string) {
+	deleteDependents(ctx, GetTaskPayload(ctx, groupName, JobID))
+
 	keys := []string{
 		fmt.Sprintf("TASK_%s_%s", groupName, JobID),
 		fmt.Sprintf("CK_%s_%s", groupName, name),
 		fmt.Sprintf("TIME_%s_%s", groupName, JobID),
+		cronjob.RunsKey(JobID),
+		cronjob.RunsPendingKey(JobID),
 	}
 	for _, key := range keys {
-		redisCacher.Conn.Del(key)
+		redisCacher.Conn.WithContext(ctx).Del(key)
 	}
 
 	key := fmt.Sprintf("TEAM_%s", groupName)
-	redisCacher.Conn.HDel(key, name)
+	redisCacher.Conn.WithContext(ctx).HDel(key, name)
 }
 
 // 刪除相關的定時任務
-func DeleteGroupJobsFromRedis(groupName string) ([]string, error) {
+func DeleteGroupJobsFromRedis(ctx context.Context, groupName string) ([]string, error) {
 
 	var (
 		jobIDs     []string
@@ -345,12 +603,14 @@ func DeleteGroupJobsFromRedis(groupName string) ([]string, error) {
 	jobIDs = make([]string, 0)
 	key := fmt.Sprintf("TEAM_%s", groupName)
 
-	groupJobs, err := redisCacher.Conn.HGetAll(key)
+	groupJobs, err := redisCacher.Conn.WithContext(ctx).HGetAll(key)
 	if err != nil {
 		return jobIDs, err
 	}
 
 	for jobName, jobID := range groupJobs {
+		deleteDependents(ctx, GetTaskPayload(ctx, groupName, jobID))
+
 		delKey := fmt.Sprintf("TASK_%s_%s", groupName, jobID)
 		deleteKeys = append(deleteKeys, delKey)
 
@@ -360,13 +620,15 @@ func DeleteGroupJobsFromRedis(groupName string) ([]string, error) {
 		delKey = fmt.Sprintf("TIME_%s_%s", groupName, jobID)
 		deleteKeys = append(deleteKeys, delKey)
 
+		deleteKeys = append(deleteKeys, cronjob.RunsKey(jobID), cronjob.RunsPendingKey(jobID))
+
 		jobIDs = append(jobIDs, jobID)
 	}
 
 	delKey := fmt.Sprintf("TEAM_%s", groupName)
 	deleteKeys = append(deleteKeys, delKey)
 
-	err = redisCacher.Conn.DelKeys(deleteKeys)
+	err = redisCacher.Conn.WithContext(ctx).DelKeys(deleteKeys)
 	if err != nil {
 		return jobIDs, err
 	}
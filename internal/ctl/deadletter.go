@@ -0,0 +1,31 @@
+package ctl
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/deadletter"
+	"dcron/internal/history"
+	"encoding/json"
+)
+
+// 依 dead letter 保存的任務內容重新執行, 成功後移除該筆 dead letter
+func ReplayDeadLetter(ctx context.Context, groupName, id string) (*history.Record, error) {
+	entry, err := deadletter.Get(ctx, groupName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload cronjob.TaskPayload
+	if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+		return nil, err
+	}
+
+	rec := payload.Replay(ctx, entry.ScheduledAt)
+	if rec.Outcome == history.OutcomeSuccess {
+		if err := deadletter.Delete(ctx, groupName, id); err != nil {
+			return rec, err
+		}
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,75 @@
+package ctl
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/deadletter"
+	"dcron/internal/history"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayDeadLetter(t *testing.T) {
+	setEventTest(t)
+
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	payload := cronjob.TaskPayload{
+		JobID:           "100004",
+		GroupName:       "test",
+		Name:            "job04",
+		RequestUrl:      srv.URL + "/api/ping",
+		RequestMethod:   http.MethodPost,
+		RequestBody:     `{"id":1}`,
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            cronjob.HttpMode,
+		Status:          1,
+	}
+	assert.Nil(t, SetTaskPayload(context.Background(), payload, 0))
+	payload.RunNow()
+
+	page, err := deadletter.Find(context.Background(), payload.GroupName, deadletter.Query{})
+	assert.Nil(t, err)
+	if !assert.Len(t, page.Entries, 1) {
+		return
+	}
+	entry := page.Entries[0]
+	assert.Equal(t, payload.JobID, entry.JobID)
+	assert.Equal(t, 500, entry.Code)
+	assert.NotEmpty(t, entry.HistoryID)
+
+	// 目標仍失敗, 保留 dead letter
+	rec, err := ReplayDeadLetter(context.Background(), payload.GroupName, entry.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, history.OutcomeFailed, rec.Outcome)
+	_, err = deadletter.Get(context.Background(), payload.GroupName, entry.ID)
+	assert.Nil(t, err)
+
+	healthy.Store(true)
+	rec, err = ReplayDeadLetter(context.Background(), payload.GroupName, entry.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, history.OutcomeSuccess, rec.Outcome)
+	_, err = deadletter.Get(context.Background(), payload.GroupName, entry.ID)
+	assert.ErrorIs(t, err, deadletter.ErrNotFound)
+
+	// replay 只寫入執行紀錄, 不會再產生 dead letter
+	page, err = deadletter.Find(context.Background(), payload.GroupName, deadletter.Query{})
+	assert.Nil(t, err)
+	assert.Len(t, page.Entries, 0)
+
+	records, err := history.Find(context.Background(), payload.GroupName, payload.JobID, history.Query{})
+	assert.Nil(t, err)
+	assert.Len(t, records.Records, 3)
+}
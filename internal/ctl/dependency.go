@@ -0,0 +1,179 @@
+package ctl
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/history"
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 下游任務索引中的一筆
+type dependent struct {
+	GroupName string `json:"group_name"` // 下游群組名稱
+	Name      string `json:"name"`       // 下游排程名稱
+	On        string `json:"on"`         // 觸發條件
+}
+
+/*
+ * 下游任務索引, 上游完成時依此找出要觸發的任務
+ * redis資料格式
+ *
+ * key: DEPENDENTS_test_job01
+ * ["test_job02"] = {"group_name":"test","name":"job02","on":"success"}
+ */
+func dependentsKey(groupName, name string) string {
+	return fmt.Sprintf("DEPENDENTS_%s_%s", groupName, name)
+}
+
+// 依群組及名稱取得任務, 不存在時 JobID 為空
+func GetTaskPayloadByName(ctx context.Context, groupName, name string) cronjob.TaskPayload {
+	jobID, _ := redisCacher.Conn.WithContext(ctx).HGet(fmt.Sprintf("TEAM_%s", groupName), name).Result()
+	if jobID == "" {
+		return cronjob.TaskPayload{}
+	}
+	return GetTaskPayload(ctx, groupName, jobID)
+}
+
+// 檢查上游設定並補上預設值, 上游需已註冊且不能形成循環
+func ValidateDependencies(ctx context.Context, payload *cronjob.TaskPayload) error {
+	seen := make(map[string]bool)
+	for i := range payload.DependsOn {
+		dep := &payload.DependsOn[i]
+		if dep.GroupName == "" {
+			dep.GroupName = payload.GroupName
+		}
+		dep.On = strings.ToLower(dep.On)
+		if dep.On == "" {
+			dep.On = cronjob.DependOnSuccess
+		}
+
+		if dep.Name == "" {
+			return errors.New("depends_on name is empty")
+		}
+		if !cronjob.IsDependCondition(dep.On) {
+			return fmt.Errorf("depends_on condition %s is not supported", dep.On)
+		}
+		if dep.GroupName == payload.GroupName && dep.Name == payload.Name {
+			return errors.New("job can not depend on itself")
+		}
+		if seen[dep.String()] {
+			return fmt.Errorf("upstream job %s is duplicated", dep)
+		}
+		seen[dep.String()] = true
+
+		if GetTaskPayloadByName(ctx, dep.GroupName, dep.Name).JobID == "" {
+			return fmt.Errorf("upstream job %s is not registered", dep)
+		}
+	}
+
+	if path := findCycle(ctx, *payload); len(path) > 0 {
+		return fmt.Errorf("dependency cycle: %s", strings.Join(path, " -> "))
+	}
+
+	return nil
+}
+
+// 沿著上游往回找, 回到自己表示形成循環, 回傳循環路徑
+func findCycle(ctx context.Context, payload cronjob.TaskPayload) []string {
+	self := cronjob.Dependency{GroupName: payload.GroupName, Name: payload.Name}.String()
+	visited := make(map[string]bool)
+
+	var walk func(deps []cronjob.Dependency, path []string) []string
+	walk = func(deps []cronjob.Dependency, path []string) []string {
+		for _, dep := range deps {
+			key := dep.String()
+			if key == self {
+				return append(path, key)
+			}
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+
+			upstream := GetTaskPayloadByName(ctx, dep.GroupName, dep.Name)
+			if cycle := walk(upstream.DependsOn, append(path, key)); len(cycle) > 0 {
+				return cycle
+			}
+		}
+		return nil
+	}
+
+	return walk(payload.DependsOn, []string{self})
+}
+
+// 寫入下游任務索引
+func setDependents(ctx context.Context, payload cronjob.TaskPayload) error {
+	for _, dep := range payload.DependsOn {
+		b, err := json.Marshal(dependent{GroupName: payload.GroupName, Name: payload.Name, On: dep.On})
+		if err != nil {
+			return err
+		}
+		field := fmt.Sprintf("%s_%s", payload.GroupName, payload.Name)
+		if err := redisCacher.Conn.WithContext(ctx).HSet(dependentsKey(dep.GroupName, dep.Name), map[string]interface{}{field: string(b)}, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 移除下游任務索引, 上游刪除後重新註冊仍會觸發其他下游
+func deleteDependents(ctx context.Context, payload cronjob.TaskPayload) {
+	field := fmt.Sprintf("%s_%s", payload.GroupName, payload.Name)
+	for _, dep := range payload.DependsOn {
+		redisCacher.Conn.WithContext(ctx).HDel(dependentsKey(dep.GroupName, dep.Name), field)
+	}
+}
+
+// 上游完成後通知叢集, 由負責下游任務的節點執行
+func TriggerDependents(ctx context.Context, j *cronjob.TaskPayload, rec *history.Record) {
+	logger := server.GetServerInstance().GetLogger()
+
+	fields, err := redisCacher.Conn.WithContext(ctx).HGetAll(dependentsKey(j.GroupName, j.Name))
+	if err != nil {
+		logger.WithField("err", err.Error()).Error("job dependents load error")
+		return
+	}
+
+	for field, value := range fields {
+		var d dependent
+		if err := json.Unmarshal([]byte(value), &d); err != nil {
+			continue
+		}
+		if !(cronjob.Dependency{On: d.On}).Match(rec.Outcome) {
+			continue
+		}
+
+		downstream := GetTaskPayloadByName(ctx, d.GroupName, d.Name)
+		if downstream.JobID == "" {
+			// 下游已不存在
+			redisCacher.Conn.WithContext(ctx).HDel(dependentsKey(j.GroupName, j.Name), field)
+			continue
+		}
+
+		err := PublishEvent(cronjob.PubJob{
+			Event:     "trigger",
+			JobID:     downstream.JobID,
+			GroupName: downstream.GroupName,
+			Name:      downstream.Name,
+			Upstream: &history.Upstream{
+				JobID:     j.JobID,
+				GroupName: j.GroupName,
+				Name:      j.Name,
+				HistoryID: rec.ID,
+				Outcome:   rec.Outcome,
+			},
+		})
+		if err != nil {
+			logger.WithFields(map[string]interface{}{
+				"job_id":        j.JobID,
+				"downstream_id": downstream.JobID,
+				"err":           err.Error(),
+			}).Error("job dependents trigger error")
+		}
+	}
+}
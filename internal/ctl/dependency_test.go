@@ -0,0 +1,115 @@
+package ctl
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/history"
+	"dcron/internal/redisCacher"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setDependencyJobs(t *testing.T, jobs ...cronjob.TaskPayload) {
+	for _, job := range jobs {
+		assert.Nil(t, SetTaskPayload(context.Background(), job, 0))
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	setEventTest(t)
+
+	setDependencyJobs(t,
+		cronjob.TaskPayload{JobID: "200001", GroupName: "test", Name: "extract", IntervalPattern: "0 0 * * * *", Type: cronjob.TestMode, Status: 1},
+		cronjob.TaskPayload{JobID: "200002", GroupName: "test", Name: "transform", Type: cronjob.TestMode, Status: 1,
+			DependsOn: []cronjob.Dependency{{GroupName: "test", Name: "extract", On: cronjob.DependOnSuccess}}},
+		cronjob.TaskPayload{JobID: "200003", GroupName: "report", Name: "load", Type: cronjob.TestMode, Status: 1,
+			DependsOn: []cronjob.Dependency{{GroupName: "test", Name: "transform", On: cronjob.DependOnAny}}},
+	)
+
+	t.Run("defaults", func(t *testing.T) {
+		payload := cronjob.TaskPayload{GroupName: "test", Name: "notify", DependsOn: []cronjob.Dependency{{Name: "transform"}}}
+		assert.Nil(t, ValidateDependencies(context.Background(), &payload))
+		assert.Equal(t, "test", payload.DependsOn[0].GroupName)
+		assert.Equal(t, cronjob.DependOnSuccess, payload.DependsOn[0].On)
+	})
+
+	tests := []struct {
+		name    string
+		payload cronjob.TaskPayload
+		err     string
+	}{
+		{"self", cronjob.TaskPayload{GroupName: "test", Name: "notify", DependsOn: []cronjob.Dependency{{Name: "notify"}}}, "job can not depend on itself"},
+		{"not registered", cronjob.TaskPayload{GroupName: "test", Name: "notify", DependsOn: []cronjob.Dependency{{Name: "missing"}}}, "upstream job test/missing is not registered"},
+		{"condition", cronjob.TaskPayload{GroupName: "test", Name: "notify", DependsOn: []cronjob.Dependency{{Name: "extract", On: "done"}}}, "depends_on condition done is not supported"},
+		{"duplicated", cronjob.TaskPayload{GroupName: "test", Name: "notify", DependsOn: []cronjob.Dependency{{Name: "extract"}, {GroupName: "test", Name: "extract"}}}, "upstream job test/extract is duplicated"},
+		{"cycle", cronjob.TaskPayload{GroupName: "test", Name: "extract", DependsOn: []cronjob.Dependency{{GroupName: "report", Name: "load"}}}, "dependency cycle: test/extract -> report/load -> test/transform -> test/extract"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDependencies(context.Background(), &tt.payload)
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestTriggerDependents(t *testing.T) {
+	setEventTest(t)
+	cronjob.OnCompleted(TriggerDependents)
+	t.Cleanup(func() { cronjob.OnCompleted(nil) })
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	upstream := cronjob.TaskPayload{JobID: "200011", GroupName: "test", Name: "upstream", IntervalPattern: "0 0 0 1 1 *", Type: cronjob.TestMode, Status: 1}
+	broken := cronjob.TaskPayload{JobID: "200012", GroupName: "test", Name: "broken", IntervalPattern: "0 0 0 1 1 *", Type: cronjob.HttpMode, RequestUrl: srv.URL, Status: 1}
+	onSuccess := cronjob.TaskPayload{JobID: "200013", GroupName: "test", Name: "on_success", Type: cronjob.TestMode, Status: 1,
+		DependsOn: []cronjob.Dependency{{GroupName: "test", Name: "upstream", On: cronjob.DependOnSuccess}}}
+	onFailure := cronjob.TaskPayload{JobID: "200014", GroupName: "test", Name: "on_failure", Type: cronjob.TestMode, Status: 1,
+		DependsOn: []cronjob.Dependency{{GroupName: "test", Name: "upstream", On: cronjob.DependOnFailure}, {GroupName: "test", Name: "broken", On: cronjob.DependOnFailure}}}
+	paused := cronjob.TaskPayload{JobID: "200015", GroupName: "test", Name: "paused", Type: cronjob.TestMode, Status: 0,
+		DependsOn: []cronjob.Dependency{{GroupName: "test", Name: "upstream", On: cronjob.DependOnAny}}}
+	setDependencyJobs(t, upstream, broken, onSuccess, onFailure, paused)
+
+	records := func(job cronjob.TaskPayload) []history.Record {
+		page, _ := history.Find(context.Background(), job.GroupName, job.JobID, history.Query{})
+		return page.Records
+	}
+
+	rec := upstream.RunManual(context.Background())
+	if assert.NotNil(t, rec) {
+		assert.Equal(t, history.OutcomeSuccess, rec.Outcome)
+	}
+	assert.Eventually(t, func() bool { return len(records(onSuccess)) == 1 }, time.Second, 10*time.Millisecond)
+
+	got := records(onSuccess)[0]
+	assert.Equal(t, history.TriggerDependency, got.Trigger)
+	if assert.NotNil(t, got.Upstream) {
+		assert.Equal(t, upstream.JobID, got.Upstream.JobID)
+		assert.Equal(t, rec.ID, got.Upstream.HistoryID)
+		assert.Equal(t, history.OutcomeSuccess, got.Upstream.Outcome)
+	}
+	assert.Empty(t, records(onFailure))
+	assert.Empty(t, records(paused))
+
+	// 同一次上游執行只觸發一次
+	assert.Nil(t, onSuccess.RunTriggered(*got.Upstream))
+
+	broken.RunNow()
+	assert.Eventually(t, func() bool { return len(records(onFailure)) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, broken.JobID, records(onFailure)[0].Upstream.JobID)
+
+	// 下游刪除後移除索引
+	DeleteJobFromRedis(context.Background(), onSuccess.GroupName, onSuccess.Name, onSuccess.JobID)
+	fields, err := redisCacher.Conn.HGetAll(dependentsKey(upstream.GroupName, upstream.Name))
+	assert.Nil(t, err)
+	assert.NotContains(t, fields, "test_on_success")
+	assert.Contains(t, fields, "test_on_failure")
+}
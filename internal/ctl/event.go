@@ -1,31 +1,122 @@
 package ctl
 
 import (
+	"context"
 	"dcron/internal/cronjob"
+	"dcron/internal/leader"
+	"dcron/internal/redisCacher"
+	"dcron/internal/shard"
+	"dcron/server"
+	"encoding/json"
 	"strings"
 
 	"github.com/robfig/cron/v3"
 )
 
+// 叢集事件頻道, 所有節點都會訂閱
+const EventChannel = "DCRON_EVENT"
+
+// 本節點名稱, 用來過濾自己發出的事件
+var hostName string
+
+// 訂閱叢集事件
+func EventInit() error {
+	hostName = server.GetServerInstance().GetHostName()
+	cronjob.OnCompleted(TriggerDependents)
+	cronjob.OnStopped(BroadcastStopped)
+	return redisCacher.Conn.Subscribe(ReceiveEvent, EventChannel)
+}
+
 func EventForAddSchedule(payload cronjob.TaskPayload) (cron.EntryID, error) {
 	return AddJobSchedule(payload)
 }
 
+// 本節點執行事件後, 再通知叢集其他節點
+func PublishEvent(pub cronjob.PubJob) error {
+	EventHandling(pub)
+	return BroadcastEvent(pub)
+}
+
+// 只通知叢集其他節點, 本節點已自行處理
+func BroadcastEvent(pub cronjob.PubJob) error {
+	pub.HostName = hostName
+	b, err := json.Marshal(pub)
+	if err != nil {
+		return err
+	}
+	return redisCacher.Conn.Publish(EventChannel, string(b))
+}
+
+// 任務自動停止後通知其他節點移出排程
+// 狀態已由停止的節點寫入 redis, 使用 delete 事件避免其他節點覆寫狀態
+func BroadcastStopped(j *cronjob.TaskPayload) {
+	err := BroadcastEvent(cronjob.PubJob{
+		Event:     "delete",
+		JobID:     j.JobID,
+		GroupName: j.GroupName,
+		Name:      j.Name,
+	})
+	if err != nil {
+		server.GetServerInstance().GetLogger().WithField("job_id", j.JobID).Errorf("job stop broadcast error: %v", err)
+	}
+}
+
+// 收到叢集事件, 自己發出的事件已處理過, 直接略過
+func ReceiveEvent(channel string, data []byte) error {
+	var pub cronjob.PubJob
+	if err := json.Unmarshal(data, &pub); err != nil {
+		return err
+	}
+	if pub.HostName == hostName {
+		return nil
+	}
+	EventHandling(pub)
+	return nil
+}
+
 func EventHandling(pub cronjob.PubJob) {
 	switch strings.ToLower(pub.Event) {
 	case "add":
-		payload := GetTaskPayload(pub.GroupName, pub.JobID)
-		AddJobFromSchedule(payload)
+		payload := GetTaskPayload(context.Background(), pub.GroupName, pub.JobID)
+		if payload.JobID != "" {
+			AddJobFromSchedule(payload)
+		}
 	case "pause":
 		PauseJobFromSchedule(pub.GroupName, pub.JobID)
 	case "active":
-		payload := GetTaskPayload(pub.GroupName, pub.JobID)
-		ActiveJobFromSchedule(payload)
+		payload := GetTaskPayload(context.Background(), pub.GroupName, pub.JobID)
+		if payload.JobID != "" {
+			ActiveJobFromSchedule(payload)
+		}
 	case "delete":
 		RemoveJobFromSchedule(pub.JobID)
+	case "trigger":
+		// 由負責下游任務的節點執行, 選主模式下只有 leader 執行
+		if pub.Upstream == nil || !shard.Owns(pub.JobID) || !leader.Fence() {
+			return
+		}
+		payload := GetTaskPayload(context.Background(), pub.GroupName, pub.JobID)
+		if payload.JobID == "" || payload.Status != 1 {
+			return
+		}
+		go payload.RunTriggered(*pub.Upstream)
+	case "cancel":
+		if n := cronjob.CancelRunning(pub.JobID); n > 0 {
+			server.GetServerInstance().GetLogger().WithFields(map[string]interface{}{
+				"job_id":    pub.JobID,
+				"cancelled": n,
+			}).Info("job cronjob cancel")
+		}
+	case "reconcile":
+		// 各節點修正自己的 cron, 結果只記錄在 log
+		go Reconcile(context.Background())
 	case "stop":
 		cronjob.Mgr.Stop()
 	case "start":
+		// 選主模式下 follower 不啟動 cron
+		if leader.Enabled() && !leader.IsLeader() {
+			return
+		}
 		cronjob.Mgr.Start()
 	}
 }
@@ -0,0 +1,225 @@
+package ctl
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/history"
+	"dcron/internal/redisCacher"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	// 訂閱的 goroutine 會讀取, 只在測試開始前設定一次
+	hostName = "node-a"
+	os.Exit(m.Run())
+}
+
+func setEventTest(t *testing.T) {
+	redisCacher.SetMiniredis()
+	cronjob.ConfigInit()
+	cronjob.Mgr.StartInit(context.Background(), true)
+	t.Cleanup(cronjob.Mgr.Stop)
+
+	subscribe(t, ReceiveEvent)
+}
+
+// 訂閱叢集事件, 測試結束時取消訂閱
+func subscribe(t *testing.T, callable func(channel string, data []byte) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	err := redisCacher.Conn.WithContext(ctx).Subscribe(callable, EventChannel)
+	assert.Nil(t, err)
+}
+
+// 模擬其他節點發出的事件
+func publishFrom(t *testing.T, host string, pub cronjob.PubJob) {
+	pub.HostName = host
+	b, _ := json.Marshal(pub)
+	err := redisCacher.Conn.Publish(EventChannel, string(b))
+	assert.Nil(t, err)
+}
+
+func isScheduled(jobID string) func() bool {
+	return func() bool {
+		_, ok := cronjob.Mgr.LoadJobMapping(jobID)
+		return ok
+	}
+}
+
+func TestReceiveEvent(t *testing.T) {
+	setEventTest(t)
+
+	payload := cronjob.TaskPayload{
+		JobID:           "100001",
+		GroupName:       "test",
+		Name:            "job01",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            cronjob.TestMode,
+		Status:          1,
+	}
+	err := SetTaskPayload(context.Background(), payload, 0)
+	assert.Nil(t, err)
+
+	t.Run("add", func(t *testing.T) {
+		publishFrom(t, "node-b", cronjob.PubJob{Event: "add", JobID: payload.JobID, GroupName: payload.GroupName})
+		assert.Eventually(t, isScheduled(payload.JobID), time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		publishFrom(t, "node-b", cronjob.PubJob{Event: "pause", JobID: payload.JobID, GroupName: payload.GroupName})
+		assert.Eventually(t, func() bool { return !isScheduled(payload.JobID)() }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, 0, GetTaskPayload(context.Background(), payload.GroupName, payload.JobID).Status)
+	})
+
+	t.Run("active", func(t *testing.T) {
+		publishFrom(t, "node-b", cronjob.PubJob{Event: "active", JobID: payload.JobID, GroupName: payload.GroupName})
+		assert.Eventually(t, isScheduled(payload.JobID), time.Second, 10*time.Millisecond)
+		assert.Equal(t, 1, GetTaskPayload(context.Background(), payload.GroupName, payload.JobID).Status)
+	})
+
+	t.Run("own event is ignored", func(t *testing.T) {
+		publishFrom(t, "node-a", cronjob.PubJob{Event: "delete", JobID: payload.JobID, GroupName: payload.GroupName})
+		assert.Never(t, func() bool { return !isScheduled(payload.JobID)() }, 200*time.Millisecond, 10*time.Millisecond)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		publishFrom(t, "node-b", cronjob.PubJob{Event: "delete", JobID: payload.JobID, GroupName: payload.GroupName})
+		assert.Eventually(t, func() bool { return !isScheduled(payload.JobID)() }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("stop and start", func(t *testing.T) {
+		publishFrom(t, "node-b", cronjob.PubJob{Event: "stop"})
+		assert.Eventually(t, func() bool { return !cronjob.Mgr.GetRunning() }, time.Second, 10*time.Millisecond)
+
+		publishFrom(t, "node-b", cronjob.PubJob{Event: "start"})
+		assert.Eventually(t, cronjob.Mgr.GetRunning, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestBroadcastEvent(t *testing.T) {
+	setEventTest(t)
+
+	received := make(chan cronjob.PubJob, 1)
+	subscribe(t, func(channel string, data []byte) error {
+		var pub cronjob.PubJob
+		json.Unmarshal(data, &pub)
+		received <- pub
+		return nil
+	})
+
+	err := BroadcastEvent(cronjob.PubJob{Event: "pause", JobID: "100002", GroupName: "test"})
+	assert.Nil(t, err)
+
+	select {
+	case pub := <-received:
+		assert.Equal(t, "node-a", pub.HostName)
+		assert.Equal(t, "pause", pub.Event)
+		assert.Equal(t, "100002", pub.JobID)
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+}
+
+func TestReceiveCancelEvent(t *testing.T) {
+	setEventTest(t)
+
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	payload := cronjob.TaskPayload{
+		JobID:           "100003",
+		GroupName:       "test",
+		Name:            "job03",
+		RequestUrl:      srv.URL,
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            cronjob.HttpMode,
+		Timeout:         60,
+		Status:          1,
+	}
+	assert.Nil(t, SetTaskPayload(context.Background(), payload, 0))
+
+	finished := make(chan struct{})
+	go func() {
+		payload.RunNow()
+		close(finished)
+	}()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("job not started")
+	}
+
+	publishFrom(t, "node-b", cronjob.PubJob{Event: "cancel", JobID: payload.JobID, GroupName: payload.GroupName})
+
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job not cancelled")
+	}
+	assert.Equal(t, 0, cronjob.CancelRunning(payload.JobID))
+
+	page, err := history.Find(context.Background(), payload.GroupName, payload.JobID, history.Query{})
+	assert.Nil(t, err)
+	if assert.Len(t, page.Records, 1) {
+		assert.Equal(t, history.OutcomeCancelled, page.Records[0].Outcome)
+		assert.Equal(t, 1, page.Records[0].Attempts)
+	}
+}
+
+func TestBroadcastStopped(t *testing.T) {
+	setEventTest(t)
+	cronjob.OnStopped(BroadcastStopped)
+	t.Cleanup(func() { cronjob.OnStopped(nil) })
+
+	received := make(chan cronjob.PubJob, 1)
+	subscribe(t, func(channel string, data []byte) error {
+		var pub cronjob.PubJob
+		json.Unmarshal(data, &pub)
+		received <- pub
+		return nil
+	})
+
+	payload := cronjob.TaskPayload{
+		JobID:           "100005",
+		GroupName:       "test",
+		Name:            "job05",
+		IntervalPattern: "0 0 0 1 1 *",
+		Type:            cronjob.TestMode,
+		Status:          1,
+		MaxRuns:         1,
+		MaxRunsPolicy:   cronjob.MaxRunsDelete,
+	}
+	assert.Nil(t, SetTaskPayload(context.Background(), payload, 0))
+
+	// 本節點 node-a 執行後刪除任務, 通知其他節點
+	payload.RunNow()
+	assert.Equal(t, "", GetTaskPayload(context.Background(), payload.GroupName, payload.JobID).JobID)
+
+	var pub cronjob.PubJob
+	select {
+	case pub = <-received:
+		assert.Equal(t, "node-a", pub.HostName)
+		assert.Equal(t, "delete", pub.Event)
+		assert.Equal(t, payload.JobID, pub.JobID)
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	// node-b 仍有排程, 收到事件後移出
+	_, err := AddJobSchedule(payload)
+	assert.Nil(t, err)
+	assert.True(t, isScheduled(payload.JobID)())
+	EventHandling(pub)
+	assert.False(t, isScheduled(payload.JobID)())
+}
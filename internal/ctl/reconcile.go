@@ -0,0 +1,255 @@
+package ctl
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/lib"
+	"dcron/internal/redisCacher"
+	"dcron/internal/shard"
+	"dcron/server"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 剛註冊的任務及註冊鎖可能還在新增中, 對帳時略過
+const reconcileGrace = 60 * time.Second
+
+// 對帳結果, 只列出有修正的項目
+type ReconcileReport struct {
+	HostName    string   `json:"host_name" example:"dcron-1"`              // 執行對帳的節點
+	Added       []string `json:"added" example:"500001"`                   // 補回 cron 的 job_id
+	Removed     []string `json:"removed" example:"500002"`                 // 移出 cron 的 job_id, 已刪除、非啟用或不由本節點負責
+	Entries     []int    `json:"entries" example:"12"`                     // 移除沒有對應任務的 cron entry id
+	TeamAdded   []string `json:"team_added" example:"test/job01"`          // 補回的群組欄位 group_name/name
+	TeamRemoved []string `json:"team_removed" example:"test/job02"`        // 移除指向不存在任務的群組欄位 group_name/name
+	Locks       []string `json:"locks" example:"CK_test_job02"`            // 刪除的註冊鎖
+	Times       []string `json:"times" example:"TIME_test_500002"`         // 刪除的執行時間紀錄
+	Errors      []string `json:"errors" example:"500003: invalid pattern"` // 無法修正的項目
+	Duration    int64    `json:"duration_ms" example:"15"`                 // 對帳時間(毫秒)
+}
+
+// 修正的項目數
+func (r ReconcileReport) Fixed() int {
+	return len(r.Added) + len(r.Removed) + len(r.Entries) + len(r.TeamAdded) + len(r.TeamRemoved) + len(r.Locks) + len(r.Times)
+}
+
+/*
+ * 比對 redis 註冊資料與本節點的 cron, 修正不一致的狀態
+ * 1. 啟用且由本節點負責的任務不在 cron 中時補回, 已刪除或非啟用的任務移出 cron
+ * 2. 移除沒有對應任務的 cron entry
+ * 3. 補回或移除 TEAM_ 欄位, 刪除沒有對應任務的 CK_ 及 TIME_
+ *
+ * cron 為各節點各自的狀態, 每個節點都需執行; redis 的清理重複執行不影響結果
+ */
+func Reconcile(ctx context.Context) ReconcileReport {
+	start := time.Now()
+	report := ReconcileReport{
+		HostName:    server.GetServerInstance().GetHostName(),
+		Added:       make([]string, 0),
+		Removed:     make([]string, 0),
+		Entries:     make([]int, 0),
+		TeamAdded:   make([]string, 0),
+		TeamRemoved: make([]string, 0),
+		Locks:       make([]string, 0),
+		Times:       make([]string, 0),
+		Errors:      make([]string, 0),
+	}
+
+	// 讀取失敗時不做任何修正, 避免誤刪
+	jobs, err := GetJobsByAll(ctx)
+	if err == nil {
+		reconcileSchedule(jobs, &report)
+		err = reconcileRedis(ctx, jobs, &report)
+	}
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	report.Duration = time.Since(start).Milliseconds()
+
+	logger := server.GetServerInstance().GetLogger().WithFields(map[string]interface{}{
+		"func":         "reconcile",
+		"added":        len(report.Added),
+		"removed":      len(report.Removed),
+		"entries":      len(report.Entries),
+		"team_added":   len(report.TeamAdded),
+		"team_removed": len(report.TeamRemoved),
+		"locks":        len(report.Locks),
+		"times":        len(report.Times),
+		"errors":       len(report.Errors),
+	})
+	if report.Fixed() > 0 || len(report.Errors) > 0 {
+		logger.Warn("Reconcile fixed drift")
+	} else {
+		logger.Debug("Reconcile end")
+	}
+
+	return report
+}
+
+// 定期對帳, RECONCILE_INTERVAL 為 0 時不執行
+func RunReconciler(ctx context.Context) {
+	interval := server.GetServerInstance().GetEnv().ReconcileInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// 停機中或尚未匯入完成
+			if !cronjob.Mgr.GetPingSuccessful() || !cronjob.Mgr.GetImported() {
+				continue
+			}
+			Reconcile(ctx)
+		}
+	}
+}
+
+func reconcileSchedule(jobs []cronjob.TaskPayload, report *ReconcileReport) {
+	now := time.Now()
+	mappings := cronjob.Mgr.JobMappings()
+
+	registered := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		registered[job.JobID] = true
+
+		entryID, loaded := mappings[job.JobID]
+		// 對應的 entry 已不在 cron 中, 重新載入
+		if loaded && cronjob.Mgr.Entry(entryID).ID == 0 {
+			cronjob.Mgr.DeleteJobMapping(job.JobID)
+			loaded = false
+		}
+
+		scheduled := job.Status == cronjob.StatusActive && shard.Owns(job.JobID) && !job.DependencyOnly()
+		switch {
+		case scheduled && !loaded:
+			// 等待延遲載入、剛註冊或已過執行時間的一次性任務不補回
+			if cronjob.Mgr.Delayed(job.JobID) || now.Sub(job.Register) < reconcileGrace || lib.ShouldExecuteNow(job.Memo) {
+				continue
+			}
+			if _, err := AddJobSchedule(job); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", job.JobID, err))
+				continue
+			}
+			report.Added = append(report.Added, job.JobID)
+		case !scheduled && loaded:
+			RemoveJobFromSchedule(job.JobID)
+			report.Removed = append(report.Removed, job.JobID)
+		}
+	}
+
+	// redis 已刪除但仍在 cron 中
+	for jobID := range mappings {
+		if !registered[jobID] {
+			RemoveJobFromSchedule(jobID)
+			report.Removed = append(report.Removed, jobID)
+		}
+	}
+
+	referenced := make(map[int]bool)
+	for _, entryID := range cronjob.Mgr.JobMappings() {
+		referenced[int(entryID)] = true
+	}
+	for _, entry := range cronjob.Mgr.Entries() {
+		payload, ok := entry.Job.(*cronjob.TaskPayload)
+		if !ok || referenced[int(entry.ID)] || now.Sub(payload.Register) < reconcileGrace {
+			continue
+		}
+		cronjob.Mgr.Remove(entry.ID)
+		report.Entries = append(report.Entries, int(entry.ID))
+	}
+}
+
+func reconcileRedis(ctx context.Context, jobs []cronjob.TaskPayload, report *ReconcileReport) error {
+	byID := make(map[string]cronjob.TaskPayload, len(jobs))
+	for _, job := range jobs {
+		byID[job.GroupName+"_"+job.JobID] = job
+	}
+
+	groups, err := FetchGroupList(ctx)
+	if err != nil {
+		return err
+	}
+
+	locks := make(map[string]bool)
+	teams := make(map[string]bool)
+	for _, groupName := range groups {
+		fields, err := redisCacher.Conn.WithContext(ctx).HGetAll(fmt.Sprintf("TEAM_%s", groupName))
+		if err != nil {
+			return err
+		}
+		for name, jobID := range fields {
+			teams[groupName+"_"+name] = true
+			lock := fmt.Sprintf("CK_%s_%s", groupName, name)
+			if _, ok := byID[groupName+"_"+jobID]; ok || lockInUse(ctx, lock) {
+				locks[lock] = true
+				continue
+			}
+			if err := redisCacher.Conn.WithContext(ctx).HDel(fmt.Sprintf("TEAM_%s", groupName), name); err != nil {
+				return err
+			}
+			report.TeamRemoved = append(report.TeamRemoved, groupName+"/"+name)
+		}
+	}
+
+	times := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		times[fmt.Sprintf("TIME_%s_%s", job.GroupName, job.JobID)] = true
+		locks[fmt.Sprintf("CK_%s_%s", job.GroupName, job.Name)] = true
+		if teams[job.GroupName+"_"+job.Name] {
+			continue
+		}
+		if err := SetJobGroup(ctx, job.GroupName, job.Name, job.JobID); err != nil {
+			return err
+		}
+		teams[job.GroupName+"_"+job.Name] = true
+		report.TeamAdded = append(report.TeamAdded, job.GroupName+"/"+job.Name)
+	}
+
+	records, err := redisCacher.Conn.WithContext(ctx).Scan("CK_*")
+	if err != nil {
+		return err
+	}
+	for _, key := range records {
+		if locks[key] || lockInUse(ctx, key) {
+			continue
+		}
+		if err := redisCacher.Conn.WithContext(ctx).Del(key); err != nil {
+			return err
+		}
+		report.Locks = append(report.Locks, key)
+	}
+
+	records, err = redisCacher.Conn.WithContext(ctx).Scan("TIME_*")
+	if err != nil {
+		return err
+	}
+	for _, key := range records {
+		if times[key] {
+			continue
+		}
+		if err := redisCacher.Conn.WithContext(ctx).Del(key); err != nil {
+			return err
+		}
+		report.Times = append(report.Times, key)
+	}
+
+	return nil
+}
+
+// 註冊鎖是否剛取得, 新增任務可能還在寫入註冊資料
+func lockInUse(ctx context.Context, key string) bool {
+	val, err := redisCacher.Conn.WithContext(ctx).Get(key).Result()
+	if err != nil {
+		return false
+	}
+	// 舊版的鎖值為 1, 視為過期
+	acquired, _ := strconv.ParseInt(val, 10, 64)
+	return time.Since(time.Unix(acquired, 0)) < reconcileGrace
+}
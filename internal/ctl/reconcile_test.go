@@ -0,0 +1,82 @@
+package ctl
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/redisCacher"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	setEventTest(t)
+
+	newJob := func(jobID, name string, status int) cronjob.TaskPayload {
+		payload := cronjob.TaskPayload{
+			JobID:           jobID,
+			GroupName:       "test",
+			Name:            name,
+			IntervalPattern: "0 0 0 1 1 *",
+			Type:            cronjob.TestMode,
+			Status:          status,
+		}
+		assert.Nil(t, SetTaskPayload(context.Background(), payload, 0))
+		return payload
+	}
+
+	// 遺漏的排程
+	missing := newJob("600001", "job01", 1)
+
+	// redis 已刪除但其他節點沒收到事件
+	deleted := newJob("600002", "job02", 1)
+	_, err := AddJobSchedule(deleted)
+	assert.Nil(t, err)
+	DeleteJobFromRedis(context.Background(), deleted.GroupName, deleted.Name, deleted.JobID)
+
+	// 已暫停但仍在 cron 中
+	paused := newJob("600003", "job03", 1)
+	_, err = AddJobSchedule(paused)
+	assert.Nil(t, err)
+	assert.Nil(t, UpdateJobStatus(context.Background(), paused.GroupName, paused.JobID, 0))
+
+	// 沒有對應任務的 cron entry
+	orphan, err := cronjob.Mgr.AddTask(newJob("600004", "job04", 0))
+	assert.Nil(t, err)
+
+	// 群組欄位遺失
+	unlisted := newJob("600005", "job05", 0)
+	assert.Nil(t, redisCacher.Conn.HDel("TEAM_test", unlisted.Name))
+
+	// 新增失敗留下的群組欄位及註冊鎖
+	assert.Nil(t, SetJobGroup(context.Background(), "test", "job06", "600006"))
+	assert.Nil(t, redisCacher.Conn.Set("CK_test_job06", 1, 0))
+	assert.Nil(t, redisCacher.Conn.Set("CK_test_job07", time.Now().Add(-2*reconcileGrace).Unix(), 0))
+	assert.Nil(t, redisCacher.Conn.HSet("TIME_test_600006", map[string]interface{}{"prev": "1"}, 0))
+
+	// 新增中的任務
+	ok, err := AcquireLock(context.Background(), "test", "job08", 0)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	report := Reconcile(context.Background())
+	assert.Equal(t, []string{missing.JobID}, report.Added)
+	assert.ElementsMatch(t, []string{deleted.JobID, paused.JobID}, report.Removed)
+	assert.Equal(t, []int{int(orphan)}, report.Entries)
+	assert.Equal(t, []string{"test/job05"}, report.TeamAdded)
+	assert.Equal(t, []string{"test/job06"}, report.TeamRemoved)
+	assert.ElementsMatch(t, []string{"CK_test_job06", "CK_test_job07"}, report.Locks)
+	assert.Equal(t, []string{"TIME_test_600006"}, report.Times)
+	assert.Empty(t, report.Errors)
+
+	assert.True(t, isScheduled(missing.JobID)())
+	assert.False(t, isScheduled(deleted.JobID)())
+	assert.False(t, isScheduled(paused.JobID)())
+	assert.Equal(t, 1, len(cronjob.Mgr.Entries()))
+	assert.Equal(t, unlisted.JobID, redisCacher.Conn.HGet("TEAM_test", unlisted.Name).Val())
+	assert.NotEqual(t, "", redisCacher.Conn.Get("CK_test_job08").Val())
+
+	// 已修正後不再有異動
+	assert.Equal(t, 0, Reconcile(context.Background()).Fixed())
+}
@@ -1,7 +1,10 @@
 package ctl
 
 import (
+	"context"
 	"dcron/internal/cronjob"
+	"dcron/internal/shard"
+	"dcron/server"
 
 	"github.com/robfig/cron/v3"
 )
@@ -17,9 +20,14 @@ const (
 )
 
 func AddJobSchedule(payload cronjob.TaskPayload) (entryID cron.EntryID, err error) {
+	// 分片模式由負責的節點載入, 只由上游觸發的任務不註冊到 cron
+	if !shard.Owns(payload.JobID) || payload.DependencyOnly() {
+		return entryID, nil
+	}
+
 	_, ok := cronjob.Mgr.LoadJobMapping(payload.JobID)
 	if !ok {
-		entryID, err = cronjob.Mgr.AddJob(payload.IntervalPattern, &payload)
+		entryID, err = cronjob.Mgr.AddTask(payload)
 		if err == nil {
 			cronjob.Mgr.StoreJobMapping(payload.JobID, entryID)
 		}
@@ -45,15 +53,20 @@ func RemoveJobFromSchedule(jobID string) {
 }
 
 func ActiveJobFromSchedule(payload cronjob.TaskPayload) error {
+	// 分片模式由負責的節點載入, 其他節點及只由上游觸發的任務只更新狀態
+	if !shard.Owns(payload.JobID) || payload.DependencyOnly() {
+		return UpdateJobStatus(context.Background(), payload.GroupName, payload.JobID, 1)
+	}
+
 	_, ok := cronjob.Mgr.LoadJobMapping(payload.JobID)
 	if !ok {
 		// 註冊新的entry
-		entryID, err := cronjob.Mgr.AddJob(payload.IntervalPattern, &payload)
+		entryID, err := cronjob.Mgr.AddTask(payload)
 		if err != nil {
 			return err
 		}
 		// 把Job的 status 1
-		err = UpdateJobStatus(payload.GroupName, payload.JobID, 1)
+		err = UpdateJobStatus(context.Background(), payload.GroupName, payload.JobID, 1)
 		if err != nil {
 			// 發生錯誤時，需要移除剛剛添加的 entry
 			cronjob.Mgr.Remove(entryID)
@@ -66,9 +79,15 @@ func ActiveJobFromSchedule(payload cronjob.TaskPayload) error {
 
 func PauseJobFromSchedule(groupName, jobID string) error {
 	entryID, ok := cronjob.Mgr.LoadJobMapping(jobID)
+	if !ok {
+		// 只由上游觸發的任務不在 cron 中, 暫停後不再被觸發
+		if payload := GetTaskPayload(context.Background(), groupName, jobID); payload.DependencyOnly() {
+			return UpdateJobStatus(context.Background(), groupName, jobID, 0)
+		}
+	}
 	if ok {
 		// 把Job的 status 0
-		err := UpdateJobStatus(groupName, jobID, 0)
+		err := UpdateJobStatus(context.Background(), groupName, jobID, 0)
 		if err != nil {
 			return err
 		}
@@ -77,3 +96,35 @@ func PauseJobFromSchedule(groupName, jobID string) error {
 	}
 	return nil
 }
+
+// 節點異動後重新分配任務, 載入新負責的任務並移除不再負責的任務
+func Rebalance() {
+	jobs, err := GetJobsByAll(context.Background())
+	if err != nil {
+		return
+	}
+
+	added := make([]cronjob.TaskPayload, 0)
+	removed := 0
+	for _, job := range jobs {
+		_, loaded := cronjob.Mgr.LoadJobMapping(job.JobID)
+		owns := shard.Owns(job.JobID)
+		if owns && !loaded && job.Status == 1 {
+			added = append(added, job)
+		}
+		if !owns && loaded {
+			RemoveJobFromSchedule(job.JobID)
+			removed++
+		}
+	}
+
+	if len(added) > 0 {
+		cronjob.Mgr.ImportJobs(added)
+	}
+
+	server.GetServerInstance().GetLogger().WithFields(map[string]interface{}{
+		"func":    "shard_rebalance",
+		"added":   len(added),
+		"removed": removed,
+	}).Info("Shard rebalance end")
+}
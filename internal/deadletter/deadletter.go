@@ -0,0 +1,211 @@
+package deadletter
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultMaxLen = 10000
+	defaultTTL    = 30 * 24 * 60 * 60
+	defaultLimit  = 20
+	maxLimit      = 100
+)
+
+var ErrNotFound = errors.New("dead letter not found")
+
+var (
+	maxLen   int64 = defaultMaxLen
+	ttl      int64 = defaultTTL
+	hostName string
+)
+
+// 重試後仍失敗的執行
+type Entry struct {
+	ID          string          `json:"id"`                           // 紀錄ID (stream ID)
+	JobID       string          `json:"job_id"`                       // 排程ID
+	GroupName   string          `json:"group_name"`                   // 群組名稱
+	Name        string          `json:"name"`                         // 排程名稱
+	Type        string          `json:"type"`                         // `nsq` `http`
+	Node        string          `json:"node"`                         // 執行節點
+	ScheduledAt time.Time       `json:"scheduled_at"`                 // 預定執行時間
+	FailedAt    time.Time       `json:"failed_at"`                    // 失敗時間
+	Attempts    int             `json:"attempts"`                     // 嘗試次數
+	Code        int             `json:"code"`                         // http 回應狀態碼
+	Error       string          `json:"error"`                        // 錯誤訊息
+	HistoryID   string          `json:"history_id"`                   // 對應的執行紀錄ID
+	Payload     json.RawMessage `json:"payload" swaggertype:"object"` // 失敗當下的完整任務內容, replay 依此重新執行
+}
+
+// 分頁查詢結果
+type Page struct {
+	Entries    []Entry `json:"entries"`     // 新的在前
+	NextCursor string  `json:"next_cursor"` // 下一頁的 cursor, 空值表示沒有下一頁
+}
+
+// 查詢條件, 時間範圍依 stream entry ID 即寫入 dead letter 的時間, 不是 scheduled_at
+type Query struct {
+	Start  time.Time // 寫入時間起始, 零值不限制
+	End    time.Time // 寫入時間結束, 零值不限制
+	Limit  int64     // 每頁筆數
+	Cursor string    // 上一頁回傳的 next_cursor
+}
+
+func ConfigInit() {
+	instance := server.GetServerInstance()
+	env := instance.GetEnv()
+
+	if env.DeadLetterMaxLen > 0 {
+		maxLen = env.DeadLetterMaxLen
+	}
+	if env.DeadLetterTTL > 0 {
+		ttl = env.DeadLetterTTL * 24 * 60 * 60
+	}
+	hostName = instance.GetHostName()
+}
+
+func deadLetterKey(groupName string) string {
+	return fmt.Sprintf("DEADLETTER_%s", groupName)
+}
+
+// 寫入 dead letter, 每個群組只保留最新 maxLen 筆
+func Save(ctx context.Context, entry *Entry) error {
+	if entry.Node == "" {
+		entry.Node = hostName
+	}
+
+	values := map[string]interface{}{
+		"job_id":       entry.JobID,
+		"group_name":   entry.GroupName,
+		"name":         entry.Name,
+		"type":         entry.Type,
+		"node":         entry.Node,
+		"scheduled_at": entry.ScheduledAt.Format(time.RFC3339Nano),
+		"failed_at":    entry.FailedAt.Format(time.RFC3339Nano),
+		"attempts":     entry.Attempts,
+		"code":         entry.Code,
+		"error":        entry.Error,
+		"history_id":   entry.HistoryID,
+		"payload":      string(entry.Payload),
+	}
+
+	id, err := redisCacher.Conn.WithContext(ctx).XAdd(deadLetterKey(entry.GroupName), values, maxLen, ttl)
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+
+	return nil
+}
+
+// 查詢群組的 dead letter, 依時間由新到舊分頁
+func Find(ctx context.Context, groupName string, q Query) (Page, error) {
+	page := Page{Entries: make([]Entry, 0)}
+
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+	if q.Limit > maxLimit {
+		q.Limit = maxLimit
+	}
+
+	end := "+"
+	if q.Cursor != "" {
+		end = "(" + q.Cursor
+	} else if !q.End.IsZero() {
+		end = strconv.FormatInt(q.End.UnixMilli(), 10)
+	}
+	start := "-"
+	if !q.Start.IsZero() {
+		start = strconv.FormatInt(q.Start.UnixMilli(), 10)
+	}
+
+	// 多取一筆判斷是否有下一頁
+	messages, err := redisCacher.Conn.WithContext(ctx).XRevRange(deadLetterKey(groupName), end, start, q.Limit+1)
+	if err != nil {
+		return page, err
+	}
+
+	if int64(len(messages)) > q.Limit {
+		messages = messages[:q.Limit]
+		page.NextCursor = messages[len(messages)-1].ID
+	}
+	for _, msg := range messages {
+		page.Entries = append(page.Entries, mapEntry(msg))
+	}
+
+	return page, nil
+}
+
+// 取得單筆 dead letter
+func Get(ctx context.Context, groupName, id string) (Entry, error) {
+	messages, err := redisCacher.Conn.WithContext(ctx).XRange(deadLetterKey(groupName), id, id, 1)
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(messages) == 0 {
+		return Entry{}, ErrNotFound
+	}
+	return mapEntry(messages[0]), nil
+}
+
+// 刪除單筆 dead letter
+func Delete(ctx context.Context, groupName, id string) error {
+	n, err := redisCacher.Conn.WithContext(ctx).XDel(deadLetterKey(groupName), id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// 清除群組所有 dead letter
+func Purge(ctx context.Context, groupName string) error {
+	return redisCacher.Conn.WithContext(ctx).Del(deadLetterKey(groupName))
+}
+
+// 將 stream 資料映射到 Entry 結構中
+func mapEntry(msg redis.XMessage) Entry {
+	str := func(field string) string {
+		v, _ := msg.Values[field].(string)
+		return v
+	}
+	num := func(field string) int64 {
+		n, _ := strconv.ParseInt(str(field), 10, 64)
+		return n
+	}
+	tm := func(field string) time.Time {
+		t, _ := time.Parse(time.RFC3339Nano, str(field))
+		return t
+	}
+
+	entry := Entry{
+		ID:          msg.ID,
+		JobID:       str("job_id"),
+		GroupName:   str("group_name"),
+		Name:        str("name"),
+		Type:        str("type"),
+		Node:        str("node"),
+		ScheduledAt: tm("scheduled_at"),
+		FailedAt:    tm("failed_at"),
+		Attempts:    int(num("attempts")),
+		Code:        int(num("code")),
+		Error:       str("error"),
+		HistoryID:   str("history_id"),
+	}
+	if payload := str("payload"); payload != "" {
+		entry.Payload = json.RawMessage(payload)
+	}
+
+	return entry
+}
@@ -0,0 +1,91 @@
+package deadletter
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setDeadLetterTest() {
+	redisCacher.SetMiniredis()
+
+	maxLen = defaultMaxLen
+	ttl = defaultTTL
+	hostName = "node-a"
+}
+
+func TestSaveAndGet(t *testing.T) {
+	setDeadLetterTest()
+
+	now := time.Now()
+	entry := &Entry{
+		JobID:       "100001",
+		GroupName:   "test",
+		Name:        "job01",
+		Type:        "http",
+		ScheduledAt: now.Truncate(time.Second),
+		FailedAt:    now,
+		Attempts:    3,
+		Code:        500,
+		Error:       "unexpected status code 500",
+		HistoryID:   "1-0",
+		Payload:     json.RawMessage(`{"job_id":"100001","request_url":"http://127.0.0.1/api/ping"}`),
+	}
+	err := Save(context.Background(), entry)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, entry.ID)
+
+	got, err := Get(context.Background(), "test", entry.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "node-a", got.Node)
+	assert.Equal(t, entry.JobID, got.JobID)
+	assert.Equal(t, 3, got.Attempts)
+	assert.Equal(t, 500, got.Code)
+	assert.Equal(t, entry.Error, got.Error)
+	assert.Equal(t, "1-0", got.HistoryID)
+	assert.True(t, entry.ScheduledAt.Equal(got.ScheduledAt))
+	assert.JSONEq(t, string(entry.Payload), string(got.Payload))
+
+	_, err = Get(context.Background(), "test", "0-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = Get(context.Background(), "other", entry.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFindDeleteAndPurge(t *testing.T) {
+	setDeadLetterTest()
+
+	ids := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		entry := &Entry{JobID: "100001", GroupName: "test", FailedAt: time.Now()}
+		assert.Nil(t, Save(context.Background(), entry))
+		ids = append(ids, entry.ID)
+	}
+
+	page, err := Find(context.Background(), "test", Query{Limit: 3})
+	assert.Nil(t, err)
+	assert.Len(t, page.Entries, 3)
+	assert.Equal(t, ids[4], page.Entries[0].ID)
+	assert.Equal(t, ids[2], page.NextCursor)
+
+	page, err = Find(context.Background(), "test", Query{Limit: 3, Cursor: page.NextCursor})
+	assert.Nil(t, err)
+	assert.Len(t, page.Entries, 2)
+	assert.Equal(t, "", page.NextCursor)
+
+	assert.Nil(t, Delete(context.Background(), "test", ids[0]))
+	assert.ErrorIs(t, Delete(context.Background(), "test", ids[0]), ErrNotFound)
+
+	page, err = Find(context.Background(), "test", Query{})
+	assert.Nil(t, err)
+	assert.Len(t, page.Entries, 4)
+
+	assert.Nil(t, Purge(context.Background(), "test"))
+	page, err = Find(context.Background(), "test", Query{})
+	assert.Nil(t, err)
+	assert.Len(t, page.Entries, 0)
+}
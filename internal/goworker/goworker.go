@@ -327,6 +327,11 @@ func (p *Pool) JobQueueLen() int {
 	return len(p.jobQueue)
 }
 
+// JobQueueCap job queue容量
+func (p *Pool) JobQueueCap() int {
+	return cap(p.jobQueue)
+}
+
 // WorkingJobCount 正在執行job數量
 func (p *Pool) WorkingJobCount() int64 {
 	return p.dispatcher.workCount()
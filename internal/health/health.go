@@ -0,0 +1,175 @@
+package health
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/leader"
+	"dcron/internal/nsqtarget"
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	// 檢查逾時, 避免 probe 卡住
+	checkTimeout = 2 * time.Second
+	// job queue 使用超過此比例視為飽和
+	saturation = 0.9
+)
+
+// 單項檢查結果
+type Check struct {
+	Name     string `json:"name"`        // 檢查項目
+	Status   string `json:"status"`      // `ok` `fail`
+	Detail   string `json:"detail"`      // 說明或錯誤訊息
+	Duration int64  `json:"duration_ms"` // 檢查時間(毫秒)
+}
+
+// 檢查報告, 任一項失敗即為 fail
+type Report struct {
+	Status string  `json:"status"` // `ok` `fail`
+	Checks []Check `json:"checks"`
+}
+
+type checker struct {
+	name string
+	fn   func(ctx context.Context) (string, error)
+}
+
+var (
+	serverCheck = checker{"server", checkServer}
+	redisCheck  = checker{"redis", checkRedis}
+	nsqCheck    = checker{"nsq", checkNsq}
+	cronCheck   = checker{"cron", checkCron}
+	importCheck = checker{"import", checkImport}
+	poolCheck   = checker{"pool", checkPool}
+	workerCheck = checker{"worker", checkWorker}
+)
+
+// 存活檢查, 只包含重啟可以恢復的項目, 外部相依異常不應重啟
+// cron 可能由操作者停止, worker pool 忙碌時重啟會遺失排隊中的任務, 都不列入
+func Live(ctx context.Context) Report {
+	return run(ctx, []checker{poolCheck})
+}
+
+// 就緒檢查, 相依服務都可用且已匯入任務才接受流量
+func Ready(ctx context.Context) Report {
+	return run(ctx, []checker{serverCheck, redisCheck, nsqCheck, importCheck, workerCheck})
+}
+
+// 所有檢查項目
+func Health(ctx context.Context) Report {
+	return run(ctx, []checker{serverCheck, redisCheck, nsqCheck, cronCheck, importCheck, poolCheck, workerCheck})
+}
+
+// 同時執行各項檢查, 逾時的項目為 fail
+func run(ctx context.Context, checkers []checker) Report {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	results := make([]chan Check, len(checkers))
+	for i, c := range checkers {
+		results[i] = make(chan Check, 1)
+		go func(c checker, result chan<- Check) {
+			start := time.Now()
+			detail, err := c.fn(ctx)
+			check := Check{Name: c.name, Status: StatusOK, Detail: detail, Duration: time.Since(start).Milliseconds()}
+			if err != nil {
+				check.Status = StatusFail
+				check.Detail = err.Error()
+			}
+			result <- check
+		}(c, results[i])
+	}
+
+	report := Report{Status: StatusOK, Checks: make([]Check, 0, len(checkers))}
+	for i, c := range checkers {
+		var check Check
+		select {
+		case check = <-results[i]:
+		case <-ctx.Done():
+			check = Check{Name: c.name, Status: StatusFail, Detail: "timeout", Duration: checkTimeout.Milliseconds()}
+		}
+		if check.Status != StatusOK {
+			report.Status = StatusFail
+		}
+		report.Checks = append(report.Checks, check)
+	}
+	return report
+}
+
+// 啟動完成且尚未開始停機
+func checkServer(ctx context.Context) (string, error) {
+	if cronjob.Mgr == nil || !cronjob.Mgr.GetPingSuccessful() {
+		return "", errors.New("server is starting or shutting down")
+	}
+	return "", nil
+}
+
+func checkRedis(ctx context.Context) (string, error) {
+	if redisCacher.Conn == nil {
+		return "", errors.New("redis is not initialized")
+	}
+	return "", redisCacher.Conn.WithContext(ctx).Ping()
+}
+
+// nsq ping 不支援 context, 逾時由 run 處理
+func checkNsq(ctx context.Context) (string, error) {
+	return "", nsqtarget.Ping()
+}
+
+// 選主模式下非 leader 的節點不執行 cron
+func checkCron(ctx context.Context) (string, error) {
+	if cronjob.Mgr == nil {
+		return "", errors.New("cron is not initialized")
+	}
+	if cronjob.Mgr.GetRunning() {
+		return "running", nil
+	}
+	if leader.Enabled() && !leader.IsLeader() {
+		return "standby", nil
+	}
+	if halted, err := cronjob.Halted(ctx); err == nil && halted {
+		return "halted", nil
+	}
+	return "", errors.New("cron is not running")
+}
+
+func checkImport(ctx context.Context) (string, error) {
+	if cronjob.Mgr == nil || !cronjob.Mgr.GetImported() {
+		return "", errors.New("jobs are not imported yet")
+	}
+	return "", nil
+}
+
+// worker pool 仍可處理任務
+func checkPool(ctx context.Context) (string, error) {
+	worker := server.GetServerInstance().GetWorker()
+	if worker == nil {
+		return "", errors.New("worker pool is not initialized")
+	}
+	if !worker.IsAlive() {
+		return "", errors.New("worker pool is closed")
+	}
+	return fmt.Sprintf("workers %d", worker.WorkerCount()), nil
+}
+
+// worker pool 未飽和, 飽和時暫停接受流量
+func checkWorker(ctx context.Context) (string, error) {
+	worker := server.GetServerInstance().GetWorker()
+	if worker == nil {
+		return "", errors.New("worker pool is not initialized")
+	}
+
+	queue, capacity := worker.JobQueueLen(), worker.JobQueueCap()
+	detail := fmt.Sprintf("queue %d/%d, working %d, workers %d", queue, capacity, worker.WorkingJobCount(), worker.WorkerCount())
+	if capacity > 0 && float64(queue) >= float64(capacity)*saturation {
+		return "", fmt.Errorf("worker pool is saturated: %s", detail)
+	}
+	return detail, nil
+}
@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"dcron/internal/cronjob"
+	"dcron/internal/redisCacher"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	checkers := []checker{
+		{"ok", func(ctx context.Context) (string, error) { return "fine", nil }},
+		{"fail", func(ctx context.Context) (string, error) { return "", errors.New("connection refused") }},
+		{"slow", func(ctx context.Context) (string, error) {
+			<-block
+			return "", nil
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := run(ctx, checkers)
+
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, Check{Name: "ok", Status: StatusOK, Detail: "fine"}, withoutDuration(report.Checks[0]))
+	assert.Equal(t, Check{Name: "fail", Status: StatusFail, Detail: "connection refused"}, withoutDuration(report.Checks[1]))
+	assert.Equal(t, Check{Name: "slow", Status: StatusFail, Detail: "timeout"}, withoutDuration(report.Checks[2]))
+
+	assert.Equal(t, StatusOK, run(context.Background(), checkers[:1]).Status)
+}
+
+func TestChecks(t *testing.T) {
+	redisCacher.SetMiniredis()
+	cronjob.Mgr = cronjob.NewCronManager()
+
+	report := Ready(context.Background())
+	assert.Equal(t, StatusFail, report.Status)
+	details := map[string]string{}
+	for _, c := range report.Checks {
+		details[c.Name] = c.Status + " " + c.Detail
+	}
+	assert.Equal(t, map[string]string{
+		"server": "fail server is starting or shutting down",
+		"redis":  "ok ",
+		"nsq":    "fail nsq producer is not initialized",
+		"import": "fail jobs are not imported yet",
+		"worker": "fail worker pool is not initialized",
+	}, details)
+
+	// 啟動完成並匯入任務後
+	cronjob.Mgr.SetPingSuccessful(true)
+	cronjob.Mgr.SetImported(true)
+	_, err := checkServer(context.Background())
+	assert.Nil(t, err)
+	_, err = checkImport(context.Background())
+	assert.Nil(t, err)
+
+	// 未選主時 cron 需在執行中
+	_, err = checkCron(context.Background())
+	assert.EqualError(t, err, "cron is not running")
+
+	// cron 停止不影響存活檢查
+	live := Live(context.Background())
+	if assert.Len(t, live.Checks, 1) {
+		assert.Equal(t, "pool", live.Checks[0].Name)
+		assert.Equal(t, "worker pool is not initialized", live.Checks[0].Detail)
+	}
+	assert.Len(t, Health(context.Background()).Checks, 7)
+}
+
+func withoutDuration(c Check) Check {
+	c.Duration = 0
+	return c
+}
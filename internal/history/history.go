@@ -0,0 +1,280 @@
+package history
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	OutcomeSuccess   = "success"
+	OutcomeFailed    = "failed"
+	OutcomeCancelled = "cancelled"
+	OutcomeSkipped   = "skipped"
+
+	TriggerSchedule   = "schedule"   // 排程觸發
+	TriggerMisfire    = "misfire"    // 停機後補跑
+	TriggerManual     = "manual"     // 手動執行
+	TriggerReplay     = "replay"     // 重新執行 dead letter
+	TriggerDependency = "dependency" // 上游任務完成後觸發
+
+	defaultMaxLen   = 1000
+	defaultTTL      = 7 * 24 * 60 * 60
+	defaultBodySize = 1024
+	defaultLimit    = 20
+	maxLimit        = 100
+)
+
+var (
+	maxLen   int64 = defaultMaxLen
+	ttl      int64 = defaultTTL
+	bodySize       = defaultBodySize
+	hostName string
+)
+
+// 單次執行紀錄
+type Record struct {
+	ID          string    `json:"id"`           // 紀錄ID (stream ID)
+	JobID       string    `json:"job_id"`       // 排程ID
+	GroupName   string    `json:"group_name"`   // 群組名稱
+	Name        string    `json:"name"`         // 排程名稱
+	Type        string    `json:"type"`         // `nsq` `http`
+	Node        string    `json:"node"`         // 執行節點
+	Trigger     string    `json:"trigger"`      // `schedule` `misfire` `manual` `replay` `dependency`
+	Upstream    *Upstream `json:"upstream"`     // 觸發本次執行的上游執行, 只有 `dependency` 有值
+	ScheduledAt time.Time `json:"scheduled_at"` // 預定執行時間
+	StartedAt   time.Time `json:"started_at"`   // 實際開始時間
+	Duration    int64     `json:"duration"`     // 執行時間(毫秒)
+	Outcome     string    `json:"outcome"`      // `success` `failed` `cancelled` `skipped`
+	Code        int       `json:"code"`         // http 回應狀態碼
+	Body        string    `json:"body"`         // 回應內容, 超過長度會截斷
+	Error       string    `json:"error"`        // 錯誤訊息
+	Attempts    int       `json:"attempts"`     // 嘗試次數
+	AttemptLog  []Attempt `json:"attempt_log"`  // 每次嘗試的結果
+}
+
+// 單次嘗試結果
+type Attempt struct {
+	StartedAt time.Time `json:"started_at"` // 開始時間
+	Duration  int64     `json:"duration"`   // 執行時間(毫秒)
+	Code      int       `json:"code"`       // http 回應狀態碼
+	Error     string    `json:"error"`      // 錯誤訊息
+}
+
+// 觸發下游任務的上游執行
+type Upstream struct {
+	JobID     string `json:"job_id"`     // 上游排程ID
+	GroupName string `json:"group_name"` // 上游群組名稱
+	Name      string `json:"name"`       // 上游排程名稱
+	HistoryID string `json:"history_id"` // 上游執行紀錄ID
+	Outcome   string `json:"outcome"`    // 上游執行結果
+}
+
+// 分頁查詢結果
+type Page struct {
+	Records    []Record `json:"records"`     // 執行紀錄, 新的在前
+	NextCursor string   `json:"next_cursor"` // 下一頁的 cursor, 空值表示沒有下一頁
+}
+
+// 查詢條件, 時間範圍依 scheduled_at, 起訖都包含
+type Query struct {
+	Start  time.Time // 預定執行時間起始, 零值不限制
+	End    time.Time // 預定執行時間結束, 零值不限制
+	Limit  int64     // 每頁筆數
+	Cursor string    // 上一頁回傳的 next_cursor
+}
+
+func (q Query) contains(t time.Time) bool {
+	if !q.Start.IsZero() && t.Before(q.Start) {
+		return false
+	}
+	return q.End.IsZero() || !t.After(q.End)
+}
+
+func ConfigInit() {
+	instance := server.GetServerInstance()
+	env := instance.GetEnv()
+
+	if env.HistoryMaxLen > 0 {
+		maxLen = env.HistoryMaxLen
+	}
+	if env.HistoryTTL > 0 {
+		ttl = env.HistoryTTL * 24 * 60 * 60
+	}
+	if env.HistoryBodySize > 0 {
+		bodySize = env.HistoryBodySize
+	}
+	hostName = instance.GetHostName()
+}
+
+func historyKey(groupName, jobID string) string {
+	return fmt.Sprintf("HISTORY_%s_%s", groupName, jobID)
+}
+
+// 寫入執行紀錄, 每個任務只保留最新 maxLen 筆
+func Save(ctx context.Context, rec *Record) error {
+	if rec.Node == "" {
+		rec.Node = hostName
+	}
+	if len(rec.Body) > bodySize {
+		rec.Body = strings.ToValidUTF8(rec.Body[:bodySize], "")
+	}
+
+	values := map[string]interface{}{
+		"job_id":       rec.JobID,
+		"group_name":   rec.GroupName,
+		"name":         rec.Name,
+		"type":         rec.Type,
+		"node":         rec.Node,
+		"trigger":      rec.Trigger,
+		"scheduled_at": rec.ScheduledAt.Format(time.RFC3339Nano),
+		"started_at":   rec.StartedAt.Format(time.RFC3339Nano),
+		"duration":     rec.Duration,
+		"outcome":      rec.Outcome,
+		"code":         rec.Code,
+		"body":         rec.Body,
+		"error":        rec.Error,
+		"attempts":     rec.Attempts,
+		"attempt_log":  marshalAttempts(rec.AttemptLog),
+		"upstream":     marshalUpstream(rec.Upstream),
+	}
+
+	id, err := redisCacher.Conn.WithContext(ctx).XAdd(historyKey(rec.GroupName, rec.JobID), values, maxLen, ttl)
+	if err != nil {
+		return err
+	}
+	rec.ID = id
+
+	return nil
+}
+
+// 查詢執行紀錄, 依時間由新到舊分頁
+func Find(ctx context.Context, groupName, jobID string, q Query) (Page, error) {
+	page := Page{Records: make([]Record, 0)}
+
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+	if q.Limit > maxLimit {
+		q.Limit = maxLimit
+	}
+
+	// 寫入時間不早於 scheduled_at, 只能以起始時間縮小 stream 範圍, 結束時間逐筆比對
+	end := "+"
+	if q.Cursor != "" {
+		end = "(" + q.Cursor
+	}
+	start := "-"
+	if !q.Start.IsZero() {
+		start = strconv.FormatInt(q.Start.UnixMilli(), 10)
+	}
+
+	for {
+		messages, err := redisCacher.Conn.WithContext(ctx).XRevRange(historyKey(groupName, jobID), end, start, maxLimit)
+		if err != nil {
+			return page, err
+		}
+		for _, msg := range messages {
+			rec := mapRecord(msg)
+			if !q.contains(rec.ScheduledAt) {
+				continue
+			}
+			// 多取一筆判斷是否有下一頁
+			if int64(len(page.Records)) == q.Limit {
+				page.NextCursor = page.Records[len(page.Records)-1].ID
+				return page, nil
+			}
+			page.Records = append(page.Records, rec)
+		}
+		if len(messages) < maxLimit {
+			return page, nil
+		}
+		end = "(" + messages[len(messages)-1].ID
+	}
+}
+
+// 將 stream 資料映射到 Record 結構中
+func mapRecord(msg redis.XMessage) Record {
+	str := func(field string) string {
+		v, _ := msg.Values[field].(string)
+		return v
+	}
+	num := func(field string) int64 {
+		n, _ := strconv.ParseInt(str(field), 10, 64)
+		return n
+	}
+	tm := func(field string) time.Time {
+		t, _ := time.Parse(time.RFC3339Nano, str(field))
+		return t
+	}
+
+	return Record{
+		ID:          msg.ID,
+		JobID:       str("job_id"),
+		GroupName:   str("group_name"),
+		Name:        str("name"),
+		Type:        str("type"),
+		Node:        str("node"),
+		Trigger:     str("trigger"),
+		ScheduledAt: tm("scheduled_at"),
+		StartedAt:   tm("started_at"),
+		Duration:    num("duration"),
+		Outcome:     str("outcome"),
+		Code:        int(num("code")),
+		Body:        str("body"),
+		Error:       str("error"),
+		Attempts:    int(num("attempts")),
+		AttemptLog:  unmarshalAttempts(str("attempt_log")),
+		Upstream:    unmarshalUpstream(str("upstream")),
+	}
+}
+
+// 嘗試結果以 json 字串存入 stream
+func marshalAttempts(attempts []Attempt) string {
+	if len(attempts) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(attempts)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func unmarshalAttempts(data string) []Attempt {
+	attempts := make([]Attempt, 0)
+	if data != "" {
+		json.Unmarshal([]byte(data), &attempts)
+	}
+	return attempts
+}
+
+// 上游執行以 json 字串存入 stream, 非上游觸發存空值
+func marshalUpstream(upstream *Upstream) string {
+	if upstream == nil {
+		return ""
+	}
+	b, err := json.Marshal(upstream)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func unmarshalUpstream(data string) *Upstream {
+	if data == "" {
+		return nil
+	}
+	var upstream Upstream
+	if err := json.Unmarshal([]byte(data), &upstream); err != nil {
+		return nil
+	}
+	return &upstream
+}
@@ -0,0 +1,140 @@
+package history
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setHistoryTest() {
+	redisCacher.SetMiniredis()
+
+	maxLen = defaultMaxLen
+	ttl = defaultTTL
+	bodySize = defaultBodySize
+	hostName = "node-a"
+}
+
+func TestSave(t *testing.T) {
+	setHistoryTest()
+
+	now := time.Now()
+	rec := &Record{
+		JobID:       "100001",
+		GroupName:   "test",
+		Name:        "job01",
+		Type:        "http",
+		ScheduledAt: now.Truncate(time.Second),
+		StartedAt:   now,
+		Duration:    12,
+		Outcome:     OutcomeFailed,
+		Code:        500,
+		Body:        strings.Repeat("a", defaultBodySize+10),
+		Error:       "http error",
+		Attempts:    3,
+	}
+	err := Save(context.Background(), rec)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, rec.ID)
+
+	page, err := Find(context.Background(), "test", "100001", Query{})
+	assert.Nil(t, err)
+	assert.Len(t, page.Records, 1)
+	assert.Equal(t, "", page.NextCursor)
+
+	got := page.Records[0]
+	assert.Equal(t, rec.ID, got.ID)
+	assert.Equal(t, "node-a", got.Node)
+	assert.Equal(t, OutcomeFailed, got.Outcome)
+	assert.Equal(t, 500, got.Code)
+	assert.Equal(t, 3, got.Attempts)
+	assert.Equal(t, int64(12), got.Duration)
+	assert.Equal(t, "http error", got.Error)
+	assert.Len(t, got.Body, defaultBodySize)
+	assert.True(t, got.ScheduledAt.Equal(rec.ScheduledAt))
+	assert.True(t, got.StartedAt.Equal(rec.StartedAt))
+
+	ttlLeft, err := redisCacher.Conn.TTL(historyKey("test", "100001"))
+	assert.Nil(t, err)
+	assert.Equal(t, int(defaultTTL), ttlLeft)
+}
+
+func TestFind(t *testing.T) {
+	setHistoryTest()
+	maxLen = 4
+
+	ids := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		rec := &Record{JobID: "100001", GroupName: "test", Outcome: OutcomeSuccess, Attempts: i, ScheduledAt: time.Now()}
+		err := Save(context.Background(), rec)
+		assert.Nil(t, err)
+		ids = append(ids, rec.ID)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	t.Run("capped and newest first", func(t *testing.T) {
+		page, err := Find(context.Background(), "test", "100001", Query{Limit: 10})
+		assert.Nil(t, err)
+		assert.Len(t, page.Records, 4)
+		assert.Equal(t, ids[4], page.Records[0].ID)
+		assert.Equal(t, ids[1], page.Records[3].ID)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		page, err := Find(context.Background(), "test", "100001", Query{Limit: 3})
+		assert.Nil(t, err)
+		assert.Len(t, page.Records, 3)
+		assert.Equal(t, ids[2], page.NextCursor)
+
+		page, err = Find(context.Background(), "test", "100001", Query{Limit: 3, Cursor: page.NextCursor})
+		assert.Nil(t, err)
+		assert.Len(t, page.Records, 1)
+		assert.Equal(t, ids[1], page.Records[0].ID)
+		assert.Equal(t, "", page.NextCursor)
+	})
+
+	t.Run("time range", func(t *testing.T) {
+		page, err := Find(context.Background(), "test", "100001", Query{Start: time.Now().Add(time.Hour)})
+		assert.Nil(t, err)
+		assert.Len(t, page.Records, 0)
+
+		page, err = Find(context.Background(), "test", "100001", Query{End: time.Now().Add(-time.Hour)})
+		assert.Nil(t, err)
+		assert.Len(t, page.Records, 0)
+
+		page, err = Find(context.Background(), "test", "100001", Query{Start: time.Now().Add(-time.Hour), End: time.Now().Add(time.Hour)})
+		assert.Nil(t, err)
+		assert.Len(t, page.Records, 4)
+	})
+
+	t.Run("scheduled at", func(t *testing.T) {
+		// 執行較久的紀錄, 寫入時間晚於結束時間仍依 scheduled_at 查詢
+		scheduled := time.Now().Add(-time.Minute).Truncate(time.Second)
+		for _, at := range []time.Time{scheduled.Add(-time.Second), scheduled, scheduled.Add(999 * time.Millisecond), scheduled.Add(time.Second)} {
+			assert.Nil(t, Save(context.Background(), &Record{JobID: "100003", GroupName: "test", ScheduledAt: at}))
+		}
+
+		page, err := Find(context.Background(), "test", "100003", Query{Start: scheduled, End: scheduled.Add(999 * time.Millisecond), Limit: 1})
+		assert.Nil(t, err)
+		if assert.Len(t, page.Records, 1) {
+			assert.True(t, page.Records[0].ScheduledAt.Equal(scheduled.Add(999*time.Millisecond)))
+		}
+
+		page, err = Find(context.Background(), "test", "100003", Query{Start: scheduled, End: scheduled.Add(999 * time.Millisecond), Cursor: page.NextCursor})
+		assert.Nil(t, err)
+		if assert.Len(t, page.Records, 1) {
+			assert.True(t, page.Records[0].ScheduledAt.Equal(scheduled))
+		}
+		assert.Equal(t, "", page.NextCursor)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		page, err := Find(context.Background(), "test", "100002", Query{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Records, 0)
+	})
+}
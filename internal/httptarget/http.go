@@ -2,8 +2,11 @@ package httptarget
 
 import (
 	"context"
+	"dcron/internal/tracing"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,23 +14,57 @@ import (
 )
 
 type ITarget interface {
-	NewTarget(ctx context.Context, apiUrl string) error
+	NewTarget(ctx context.Context, req Request) error
 	GetResponse() (*ghc.Response, error)
 }
 
+// 支援的 http method
+var Methods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// 未設定逾時的請求逾時時間
+const DefaultTimeout = time.Second * 20
+
 var (
-	httpConn  = make(map[string]*ghc.Client)
+	httpConn  = make(map[string]*http.Client)
 	httpMutex sync.RWMutex
 )
 
+// http 任務的請求內容
+type Request struct {
+	Method  string            // 預設 GET
+	Url     string            // 網址, 可帶 query string
+	Headers map[string]string // 自訂 header, 會覆蓋預設的 Content-Type, Accept
+	Body    string            // 請求內容
+	Timeout time.Duration     // 請求逾時, 0 為 DefaultTimeout
+}
+
 type Service struct {
 	HttpConn *ghc.Client
 	ApiPath  string
+	Method   string
+	Headers  map[string]string
+	Body     string
 	Ctx      context.Context
 }
 
-func (s *Service) NewTarget(ctx context.Context, apiUrl string) error {
-	u, err := url.ParseRequestURI(apiUrl)
+// 是否為支援的 http method
+func IsMethod(method string) bool {
+	for _, m := range Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) NewTarget(ctx context.Context, req Request) error {
+	u, err := url.ParseRequestURI(req.Url)
 	if err != nil {
 		return fmt.Errorf("invalid URL: %w", err)
 	}
@@ -38,36 +75,51 @@ func (s *Service) NewTarget(ctx context.Context, apiUrl string) error {
 		u.Path = "/"
 	}
 	s.Ctx = ctx
-	s.ApiPath = u.Path
+	s.ApiPath = u.RequestURI()
+	s.Method = strings.ToUpper(req.Method)
+	s.Body = req.Body
+
+	// header 名稱統一格式, 避免和預設 header 重複
+	s.Headers = make(map[string]string, len(req.Headers))
+	for k, v := range req.Headers {
+		s.Headers[http.CanonicalHeaderKey(k)] = v
+	}
+	// 啟用追蹤時帶上目前 span 的 traceparent, 供下游延續 trace
+	if traceparent := tracing.TraceParent(ctx); traceparent != "" {
+		s.Headers["Traceparent"] = traceparent
+	}
 
 	httpMutex.RLock()
 	conn, ok := httpConn[host]
 	httpMutex.RUnlock()
 
-	if ok {
-		s.HttpConn = conn
-	} else {
+	if !ok {
 		httpMutex.Lock()
-		defer httpMutex.Unlock()
-
 		// Recheck to avoid race condition
-		if conn, ok := httpConn[host]; ok {
-			s.HttpConn = conn
-			return nil
-		}
-
-		opts := []ghc.ClientOption{
-			ghc.WithDefaultHeaders(),
-			ghc.WithTimeout(time.Second * 20),
+		if conn, ok = httpConn[host]; !ok {
+			// 逾時由每次請求的 context 控制, 共用連線不設定固定逾時
+			conn = &http.Client{}
+			httpConn[host] = conn
 		}
+		httpMutex.Unlock()
+	}
 
-		baseUrl := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
-		client := ghc.New(baseUrl, opts...)
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
 
-		s.HttpConn = client
-		httpConn[host] = client
+	// ghc.Client 會暫存每次請求的 header/body, 不能共用; 共用底層 http.Client 保留連線
+	// WithTimeout 需在 WithCustomHttpClient 之前, 避免改到共用的 http.Client
+	opts := []ghc.ClientOption{
+		ghc.WithDefaultHeaders(),
+		ghc.WithTimeout(timeout),
+		ghc.WithCustomHttpClient(conn),
 	}
 
+	baseUrl := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	s.HttpConn = ghc.New(baseUrl, opts...)
+
 	return nil
 }
 
@@ -76,5 +128,24 @@ func (s *Service) GetResponse() (*ghc.Response, error) {
 		return nil, fmt.Errorf("HTTP connection is not initialized")
 	}
 
-	return s.HttpConn.Get(s.Ctx, s.ApiPath)
+	opts := make([]ghc.Option, 0, len(s.Headers)+1)
+	for k, v := range s.Headers {
+		opts = append(opts, ghc.WithHeader(k, v))
+	}
+	if s.Body != "" {
+		opts = append(opts, ghc.WithBody([]byte(s.Body)))
+	}
+
+	switch s.Method {
+	case http.MethodPost:
+		return s.HttpConn.Post(s.Ctx, s.ApiPath, opts...)
+	case http.MethodPut:
+		return s.HttpConn.Put(s.Ctx, s.ApiPath, opts...)
+	case http.MethodPatch:
+		return s.HttpConn.Patch(s.Ctx, s.ApiPath, opts...)
+	case http.MethodDelete:
+		return s.HttpConn.Delete(s.Ctx, s.ApiPath, opts...)
+	default:
+		return s.HttpConn.Get(s.Ctx, s.ApiPath, opts...)
+	}
 }
@@ -0,0 +1,150 @@
+package httptarget
+
+import (
+	"context"
+	"dcron/internal/tracing"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetResponse(t *testing.T) {
+	var got *http.Request
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got, body = r, string(b)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	t.Run("default get", func(t *testing.T) {
+		s := &Service{}
+		err := s.NewTarget(context.Background(), Request{Url: srv.URL + "/api/ping?a=1"})
+		assert.Nil(t, err)
+
+		res, err := s.GetResponse()
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusCreated, res.Status())
+		assert.Equal(t, http.MethodGet, got.Method)
+		assert.Equal(t, "/api/ping", got.URL.Path)
+		assert.Equal(t, "1", got.URL.Query().Get("a"))
+		assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	})
+
+	t.Run("post form with headers", func(t *testing.T) {
+		s := &Service{}
+		err := s.NewTarget(context.Background(), Request{
+			Method: "post",
+			Url:    srv.URL + "/api/job",
+			Headers: map[string]string{
+				"content-type":  "application/x-www-form-urlencoded",
+				"Authorization": "Bearer token",
+				"X-Tenant-Id":   "t1",
+			},
+			Body: "a=1&b=2",
+		})
+		assert.Nil(t, err)
+
+		_, err = s.GetResponse()
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodPost, got.Method)
+		assert.Equal(t, "application/x-www-form-urlencoded", got.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", got.Header.Get("Authorization"))
+		assert.Equal(t, "t1", got.Header.Get("X-Tenant-Id"))
+		assert.Equal(t, "a=1&b=2", body)
+	})
+
+	t.Run("other methods", func(t *testing.T) {
+		for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			s := &Service{}
+			err := s.NewTarget(context.Background(), Request{Method: method, Url: srv.URL, Body: `{"id":1}`})
+			assert.Nil(t, err)
+
+			_, err = s.GetResponse()
+			assert.Nil(t, err)
+			assert.Equal(t, method, got.Method)
+			assert.Equal(t, "/", got.URL.Path)
+			assert.Equal(t, `{"id":1}`, body)
+		}
+	})
+}
+
+func TestGetResponseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	t.Run("job timeout", func(t *testing.T) {
+		s := &Service{}
+		err := s.NewTarget(context.Background(), Request{Url: srv.URL, Timeout: 50 * time.Millisecond})
+		assert.Nil(t, err)
+
+		start := time.Now()
+		_, err = s.GetResponse()
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("context cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		s := &Service{}
+		err := s.NewTarget(ctx, Request{Url: srv.URL})
+		assert.Nil(t, err)
+
+		start := time.Now()
+		_, err = s.GetResponse()
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestScanTraceParent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Traceparent")
+	}))
+	defer srv.Close()
+
+	headers := map[string]string{"X-Tenant-Id": "t1"}
+	req := Request{Url: srv.URL, Headers: headers}
+
+	t.Run("disabled", func(t *testing.T) {
+		res := NewEntryScan(context.Background(), req, SuccessRule{}).Scan()
+		assert.Nil(t, res.Err)
+		assert.Equal(t, "", got)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		err := tracing.Configure(tracing.Config{Exporter: tracing.ExporterFile, File: filepath.Join(t.TempDir(), "traces.json")})
+		assert.Nil(t, err)
+		defer tracing.Shutdown(context.Background())
+
+		ctx, span := tracing.Start(context.Background(), "cronjob.run", tracing.KindInternal)
+		defer span.End()
+
+		res := NewEntryScan(ctx, req, SuccessRule{}).Scan()
+		assert.Nil(t, res.Err)
+
+		// 下游收到的是 http 子 span, 與執行的 span 同一個 trace
+		sc, err := tracing.ParseTraceParent(got)
+		assert.Nil(t, err)
+		assert.Equal(t, span.SpanContext().TraceID(), sc.TraceID())
+		assert.NotEqual(t, span.SpanContext().SpanID(), sc.SpanID())
+		assert.Equal(t, map[string]string{"X-Tenant-Id": "t1"}, headers)
+	})
+}
@@ -2,63 +2,79 @@ package httptarget
 
 import (
 	"context"
-	"time"
+	"dcron/internal/tracing"
+	"net/http"
+	"strings"
 )
 
 type entryScan struct {
-	TryMaxCount int
-	Url         string
-	Target      ITarget
-	Ctx         context.Context
+	Request Request
+	Rule    SuccessRule
+	Target  ITarget
+	Ctx     context.Context
 }
 
 type EntryScanBody struct {
-	Body  string
-	Code  int
-	Count int
-	Err   error
+	Body    string
+	Code    int
+	Success bool
+	Err     error
 }
 
-func NewEntryScan(ctx context.Context, requestUrl string, maxCount int) *entryScan {
+// 單次請求, 重試由呼叫端的重試策略處理
+func NewEntryScan(ctx context.Context, req Request, rule SuccessRule) *entryScan {
 	target := &Service{}
 
-	if maxCount < 1 {
-		maxCount = 1
-	}
-
 	return &entryScan{
-		Ctx:         ctx,
-		Url:         requestUrl,
-		Target:      target,
-		TryMaxCount: maxCount,
+		Ctx:     ctx,
+		Request: req,
+		Rule:    rule,
+		Target:  target,
 	}
 }
 
 func (m *entryScan) Scan() *EntryScanBody {
 	ret := &EntryScanBody{}
-	err := m.Target.NewTarget(m.Ctx, m.Url)
+
+	ctx, span := m.startSpan()
+	defer func() {
+		if ret.Code != 0 {
+			span.SetAttributes(tracing.Int("http.status_code", ret.Code))
+		}
+		span.SetError(ret.Err)
+		span.End()
+	}()
+
+	err := m.Target.NewTarget(ctx, m.Request)
+	if err != nil {
+		ret.Err = err
+		return ret
+	}
+
+	res, err := m.Target.GetResponse()
 	if err != nil {
+		ret.Err = err
 		return ret
 	}
 
-	for n := 1; n <= m.TryMaxCount; n++ {
-		res, err := m.Target.GetResponse()
+	ret.Code = res.Status()
+	ret.Body = string(res.Body())
+	ret.Err = m.Rule.Check(ret.Code, ret.Body)
+	ret.Success = ret.Err == nil
 
-		ret.Count = n
-		if err != nil {
-			ret.Err = err
-		} else {
-			ret.Code = res.Status()
-			if ret.Code == 200 {
-				ret.Body = string(res.Body())
-				break
-			}
-		}
+	return ret
+}
 
-		if err != nil || ret.Code != 200 {
-			time.Sleep(100 * time.Millisecond)
-		}
+// 對外請求的 span, url 不記錄 query string
+func (m *entryScan) startSpan() (context.Context, *tracing.Span) {
+	method := strings.ToUpper(m.Request.Method)
+	if method == "" {
+		method = http.MethodGet
 	}
+	u, _, _ := strings.Cut(m.Request.Url, "?")
 
-	return ret
+	return tracing.Start(m.Ctx, "HTTP "+method, tracing.KindClient,
+		tracing.String("http.method", method),
+		tracing.String("http.url", u),
+	)
 }
@@ -0,0 +1,251 @@
+package httptarget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 已編譯的 regex, 依 pattern 快取, 避免每次執行都重新編譯
+var regexps sync.Map
+
+// http 任務的成功條件, 零值為狀態碼 200 即成功
+type SuccessRule struct {
+	Codes    string `json:"codes" example:"200-299"`  // 成功的狀態碼, 支援 `200,201` `200-204` `2xx`, 預設 `200`
+	JsonPath string `json:"json_path" example:"$.ok"` // 檢查回應內容的 JSONPath, 支援 `$.a.b` `$.list[0].c`
+	Expect   string `json:"expect" example:"true"`    // JSONPath 的值需等於此值, 空值表示值存在且不為 null/false
+	Regex    string `json:"regex" example:""`         // 回應內容需符合的正規表示式
+}
+
+type codeRange struct {
+	min, max int
+}
+
+// 是否為預設條件
+func (r SuccessRule) IsZero() bool {
+	return r == SuccessRule{}
+}
+
+// 檢查條件格式
+func (r SuccessRule) Validate() error {
+	if _, err := parseCodes(r.Codes); err != nil {
+		return err
+	}
+	if r.JsonPath != "" {
+		if _, err := parseJsonPath(r.JsonPath); err != nil {
+			return err
+		}
+	} else if r.Expect != "" {
+		return errors.New("success rule expect requires json_path")
+	}
+	if r.Regex != "" {
+		if _, err := compileRegex(r.Regex); err != nil {
+			return fmt.Errorf("success rule regex is invalid: %w", err)
+		}
+	}
+	return nil
+}
+
+// 檢查回應是否成功, 失敗時回傳原因
+func (r SuccessRule) Check(code int, body string) error {
+	ranges, err := parseCodes(r.Codes)
+	if err != nil {
+		return err
+	}
+	if !matchCode(ranges, code) {
+		return fmt.Errorf("unexpected status code %d", code)
+	}
+
+	if r.JsonPath != "" {
+		if err := r.checkJsonPath(body); err != nil {
+			return err
+		}
+	}
+
+	if r.Regex != "" {
+		re, err := compileRegex(r.Regex)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(body) {
+			return fmt.Errorf("response body does not match %s", r.Regex)
+		}
+	}
+
+	return nil
+}
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Store(pattern, re)
+	return re, nil
+}
+
+func (r SuccessRule) checkJsonPath(body string) error {
+	path, err := parseJsonPath(r.JsonPath)
+	if err != nil {
+		return err
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return errors.New("response body is not json")
+	}
+
+	value, ok := lookupJsonPath(data, path)
+	if !ok {
+		return fmt.Errorf("response body has no %s", r.JsonPath)
+	}
+
+	if r.Expect == "" {
+		if value == nil || value == false {
+			return fmt.Errorf("response body %s is %v", r.JsonPath, value)
+		}
+		return nil
+	}
+
+	actual, ok := value.(string)
+	if !ok {
+		b, _ := json.Marshal(value)
+		actual = string(b)
+	}
+	if actual != r.Expect {
+		return fmt.Errorf("response body %s is %s, expect %s", r.JsonPath, actual, r.Expect)
+	}
+
+	return nil
+}
+
+// 解析狀態碼條件, 空值為 200
+func parseCodes(codes string) ([]codeRange, error) {
+	if strings.TrimSpace(codes) == "" {
+		return []codeRange{{200, 200}}, nil
+	}
+
+	ranges := make([]codeRange, 0)
+	for _, part := range strings.Split(codes, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+
+		var (
+			cr  codeRange
+			err error
+		)
+		switch {
+		case len(part) == 3 && strings.HasSuffix(part, "xx"):
+			var n int
+			n, err = strconv.Atoi(part[:1])
+			cr = codeRange{n * 100, n*100 + 99}
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			cr.min, err = strconv.Atoi(strings.TrimSpace(bounds[0]))
+			if err == nil {
+				cr.max, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			}
+		default:
+			cr.min, err = strconv.Atoi(part)
+			cr.max = cr.min
+		}
+
+		if err != nil || cr.min < 100 || cr.max > 599 || cr.min > cr.max {
+			return nil, fmt.Errorf("status codes %s is invalid", part)
+		}
+		ranges = append(ranges, cr)
+	}
+
+	return ranges, nil
+}
+
+// 檢查狀態碼條件格式
+func ValidateCodes(codes string) error {
+	_, err := parseCodes(codes)
+	return err
+}
+
+// 狀態碼是否符合條件, 條件格式錯誤視為不符合
+func MatchCode(codes string, code int) bool {
+	ranges, err := parseCodes(codes)
+	return err == nil && matchCode(ranges, code)
+}
+
+func matchCode(ranges []codeRange, code int) bool {
+	for _, cr := range ranges {
+		if code >= cr.min && code <= cr.max {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析 JSONPath, 欄位為 string, 陣列索引為 int
+func parseJsonPath(path string) ([]interface{}, error) {
+	invalid := fmt.Errorf("success rule json_path %s is invalid", path)
+
+	if !strings.HasPrefix(path, "$") {
+		return nil, invalid
+	}
+
+	steps := make([]interface{}, 0)
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, invalid
+			}
+			steps = append(steps, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, invalid
+			}
+			idx, err := strconv.Atoi(rest[1:end])
+			if err != nil || idx < 0 {
+				return nil, invalid
+			}
+			steps = append(steps, idx)
+			rest = rest[end+1:]
+		default:
+			return nil, invalid
+		}
+	}
+
+	return steps, nil
+}
+
+func lookupJsonPath(data interface{}, path []interface{}) (interface{}, bool) {
+	for _, step := range path {
+		switch key := step.(type) {
+		case string:
+			obj, ok := data.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if data, ok = obj[key]; !ok {
+				return nil, false
+			}
+		case int:
+			list, ok := data.([]interface{})
+			if !ok || key >= len(list) {
+				return nil, false
+			}
+			data = list[key]
+		}
+	}
+	return data, true
+}
@@ -0,0 +1,82 @@
+package httptarget
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuccessRuleValidate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rule  SuccessRule
+		valid bool
+	}{
+		{"default", SuccessRule{}, true},
+		{"code list", SuccessRule{Codes: "200, 201,204"}, true},
+		{"code range", SuccessRule{Codes: "200-299"}, true},
+		{"code class", SuccessRule{Codes: "2xx,304"}, true},
+		{"code not number", SuccessRule{Codes: "abc"}, false},
+		{"code out of range", SuccessRule{Codes: "700"}, false},
+		{"code reversed range", SuccessRule{Codes: "299-200"}, false},
+		{"json path", SuccessRule{JsonPath: "$.data.list[0].ok", Expect: "true"}, true},
+		{"json path without $", SuccessRule{JsonPath: "data.ok"}, false},
+		{"json path bad index", SuccessRule{JsonPath: "$.list[a]"}, false},
+		{"expect without json path", SuccessRule{Expect: "true"}, false},
+		{"regex", SuccessRule{Regex: `"ok":\s*true`}, true},
+		{"bad regex", SuccessRule{Regex: "("}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rule.Validate()
+			assert.Equal(t, tc.valid, err == nil, err)
+		})
+	}
+}
+
+func TestSuccessRuleCheck(t *testing.T) {
+	testCases := []struct {
+		name    string
+		rule    SuccessRule
+		code    int
+		body    string
+		success bool
+	}{
+		{"default 200", SuccessRule{}, 200, "", true},
+		{"default 201", SuccessRule{}, 201, "", false},
+		{"accepted 204", SuccessRule{Codes: "200-204"}, 204, "", true},
+		{"class 5xx", SuccessRule{Codes: "2xx"}, 500, "", false},
+		{"json ok", SuccessRule{JsonPath: "$.ok"}, 200, `{"ok":true}`, true},
+		{"json not ok", SuccessRule{JsonPath: "$.ok"}, 200, `{"ok":false}`, false},
+		{"json missing", SuccessRule{JsonPath: "$.ok"}, 200, `{}`, false},
+		{"json not json", SuccessRule{JsonPath: "$.ok"}, 200, `ok`, false},
+		{"json expect string", SuccessRule{JsonPath: "$.data.status", Expect: "done"}, 200, `{"data":{"status":"done"}}`, true},
+		{"json expect number", SuccessRule{JsonPath: "$.list[1].code", Expect: "0"}, 200, `{"list":[{"code":1},{"code":0}]}`, true},
+		{"json expect mismatch", SuccessRule{JsonPath: "$.code", Expect: "0"}, 200, `{"code":1}`, false},
+		{"json index out of range", SuccessRule{JsonPath: "$.list[2]"}, 200, `{"list":[1]}`, false},
+		{"regex match", SuccessRule{Regex: `"ok":\s*true`}, 200, `{"ok": true}`, true},
+		{"regex not match", SuccessRule{Regex: `"ok":\s*true`}, 200, `{"ok": false}`, false},
+		{"code checked before body", SuccessRule{JsonPath: "$.ok"}, 500, `{"ok":true}`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rule.Check(tc.code, tc.body)
+			assert.Equal(t, tc.success, err == nil, err)
+		})
+	}
+}
+
+func TestCompileRegexCache(t *testing.T) {
+	re, err := compileRegex(`^cached-\d+$`)
+	assert.Nil(t, err)
+	again, err := compileRegex(`^cached-\d+$`)
+	assert.Nil(t, err)
+	assert.Same(t, re, again)
+
+	_, err = compileRegex(`(`)
+	assert.NotNil(t, err)
+	_, ok := regexps.Load(`(`)
+	assert.False(t, ok)
+}
@@ -0,0 +1,189 @@
+package leader
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	leaderKey = "LEADER"       // 目前 leader 的節點名稱, TTL 為租約時間
+	tokenKey  = "LEADER_TOKEN" // fencing token, 每次選出新 leader 加一
+
+	defaultLeaseTime = 10
+)
+
+// 租約仍屬於自己才延長
+const renewScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("EXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+// 租約仍屬於自己才釋放
+const releaseScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+var (
+	logger    *logrus.Logger
+	enabled   bool
+	leaseTime int64
+	hostName  string
+
+	mutex    sync.RWMutex
+	isLeader bool
+	token    int64
+)
+
+type Status struct {
+	Enabled   bool   `json:"enabled"`    // 是否啟用選主模式
+	HostName  string `json:"host_name"`  // 本節點名稱
+	IsLeader  bool   `json:"is_leader"`  // 本節點是否為 leader
+	Leader    string `json:"leader"`     // 目前 leader 節點名稱
+	Token     int64  `json:"token"`      // 本節點取得的 fencing token
+	LeaseTime int64  `json:"lease_time"` // 租約時間(秒)
+}
+
+func ConfigInit() {
+	instance := server.GetServerInstance()
+	env := instance.GetEnv()
+
+	logger = instance.GetLogger()
+	enabled = env.LeaderElection
+	leaseTime = env.LeaderLeaseTime
+	if leaseTime < 3 {
+		leaseTime = defaultLeaseTime
+	}
+	hostName = instance.GetHostName()
+}
+
+// 是否啟用選主模式
+func Enabled() bool {
+	return enabled
+}
+
+// 本節點是否為 leader
+func IsLeader() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return isLeader
+}
+
+// 確認本節點仍是最新的 leader, 未啟用選主模式一律通過
+func Fence() bool {
+	if !enabled {
+		return true
+	}
+
+	mutex.RLock()
+	leader, current := isLeader, token
+	mutex.RUnlock()
+	if !leader {
+		return false
+	}
+
+	latest, err := redisCacher.Conn.Get(tokenKey).Int64()
+	return err == nil && latest == current
+}
+
+// 參與選主直到 ctx 結束, 成為 leader 時呼叫 onElected, 失去 leader 時呼叫 onRevoked
+func Run(ctx context.Context, onElected, onRevoked func()) {
+	ticker := time.NewTicker(time.Duration(leaseTime) * time.Second / 3)
+	defer ticker.Stop()
+
+	for {
+		campaign(onElected, onRevoked)
+
+		select {
+		case <-ctx.Done():
+			resign(onRevoked)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func campaign(onElected, onRevoked func()) {
+	if IsLeader() {
+		ret, err := redisCacher.Conn.Eval(renewScript, []string{leaderKey}, hostName, leaseTime)
+		if err == nil && ret == int64(1) {
+			return
+		}
+		logInfo := logger.WithField("host_name", hostName)
+		if err != nil {
+			// 無法確認租約是否仍屬於自己, 先讓出避免與新 leader 同時執行
+			logInfo.WithField("err", err).Warn("Leader lease renew failed")
+		} else {
+			// 租約已過期或已由其他節點取得
+			holder, _ := redisCacher.Conn.Get(leaderKey).Result()
+			logInfo.WithField("leader", holder).Warn("Leader lease lost")
+		}
+		stepDown(onRevoked)
+		return
+	}
+
+	ok, err := redisCacher.Conn.SetNX(leaderKey, hostName, leaseTime)
+	if err != nil || !ok {
+		return
+	}
+
+	n, err := redisCacher.Conn.Incr(tokenKey)
+	if err != nil {
+		redisCacher.Conn.Eval(releaseScript, []string{leaderKey}, hostName)
+		return
+	}
+
+	mutex.Lock()
+	isLeader = true
+	token = n
+	mutex.Unlock()
+
+	logger.WithFields(logrus.Fields{
+		"host_name": hostName,
+		"token":     n,
+	}).Info("Leader elected")
+
+	onElected()
+}
+
+func resign(onRevoked func()) {
+	if !IsLeader() {
+		return
+	}
+	redisCacher.Conn.Eval(releaseScript, []string{leaderKey}, hostName)
+	stepDown(onRevoked)
+}
+
+func stepDown(onRevoked func()) {
+	mutex.Lock()
+	isLeader = false
+	mutex.Unlock()
+
+	onRevoked()
+}
+
+// 查詢選主狀態
+func GetStatus() Status {
+	mutex.RLock()
+	status := Status{
+		Enabled:   enabled,
+		HostName:  hostName,
+		IsLeader:  isLeader,
+		Token:     token,
+		LeaseTime: leaseTime,
+	}
+	mutex.RUnlock()
+
+	if enabled {
+		status.Leader, _ = redisCacher.Conn.Get(leaderKey).Result()
+	}
+
+	return status
+}
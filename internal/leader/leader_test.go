@@ -0,0 +1,120 @@
+package leader
+
+import (
+	"bytes"
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func setLeaderTest(name string) {
+	redisCacher.SetMiniredis()
+
+	logger = server.GetServerInstance().GetLogger()
+	enabled = true
+	hostName = name
+	leaseTime = defaultLeaseTime
+	isLeader = false
+	token = 0
+}
+
+func TestCampaign(t *testing.T) {
+	setLeaderTest("node-a")
+
+	var elected, revoked int
+	onElected := func() { elected++ }
+	onRevoked := func() { revoked++ }
+
+	t.Run("acquire lease", func(t *testing.T) {
+		campaign(onElected, onRevoked)
+		assert.True(t, IsLeader())
+		assert.True(t, Fence())
+		assert.Equal(t, 1, elected)
+
+		status := GetStatus()
+		assert.Equal(t, "node-a", status.Leader)
+		assert.Equal(t, int64(1), status.Token)
+	})
+
+	t.Run("renew lease", func(t *testing.T) {
+		campaign(onElected, onRevoked)
+		assert.True(t, IsLeader())
+		assert.Equal(t, 1, elected)
+		assert.Equal(t, 0, revoked)
+
+		ttl, err := redisCacher.Conn.TTL(leaderKey)
+		assert.Nil(t, err)
+		assert.Equal(t, int(defaultLeaseTime), ttl)
+	})
+
+	t.Run("newer leader fences old one", func(t *testing.T) {
+		// 模擬租約過期後 node-b 當選
+		redisCacher.Conn.Set(leaderKey, "node-b", defaultLeaseTime)
+		redisCacher.Conn.Incr(tokenKey)
+		assert.False(t, Fence())
+
+		campaign(onElected, onRevoked)
+		assert.False(t, IsLeader())
+		assert.Equal(t, 1, revoked)
+	})
+
+	t.Run("follower takes over after lease expires", func(t *testing.T) {
+		redisCacher.Conn.Del(leaderKey)
+
+		campaign(onElected, onRevoked)
+		assert.True(t, IsLeader())
+		assert.True(t, Fence())
+		assert.Equal(t, 2, elected)
+		assert.Equal(t, int64(3), GetStatus().Token)
+	})
+
+	t.Run("resign releases lease", func(t *testing.T) {
+		resign(onRevoked)
+		assert.False(t, IsLeader())
+		assert.Equal(t, 2, revoked)
+
+		_, err := redisCacher.Conn.Get(leaderKey).Result()
+		assert.Equal(t, redisCacher.ErrNil.Error(), err.Error())
+	})
+}
+
+func TestFenceDisabled(t *testing.T) {
+	setLeaderTest("node-a")
+	enabled = false
+
+	assert.True(t, Fence())
+}
+
+func TestLeaseLostLog(t *testing.T) {
+	setLeaderTest("node-a")
+	var buf bytes.Buffer
+	logger = logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	noop := func() {}
+
+	// 其他節點取得租約, 記錄目前的 leader
+	campaign(noop, noop)
+	assert.True(t, IsLeader())
+	redisCacher.Conn.Set(leaderKey, "node-b", defaultLeaseTime)
+	campaign(noop, noop)
+	assert.False(t, IsLeader())
+	assert.Contains(t, buf.String(), `"msg":"Leader lease lost"`)
+	assert.Contains(t, buf.String(), `"leader":"node-b"`)
+	assert.NotContains(t, buf.String(), "<nil>")
+
+	// 延長失敗時記錄錯誤原因
+	buf.Reset()
+	redisCacher.Conn.Del(leaderKey)
+	campaign(noop, noop)
+	assert.True(t, IsLeader())
+	redisCacher.Conn.(*redisCacher.RedisPool).RedisConn.Close()
+	campaign(noop, noop)
+	assert.False(t, IsLeader())
+	assert.Contains(t, buf.String(), `"msg":"Leader lease renew failed"`)
+	assert.Contains(t, buf.String(), `"err":"redis: client is closed"`)
+}
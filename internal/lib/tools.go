@@ -44,19 +44,19 @@ func ShouldExecuteNow(memo string) bool {
 		return false
 	}
 
-	loc, _ := time.LoadLocation("Asia/Taipei")
-	now := time.Now().In(loc)
+	// 只比較 timestamp, 與時區無關
+	now := time.Now()
 
 	t1 := taskTime.IntPart()
-	crontime := time.Unix(t1, 0).In(loc)
+	crontime := time.Unix(t1, 0)
 
 	d1 := crontime.Sub(now)
 
 	return d1 <= 0
 }
 
-// t1 執行時間大於現在時間往前3h,  true:執行 false:不執行
-func ShouldExecuteThreeHours(memo string) bool {
+// t1 執行時間大於現在時間往前 grace,  true:執行 false:不執行
+func ShouldExecuteWithin(memo string, grace time.Duration) bool {
 	if !IsMemoOnce(memo) {
 		return false
 	}
@@ -70,17 +70,16 @@ func ShouldExecuteThreeHours(memo string) bool {
 		return false
 	}
 
-	loc, _ := time.LoadLocation("Asia/Taipei")
-	now := time.Now().In(loc)
+	now := time.Now()
 
-	threeHoursAgo := now.Add(-3 * time.Hour).Unix()
+	graceAgo := now.Add(-grace).Unix()
 
 	t1 := taskTime.IntPart()
 
-	// 檢查 t1 是否大於前3小時 -  3小時之內:true
-	isWithinThreeHours := t1 >= threeHoursAgo
+	// 檢查 t1 是否大於前 grace - grace 之內:true
+	isWithinGrace := t1 >= graceAgo
 
-	return isWithinThreeHours
+	return isWithinGrace
 }
 
 // 排序資料
@@ -95,7 +95,7 @@ func TestShouldExecuteNow(t *testing.T) {
 	}
 }
 
-func TestShouldExecuteThreeHours(t *testing.T) {
+func TestShouldExecuteWithin(t *testing.T) {
 	now := time.Now().UTC()
 	loc, _ := time.LoadLocation("Asia/Taipei")
 	tt := now.In(loc)
@@ -138,7 +138,7 @@ func TestShouldExecuteThreeHours(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.memo, func(t *testing.T) {
-			if result := ShouldExecuteThreeHours(tc.memo); result != tc.expected {
+			if result := ShouldExecuteWithin(tc.memo, 3*time.Hour); result != tc.expected {
 				t.Errorf("CheckRightNow(%q) = %v, expected %v", tc.memo, result, tc.expected)
 			}
 
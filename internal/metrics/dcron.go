@@ -0,0 +1,37 @@
+package metrics
+
+// 執行時間的 bucket(秒), 預設逾時為 20 秒
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60}
+
+// 排程延遲的 bucket(秒)
+var lagBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+var (
+	Executions = NewCounterVec("dcron_job_executions_total",
+		"Job executions by group, type and outcome.", "group", "type", "outcome")
+	ExecutionDuration = NewHistogramVec("dcron_job_execution_duration_seconds",
+		"Job execution latency in seconds, skipped executions excluded.", durationBuckets, "group", "type")
+	ScheduleLag = NewHistogramVec("dcron_job_schedule_lag_seconds",
+		"Delay between the scheduled time and the actual start of cron triggered executions.", lagBuckets, "group")
+	LockSkips = NewCounterVec("dcron_job_lock_skips_total",
+		"Executions skipped because the run lock was held by another node or trigger.", "group")
+
+	RedisErrors = NewCounterVec("dcron_redis_errors_total",
+		"Redis command errors by command, nil replies excluded.", "command")
+	NsqErrors = NewCounterVec("dcron_nsq_errors_total",
+		"NSQ publish errors by topic.", "topic")
+
+	WorkerQueueLength = NewGauge("dcron_worker_queue_length",
+		"Jobs waiting in the worker pool queue.")
+	WorkerWorkingJobs = NewGauge("dcron_worker_working_jobs",
+		"Jobs being processed by the worker pool.")
+	WorkerCount = NewGauge("dcron_worker_count",
+		"Workers in the worker pool.")
+
+	JobsRegistered = NewGauge("dcron_jobs_registered",
+		"Jobs registered in Redis.")
+	JobsActive = NewGauge("dcron_jobs_active",
+		"Registered jobs that are active.")
+	JobsScheduled = NewGauge("dcron_jobs_scheduled",
+		"Cron entries scheduled on this node.")
+)
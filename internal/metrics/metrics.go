@@ -0,0 +1,253 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 輸出為 Prometheus text format 的指標
+type collector interface {
+	write(w io.Writer)
+}
+
+var (
+	mu         sync.Mutex
+	collectors []collector
+)
+
+func register(c collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	collectors = append(collectors, c)
+}
+
+// 依註冊順序輸出所有指標
+func Write(w io.Writer) {
+	mu.Lock()
+	list := append([]collector(nil), collectors...)
+	mu.Unlock()
+
+	for _, c := range list {
+		c.write(w)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	help = strings.ReplaceAll(help, `\`, `\\`)
+	help = strings.ReplaceAll(help, "\n", `\n`)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// {a="1",b="2"}, 無 label 時為空字串
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(names))
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escape(values[i])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return strings.ReplaceAll(v, `"`, `\"`)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// label 值數量需與 label 名稱相同, 不足補空值, 多的捨棄
+func labelValues(names, values []string) []string {
+	ret := make([]string, len(names))
+	copy(ret, values)
+	return ret
+}
+
+// 只增不減的計數
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+	keys   map[string][]string
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+		keys:   make(map[string][]string),
+	}
+	register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *CounterVec) Add(v float64, values ...string) {
+	values = labelValues(c.labels, values)
+	key := strings.Join(values, "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += v
+	c.keys[key] = values
+}
+
+// 目前的計數
+func (c *CounterVec) Value(values ...string) float64 {
+	key := strings.Join(labelValues(c.labels, values), "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, c.keys[key]), formatFloat(c.values[key]))
+	}
+}
+
+// 可增可減的數值
+type Gauge struct {
+	name string
+	help string
+	bits uint64
+}
+
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	register(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+func (g *Gauge) write(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.Value()))
+}
+
+// 分布, buckets 為各區間的上限(包含), 由小到大
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	labels []string
+	counts []uint64 // 落在各 bucket 的次數, 非累計
+	sum    float64
+	count  uint64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*histogram),
+	}
+	register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	values = labelValues(h.labels, values)
+	key := strings.Join(values, "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{labels: values, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// 目前的次數
+func (h *HistogramVec) Count(values ...string) uint64 {
+	key := strings.Join(labelValues(h.labels, values), "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[key]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	names := append(append([]string(nil), h.labels...), "le")
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := h.series[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			values := append(append([]string(nil), s.labels...), formatFloat(upper))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(names, values), cumulative)
+		}
+		values := append(append([]string(nil), s.labels...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(names, values), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, s.labels), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, s.labels), s.count)
+	}
+}
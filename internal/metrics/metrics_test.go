@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterVec(t *testing.T) {
+	c := NewCounterVec("test_counter_total", "Test counter.", "group", "outcome")
+	c.Inc("b", "success")
+	c.Inc("a", "failed")
+	c.Add(2, "b", "success")
+
+	assert.Equal(t, float64(3), c.Value("b", "success"))
+	assert.Equal(t, float64(0), c.Value("c", "success"))
+
+	var buf bytes.Buffer
+	c.write(&buf)
+	assert.Equal(t, `# HELP test_counter_total Test counter.
+# TYPE test_counter_total counter
+test_counter_total{group="a",outcome="failed"} 1
+test_counter_total{group="b",outcome="success"} 3
+`, buf.String())
+}
+
+func TestGauge(t *testing.T) {
+	g := NewGauge("test_gauge", "Test gauge.")
+	g.Set(1.5)
+	g.Set(2)
+
+	var buf bytes.Buffer
+	g.write(&buf)
+	assert.Equal(t, "# HELP test_gauge Test gauge.\n# TYPE test_gauge gauge\ntest_gauge 2\n", buf.String())
+}
+
+func TestHistogramVec(t *testing.T) {
+	h := NewHistogramVec("test_seconds", "Test histogram.", []float64{0.1, 1}, "group")
+	h.Observe(0.05, "a")
+	h.Observe(0.5, "a")
+	h.Observe(3, "a")
+
+	assert.Equal(t, uint64(3), h.Count("a"))
+
+	var buf bytes.Buffer
+	h.write(&buf)
+	assert.Equal(t, `# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{group="a",le="0.1"} 1
+test_seconds_bucket{group="a",le="1"} 2
+test_seconds_bucket{group="a",le="+Inf"} 3
+test_seconds_sum{group="a"} 3.55
+test_seconds_count{group="a"} 3
+`, buf.String())
+}
+
+func TestWrite(t *testing.T) {
+	c := NewCounterVec("test_escape_total", "Test escape.", "name")
+	c.Inc("a\"b\\c\nd")
+
+	var buf bytes.Buffer
+	Write(&buf)
+	assert.Contains(t, buf.String(), `test_escape_total{name="a\"b\\c\nd"} 1`)
+	assert.True(t, strings.Contains(buf.String(), "# TYPE dcron_job_executions_total counter"))
+
+	// 所有註冊的指標都有輸出
+	for _, name := range []string{"dcron_job_execution_duration_seconds", "dcron_job_schedule_lag_seconds", "dcron_job_lock_skips_total",
+		"dcron_redis_errors_total", "dcron_nsq_errors_total", "dcron_worker_queue_length", "dcron_worker_working_jobs",
+		"dcron_worker_count", "dcron_jobs_registered", "dcron_jobs_active", "dcron_jobs_scheduled"} {
+		assert.Contains(t, buf.String(), "# TYPE "+name+" ")
+	}
+}
+
+func TestWriteEscape(t *testing.T) {
+	g := NewGauge("test_help_escape", "Help with \\ and\nnewline.")
+	h := NewHistogramVec("test_escape_seconds", "Test escape.", []float64{1}, "group")
+	h.Observe(0.5, "group\n\"2\"")
+
+	var buf bytes.Buffer
+	g.write(&buf)
+	h.write(&buf)
+	assert.Equal(t, `# HELP test_help_escape Help with \\ and\nnewline.
+# TYPE test_help_escape gauge
+test_help_escape 0
+# HELP test_escape_seconds Test escape.
+# TYPE test_escape_seconds histogram
+test_escape_seconds_bucket{group="group\n\"2\"",le="1"} 1
+test_escape_seconds_bucket{group="group\n\"2\"",le="+Inf"} 1
+test_escape_seconds_sum{group="group\n\"2\""} 0.5
+test_escape_seconds_count{group="group\n\"2\""} 1
+`, buf.String())
+}
@@ -0,0 +1,367 @@
+package notify
+
+import (
+	"context"
+	"dcron/internal/history"
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	channelKey = "NOTIFY_CHANNEL"
+	groupKey   = "GROUP_NOTIFY"
+
+	defaultThreshold = 3
+	defaultDedup     = 300
+	streakTTL        = 7 * 24 * 60 * 60
+)
+
+// 通知方式
+const (
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeEmail   = "email"
+)
+
+// 通知事件
+const (
+	EventFailure     = "failure"     // 每次失敗
+	EventConsecutive = "consecutive" // 連續失敗達到 threshold 次
+	EventRecovery    = "recovery"    // 失敗後恢復成功
+	EventTest        = "test"        // 手動測試
+)
+
+var Types = []string{TypeWebhook, TypeSlack, TypeEmail}
+
+var Events = []string{EventFailure, EventConsecutive, EventRecovery}
+
+var ErrNotFound = errors.New("notify channel not found")
+
+var logger *logrus.Logger
+
+/*
+ * 通知管道, 可掛在群組或任務上, 兩者合併通知
+ * redis資料格式
+ *
+ * key: NOTIFY_CHANNEL
+ * ["oncall"] = {"name":"oncall","type":"slack","url":"https://hooks.slack.com/services/...","events":["consecutive","recovery"]}
+ *
+ * key: GROUP_NOTIFY
+ * ["test"] = "oncall,mail"
+ *
+ * key: NOTIFY_STREAK_{job_id} 連續失敗次數
+ * key: NOTIFY_DEDUP_{channel}_{job_id}_{event} 去重, dedup 秒後過期
+ */
+type Channel struct {
+	Name      string            `json:"name" example:"oncall"`                         // 通知管道名稱
+	Type      string            `json:"type" example:"slack"`                          // `webhook` `slack` `email`
+	Url       string            `json:"url" example:"https://hooks.slack.com/xxx"`     // webhook 及 slack 的網址
+	Headers   map[string]string `json:"headers"`                                       // webhook 自訂 header
+	To        []string          `json:"to" example:"oncall@example.com"`               // email 收件人
+	Events    []string          `json:"events" example:"failure,consecutive,recovery"` // `failure` `consecutive` `recovery`, 空值為全部
+	Threshold int               `json:"threshold" example:"3"`                         // consecutive 的連續失敗次數, 0 為 3
+	Dedup     int64             `json:"dedup" example:"300"`                           // 同一任務同一事件的最短通知間隔(秒), 0 為 300
+	Template  string            `json:"template"`                                      // Go text/template, 空值使用預設訊息
+}
+
+// 通知內容, template 可使用執行紀錄的欄位 ex. `{{.GroupName}}` `{{.Error}}`
+type Event struct {
+	Event    string `json:"event"`    // `failure` `consecutive` `recovery` `test`
+	Failures int64  `json:"failures"` // 連續失敗次數, recovery 為恢復前的次數
+	Channel  string `json:"channel"`  // 送出的通知管道
+	*history.Record
+}
+
+func ConfigInit() {
+	instance := server.GetServerInstance()
+	env := instance.GetEnv()
+	logger = instance.GetLogger()
+
+	smtpConfig = smtpSetting{
+		Host:     env.SmtpHost,
+		Port:     env.SmtpPort,
+		Username: env.SmtpUsername,
+		Password: env.SmtpPassword,
+		From:     env.SmtpFrom,
+	}
+}
+
+// 檢查通知管道設定
+func (c *Channel) Validate() error {
+	if c.Name == "" {
+		return errors.New("notify channel name is empty")
+	}
+	// 任務及群組以逗號分隔存放名稱
+	if strings.Contains(c.Name, ",") {
+		return errors.New("notify channel name must not contain comma")
+	}
+	switch c.Type {
+	case TypeWebhook, TypeSlack:
+		u, err := url.ParseRequestURI(c.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("notify channel url %s is invalid", c.Url)
+		}
+	case TypeEmail:
+		if len(c.To) == 0 {
+			return errors.New("notify channel to is empty")
+		}
+	default:
+		return fmt.Errorf("notify channel type must be one of %s", strings.Join(Types, ", "))
+	}
+	for _, e := range c.Events {
+		if !contains(Events, e) {
+			return fmt.Errorf("notify channel event must be one of %s", strings.Join(Events, ", "))
+		}
+	}
+	if c.Threshold < 0 {
+		return errors.New("notify channel threshold must not be negative")
+	}
+	if c.Dedup < 0 {
+		return errors.New("notify channel dedup must not be negative")
+	}
+	if c.Template != "" {
+		if _, err := template.New(c.Name).Parse(c.Template); err != nil {
+			return fmt.Errorf("notify channel template is invalid: %v", err)
+		}
+	}
+	return nil
+}
+
+// 是否訂閱事件, 未設定時訂閱全部
+func (c *Channel) Subscribed(event string) bool {
+	return len(c.Events) == 0 || contains(c.Events, event)
+}
+
+func (c *Channel) threshold() int64 {
+	if c.Threshold > 0 {
+		return int64(c.Threshold)
+	}
+	return defaultThreshold
+}
+
+func (c *Channel) dedup() int64 {
+	if c.Dedup > 0 {
+		return c.Dedup
+	}
+	return defaultDedup
+}
+
+// 本次結果要通知的事件, 不需通知時回傳空值
+func (c *Channel) eventFor(outcome string, failures int64) string {
+	switch outcome {
+	case history.OutcomeFailed:
+		if c.Subscribed(EventConsecutive) && failures == c.threshold() {
+			return EventConsecutive
+		}
+		if c.Subscribed(EventFailure) {
+			return EventFailure
+		}
+	case history.OutcomeSuccess:
+		if !c.Subscribed(EventRecovery) || failures == 0 {
+			return ""
+		}
+		// 只訂閱連續失敗時, 未達 threshold 不需通知恢復
+		if !c.Subscribed(EventFailure) && failures < c.threshold() {
+			return ""
+		}
+		return EventRecovery
+	}
+	return ""
+}
+
+// 新增或更新通知管道
+func Save(ctx context.Context, c Channel) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return redisCacher.Conn.WithContext(ctx).HSet(channelKey, map[string]interface{}{c.Name: string(b)}, 0)
+}
+
+// 取得通知管道
+func Get(ctx context.Context, name string) (Channel, error) {
+	var c Channel
+
+	data, err := redisCacher.Conn.WithContext(ctx).HGet(channelKey, name).Result()
+	if redisCacher.IsNil(err) || (err == nil && data == "") {
+		return c, ErrNotFound
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// 所有通知管道, 依名稱排序
+func List(ctx context.Context) ([]Channel, error) {
+	ret := make([]Channel, 0)
+
+	data, err := redisCacher.Conn.WithContext(ctx).HGetAll(channelKey)
+	if err != nil {
+		return ret, err
+	}
+	for _, v := range data {
+		var c Channel
+		if err := json.Unmarshal([]byte(v), &c); err == nil {
+			ret = append(ret, c)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+
+	return ret, nil
+}
+
+// 刪除通知管道, 引用的群組及任務不再通知
+func Delete(ctx context.Context, name string) error {
+	if _, err := Get(ctx, name); err != nil {
+		return err
+	}
+	return redisCacher.Conn.WithContext(ctx).HDel(channelKey, name)
+}
+
+// 群組的通知管道
+func GroupChannels(ctx context.Context, groupName string) []string {
+	data, _ := redisCacher.Conn.WithContext(ctx).HGet(groupKey, groupName).Result()
+	return Split(data)
+}
+
+// 設定群組的通知管道, 空值為清除
+func SetGroupChannels(ctx context.Context, groupName string, names []string) error {
+	if len(names) == 0 {
+		return redisCacher.Conn.WithContext(ctx).HDel(groupKey, groupName)
+	}
+	if err := Exists(ctx, names); err != nil {
+		return err
+	}
+	return redisCacher.Conn.WithContext(ctx).HSet(groupKey, map[string]interface{}{groupName: strings.Join(names, ",")}, 0)
+}
+
+// 檢查通知管道都已建立
+func Exists(ctx context.Context, names []string) error {
+	for _, name := range names {
+		_, err := Get(ctx, name)
+		if err == ErrNotFound {
+			return fmt.Errorf("notify channel %s is not found", name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 以逗號分隔的名稱
+func Split(data string) []string {
+	if data == "" {
+		return nil
+	}
+	return strings.Split(data, ",")
+}
+
+// 任務與群組的通知管道, 重複的只通知一次
+func resolve(ctx context.Context, groupName string, names []string) []Channel {
+	seen := make(map[string]bool)
+	ret := make([]Channel, 0)
+	for _, name := range append(append([]string(nil), names...), GroupChannels(ctx, groupName)...) {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if c, err := Get(ctx, name); err == nil {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func streakKey(jobID string) string {
+	return fmt.Sprintf("NOTIFY_STREAK_%s", jobID)
+}
+
+func dedupKey(channel, jobID, event string) string {
+	return fmt.Sprintf("NOTIFY_DEDUP_%s_%s_%s", channel, jobID, event)
+}
+
+// 更新連續失敗次數, 成功時回傳恢復前的次數並清除
+func updateStreak(ctx context.Context, rec *history.Record) int64 {
+	key := streakKey(rec.JobID)
+	if rec.Outcome == history.OutcomeFailed {
+		n, err := redisCacher.Conn.WithContext(ctx).Incr(key)
+		if err != nil {
+			return 0
+		}
+		redisCacher.Conn.WithContext(ctx).Expire(key, streakTTL)
+		return n
+	}
+
+	n, _ := redisCacher.Conn.WithContext(ctx).Get(key).Int64()
+	if n > 0 {
+		redisCacher.Conn.WithContext(ctx).Del(key)
+	}
+	return n
+}
+
+// 執行結果通知, 只處理成功及失敗; 送出在背景執行, 不影響任務
+func Result(ctx context.Context, rec *history.Record, names []string) {
+	if rec.Outcome != history.OutcomeFailed && rec.Outcome != history.OutcomeSuccess {
+		return
+	}
+	channels := resolve(ctx, rec.GroupName, names)
+	if len(channels) == 0 {
+		return
+	}
+
+	failures := updateStreak(ctx, rec)
+	// 背景送出時不與呼叫端共用紀錄
+	snapshot := *rec
+	for _, c := range channels {
+		event := c.eventFor(rec.Outcome, failures)
+		if event == "" {
+			continue
+		}
+		ok, err := redisCacher.Conn.WithContext(ctx).SetNX(dedupKey(c.Name, rec.JobID, event), 1, c.dedup())
+		if err != nil || !ok {
+			continue
+		}
+
+		ev := Event{Event: event, Failures: failures, Channel: c.Name, Record: &snapshot}
+		c := c
+		pending.Add(1)
+		go func() {
+			defer pending.Done()
+			if err := Send(c, ev); err != nil && logger != nil {
+				logger.WithFields(map[string]interface{}{
+					"func":       "notify_send",
+					"channel":    c.Name,
+					"event":      ev.Event,
+					"group_name": rec.GroupName,
+					"job_id":     rec.JobID,
+				}).Errorf("notify send error: %v", err)
+			}
+		}()
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
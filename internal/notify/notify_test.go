@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"context"
+	"dcron/internal/history"
+	"dcron/internal/redisCacher"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 收到的通知內容
+type receiver struct {
+	mu     sync.Mutex
+	bodies []map[string]interface{}
+}
+
+func (r *receiver) received() []map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]interface{}(nil), r.bodies...)
+}
+
+func newReceiver(t *testing.T) (*receiver, *httptest.Server) {
+	r := &receiver{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		var body map[string]interface{}
+		json.Unmarshal(b, &body)
+		body["header"] = req.Header.Get("X-Token")
+
+		r.mu.Lock()
+		r.bodies = append(r.bodies, body)
+		r.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return r, srv
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel Channel
+		err     string
+	}{
+		{"name", Channel{Type: TypeSlack, Url: "https://hooks.slack.com/x"}, "notify channel name is empty"},
+		{"comma", Channel{Name: "a,b", Type: TypeSlack, Url: "https://hooks.slack.com/x"}, "notify channel name must not contain comma"},
+		{"type", Channel{Name: "c", Type: "sms"}, "notify channel type must be one of webhook, slack, email"},
+		{"url", Channel{Name: "c", Type: TypeWebhook, Url: "ftp://example.com"}, "notify channel url ftp://example.com is invalid"},
+		{"to", Channel{Name: "c", Type: TypeEmail}, "notify channel to is empty"},
+		{"event", Channel{Name: "c", Type: TypeEmail, To: []string{"a@example.com"}, Events: []string{"success"}}, "notify channel event must be one of failure, consecutive, recovery"},
+		{"threshold", Channel{Name: "c", Type: TypeEmail, To: []string{"a@example.com"}, Threshold: -1}, "notify channel threshold must not be negative"},
+		{"dedup", Channel{Name: "c", Type: TypeEmail, To: []string{"a@example.com"}, Dedup: -1}, "notify channel dedup must not be negative"},
+		{"template", Channel{Name: "c", Type: TypeEmail, To: []string{"a@example.com"}, Template: "{{.Name"}, "notify channel template is invalid: template: c:1: unclosed action"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.channel.Validate()
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestEventFor(t *testing.T) {
+	all := Channel{}
+	streak := Channel{Events: []string{EventConsecutive, EventRecovery}, Threshold: 2}
+
+	tests := []struct {
+		name     string
+		channel  Channel
+		outcome  string
+		failures int64
+		want     string
+	}{
+		{"failure", all, history.OutcomeFailed, 1, EventFailure},
+		{"consecutive", all, history.OutcomeFailed, 3, EventConsecutive},
+		{"after consecutive", all, history.OutcomeFailed, 4, EventFailure},
+		{"recovery", all, history.OutcomeSuccess, 1, EventRecovery},
+		{"success", all, history.OutcomeSuccess, 0, ""},
+		{"streak not reached", streak, history.OutcomeFailed, 1, ""},
+		{"streak reached", streak, history.OutcomeFailed, 2, EventConsecutive},
+		{"streak over", streak, history.OutcomeFailed, 3, ""},
+		{"streak recovery", streak, history.OutcomeSuccess, 2, EventRecovery},
+		{"streak short recovery", streak, history.OutcomeSuccess, 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.channel.eventFor(tt.outcome, tt.failures))
+		})
+	}
+}
+
+func TestResult(t *testing.T) {
+	redisCacher.SetMiniredis()
+
+	webhook, webhookSrv := newReceiver(t)
+	slack, slackSrv := newReceiver(t)
+
+	assert.Nil(t, Save(context.Background(), Channel{Name: "hook", Type: TypeWebhook, Url: webhookSrv.URL, Headers: map[string]string{"X-Token": "t1"}}))
+	assert.Nil(t, Save(context.Background(), Channel{
+		Name:     "oncall",
+		Type:     TypeSlack,
+		Url:      slackSrv.URL,
+		Events:   []string{EventConsecutive, EventRecovery},
+		Template: `{{.Event}} {{.GroupName}}/{{.Name}} {{.Failures}}`,
+	}))
+	assert.NotNil(t, SetGroupChannels(context.Background(), "test", []string{"missing"}))
+	assert.Nil(t, SetGroupChannels(context.Background(), "test", []string{"oncall", "hook"}))
+	assert.Equal(t, []string{"oncall", "hook"}, GroupChannels(context.Background(), "test"))
+
+	run := func(outcome string) {
+		Result(context.Background(), &history.Record{JobID: "500001", GroupName: "test", Name: "job01", Outcome: outcome, Error: "status 500"}, []string{"hook"})
+		assert.Nil(t, Wait(context.Background()))
+	}
+
+	// 每秒失敗的任務, failure 在 dedup 時間內只通知一次
+	for i := 0; i < 3; i++ {
+		run(history.OutcomeFailed)
+	}
+	// 略過的執行不影響連續失敗次數
+	run(history.OutcomeSkipped)
+	run(history.OutcomeSuccess)
+	run(history.OutcomeSuccess)
+
+	hooks := webhook.received()
+	if assert.Len(t, hooks, 3) {
+		assert.Equal(t, EventFailure, hooks[0]["event"])
+		assert.Equal(t, "500001", hooks[0]["job_id"])
+		assert.Equal(t, "status 500", hooks[0]["error"])
+		assert.Equal(t, "t1", hooks[0]["header"])
+		assert.Equal(t, "hook", hooks[0]["channel"])
+		assert.Equal(t, "[dcron] test/job01 (500001) failed: status 500", hooks[0]["text"])
+		assert.Equal(t, EventConsecutive, hooks[1]["event"])
+		assert.Equal(t, float64(3), hooks[1]["failures"])
+		assert.Equal(t, EventRecovery, hooks[2]["event"])
+	}
+
+	texts := make([]interface{}, 0)
+	for _, body := range slack.received() {
+		texts = append(texts, body["text"])
+	}
+	assert.Equal(t, []interface{}{"consecutive test/job01 3", "recovery test/job01 3"}, texts)
+
+	// 沒有通知管道的群組不記錄連續失敗
+	Result(context.Background(), &history.Record{JobID: "500002", GroupName: "other", Outcome: history.OutcomeFailed}, nil)
+	assert.Equal(t, "", redisCacher.Conn.Get(streakKey("500002")).Val())
+
+	assert.Nil(t, SetGroupChannels(context.Background(), "test", nil))
+	assert.Nil(t, GroupChannels(context.Background(), "test"))
+}
+
+func TestGet(t *testing.T) {
+	redisCacher.SetMiniredis()
+
+	_, err := Get(context.Background(), "missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	// redis 異常不視為管道不存在
+	assert.Nil(t, redisCacher.Conn.Set(channelKey, "x", 0))
+	_, err = Get(context.Background(), "missing")
+	if assert.NotNil(t, err) {
+		assert.NotEqual(t, ErrNotFound, err)
+	}
+}
+
+func TestSendEmail(t *testing.T) {
+	var (
+		gotAddr string
+		gotTo   []string
+		gotMsg  string
+	)
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotTo, gotMsg = addr, to, string(msg)
+		return nil
+	}
+	defer func() {
+		sendMail = smtp.SendMail
+		smtpConfig = smtpSetting{}
+	}()
+
+	channel := Channel{Name: "mail", Type: TypeEmail, To: []string{"a@example.com", "b@example.com"}}
+	ev := Event{Event: EventFailure, Failures: 1, Record: &history.Record{JobID: "500003", GroupName: "test", Name: "job03", Error: "timeout"}}
+
+	assert.EqualError(t, Send(channel, ev), "smtp host is not configured")
+
+	smtpConfig = smtpSetting{Host: "smtp.example.com", From: "dcron@example.com"}
+	assert.Nil(t, Send(channel, ev))
+	assert.Equal(t, "smtp.example.com:25", gotAddr)
+	assert.Equal(t, channel.To, gotTo)
+	assert.True(t, strings.Contains(gotMsg, "To: a@example.com, b@example.com\r\n"))
+	assert.True(t, strings.Contains(gotMsg, "Subject: [dcron] test/job03 (500003) failed: timeout\r\n"))
+}
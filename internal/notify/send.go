@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const sendTimeout = 10 * time.Second
+
+var defaultTemplates = map[string]string{
+	EventFailure:     `[dcron] {{.GroupName}}/{{.Name}} ({{.JobID}}) failed: {{.Error}}`,
+	EventConsecutive: `[dcron] {{.GroupName}}/{{.Name}} ({{.JobID}}) failed {{.Failures}} times in a row: {{.Error}}`,
+	EventRecovery:    `[dcron] {{.GroupName}}/{{.Name}} ({{.JobID}}) recovered after {{.Failures}} failures`,
+	EventTest:        `[dcron] test notification from channel {{.Channel}}`,
+}
+
+type smtpSetting struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+var (
+	smtpConfig smtpSetting
+	sendMail   = smtp.SendMail
+	client     = &http.Client{Timeout: sendTimeout}
+
+	// 背景送出中的通知
+	pending sync.WaitGroup
+)
+
+// 等待背景送出中的通知完成, ctx 結束時不再等待
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 依管道設定的 template 產生訊息
+func Render(c Channel, ev Event) (string, error) {
+	text := c.Template
+	if text == "" {
+		text = defaultTemplates[ev.Event]
+	}
+	tmpl, err := template.New(c.Name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ev); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// 立即送出通知
+func Send(c Channel, ev Event) error {
+	text, err := Render(c, ev)
+	if err != nil {
+		return err
+	}
+
+	switch c.Type {
+	case TypeWebhook:
+		// 執行紀錄的欄位及訊息
+		body := struct {
+			Event
+			Text string `json:"text"`
+		}{ev, text}
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		return post(c.Url, c.Headers, b)
+	case TypeSlack:
+		b, err := json.Marshal(map[string]string{"text": text})
+		if err != nil {
+			return err
+		}
+		return post(c.Url, nil, b)
+	case TypeEmail:
+		return mail(c.To, text)
+	}
+	return fmt.Errorf("notify channel type %s is not supported", c.Type)
+}
+
+func post(url string, headers map[string]string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify webhook: %s", resp.Status)
+	}
+	return nil
+}
+
+// 訊息第一行為主旨
+func mail(to []string, text string) error {
+	if smtpConfig.Host == "" {
+		return errors.New("smtp host is not configured")
+	}
+	port := smtpConfig.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := fmt.Sprintf("%s:%d", smtpConfig.Host, port)
+
+	var auth smtp.Auth
+	if smtpConfig.Username != "" {
+		auth = smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpConfig.Host)
+	}
+
+	subject, _, _ := strings.Cut(text, "\n")
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", smtpConfig.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+
+	return sendMail(addr, auth, smtpConfig.From, to, msg.Bytes())
+}
@@ -1,12 +1,17 @@
 package nsqtarget
 
 import (
+	"context"
+	"dcron/internal/metrics"
+	"dcron/internal/tracing"
 	"dcron/server"
 	"encoding/json"
+	"errors"
 )
 
 type NSQProducer interface {
 	Publish(topic string, body []byte) error
+	Ping() error
 }
 
 var p NSQProducer
@@ -15,14 +20,38 @@ func ConfigInit() {
 	p = server.GetServerInstance().GetNSQProducer()
 }
 
-func Publish(topic string, message string) error {
+// 確認 nsqd 連線
+func Ping() error {
+	if p == nil {
+		return errors.New("nsq producer is not initialized")
+	}
+	return p.Ping()
+}
+
+// 啟用追蹤時訊息加上 traceparent 欄位, 供下游延續 trace
+func Publish(ctx context.Context, topic string, message string) error {
 	var data map[string]interface{}
 
 	err := json.Unmarshal([]byte(message), &data)
 	if err != nil {
 		return err
 	}
+
+	ctx, span := tracing.Start(ctx, topic+" publish", tracing.KindProducer,
+		tracing.String("messaging.system", "nsq"),
+		tracing.String("messaging.destination", topic),
+	)
+	defer span.End()
+
+	if traceparent := tracing.TraceParent(ctx); traceparent != "" && data != nil {
+		data["traceparent"] = traceparent
+	}
 	b, _ := json.Marshal(data)
 
-	return p.Publish(topic, b)
+	if err := p.Publish(topic, b); err != nil {
+		metrics.NsqErrors.Inc(topic)
+		span.SetError(err)
+		return err
+	}
+	return nil
 }
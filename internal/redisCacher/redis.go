@@ -2,8 +2,11 @@ package redisCacher
 
 import (
 	"context"
+	"dcron/internal/metrics"
+	"dcron/internal/tracing"
 	"dcron/server"
 	"errors"
+	"net"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
@@ -17,6 +20,11 @@ var (
 	IsMiniredis bool
 )
 
+// key 或欄位不存在, 與連線等其他錯誤區分
+func IsNil(err error) bool {
+	return err != nil && err.Error() == ErrNil.Error()
+}
+
 type RedisPool struct {
 	RedisConn *redis.Client
 	Ctx       *context.Context
@@ -35,6 +43,8 @@ type IRedis interface {
 	DelKeys(keys []string) error
 	/** 取單一欄位資料 **/
 	HGet(key string, field string) *redis.StringCmd
+	/** 以 pipeline 取多個 key 的同一欄位, 不存在的為空字串 **/
+	HGetKeys(keys []string, field string) ([]string, error)
 	/** 寫入一對多(key:fields[value]...)資料 **/
 	HSet(key string, values map[string]interface{}, ttl int64) error
 	/** 刪除一欄位資料 **/
@@ -49,15 +59,33 @@ type IRedis interface {
 	TTL(key string) (int, error)
 	/** 清空資料 **/
 	Flushall() (err error)
-	/** 訂閱頻道的信息 **/
-	Subscribe(callable func(channel string, data []byte) error, channel string)
+	/** 訂閱頻道的信息, 直到 ctx 結束, 以 WithContext 指定可取消的 ctx **/
+	Subscribe(callable func(channel string, data []byte) error, channel string) error
 	/** 發送信息到指定的頻道 **/
 	Publish(channel, message string) error
+	/** 數值加一 **/
+	Incr(key string) (int64, error)
+	/** 執行 lua script **/
+	Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+	/** 寫入 stream, 超過 maxLen 筆時移除最舊的資料 **/
+	XAdd(key string, values map[string]interface{}, maxLen int64, ttl int64) (string, error)
+	/** 由新到舊取出 stream 資料 **/
+	XRevRange(key, end, start string, count int64) ([]redis.XMessage, error)
+	/** 由舊到新取出 stream 資料 **/
+	XRange(key, start, end string, count int64) ([]redis.XMessage, error)
+	/** 刪除 stream 資料 **/
+	XDel(key string, ids ...string) (int64, error)
+	/** 以 ctx 執行指令, ctx 帶有 span 時記錄 redis 子 span **/
+	WithContext(ctx context.Context) IRedis
+	/** 確認連線 **/
+	Ping() error
 }
 
 func ConfigInit() {
 	instance := server.GetServerInstance()
 	redisCacher := instance.GetRedisCacher()
+	redisCacher.AddHook(metricsHook{})
+	redisCacher.AddHook(traceHook{})
 	ctx := instance.GetGracefulCtx()
 	Conn = &RedisPool{
 		RedisConn: redisCacher,
@@ -71,6 +99,8 @@ func SetMiniredis() {
 	rdb := redis.NewClient(&redis.Options{
 		Addr: m.Addr(),
 	})
+	rdb.AddHook(metricsHook{})
+	rdb.AddHook(traceHook{})
 	ctx := context.Background()
 	Conn = &RedisPool{
 		RedisConn: rdb,
@@ -114,6 +144,23 @@ func (r *RedisPool) HGet(key string, field string) *redis.StringCmd {
 	return r.RedisConn.HGet(*r.Ctx, key, field)
 }
 
+/** 以 pipeline 取多個 key 的同一欄位, 不存在的為空字串 **/
+func (r *RedisPool) HGetKeys(keys []string, field string) ([]string, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	pipe := r.RedisConn.Pipeline()
+	for i, key := range keys {
+		cmds[i] = pipe.HGet(*r.Ctx, key, field)
+	}
+	if _, err := pipe.Exec(*r.Ctx); err != nil && !IsNil(err) {
+		return nil, err
+	}
+	ret := make([]string, len(keys))
+	for i, cmd := range cmds {
+		ret[i] = cmd.Val()
+	}
+	return ret, nil
+}
+
 /** 寫入一對多(key:fields...)資料 **/
 func (r *RedisPool) HSet(key string, values map[string]interface{}, ttl int64) (err error) {
 	var field []interface{}
@@ -178,20 +225,167 @@ func (r *RedisPool) Flushall() (err error) {
 	return r.RedisConn.Do(*r.Ctx, "flushall").Err()
 }
 
-func (r *RedisPool) Subscribe(callable func(channel string, data []byte) error, channel string) {
+func (r *RedisPool) Subscribe(callable func(channel string, data []byte) error, channel string) error {
 	pubSub := r.RedisConn.Subscribe(*r.Ctx, channel)
-	defer pubSub.Close()
+
+	// 等待訂閱完成, 避免訂閱前發送的信息遺失
+	if _, err := pubSub.Receive(*r.Ctx); err != nil {
+		pubSub.Close()
+		return err
+	}
 
 	// 處理消息
 	ch := pubSub.Channel()
 	go func() {
-		for msg := range ch {
-			//如果redis斷線 會自己重連
-			go callable(msg.Channel, []byte(msg.Payload))
+		defer pubSub.Close()
+		for {
+			select {
+			case <-(*r.Ctx).Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				//如果redis斷線 會自己重連, 依序處理確保事件順序
+				callable(msg.Channel, []byte(msg.Payload))
+			}
 		}
 	}()
+
+	return nil
 }
 
 func (r *RedisPool) Publish(channel, message string) error {
 	return r.RedisConn.Publish(*r.Ctx, channel, message).Err()
 }
+
+/** 數值加一 **/
+func (r *RedisPool) Incr(key string) (int64, error) {
+	return r.RedisConn.Incr(*r.Ctx, key).Result()
+}
+
+/** 執行 lua script **/
+func (r *RedisPool) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return r.RedisConn.Eval(*r.Ctx, script, keys, args...).Result()
+}
+
+/** 寫入 stream, 超過 maxLen 筆時移除最舊的資料 **/
+func (r *RedisPool) XAdd(key string, values map[string]interface{}, maxLen int64, ttl int64) (string, error) {
+	id, err := r.RedisConn.XAdd(*r.Ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: values,
+	}).Result()
+	if err == nil && ttl > 0 {
+		r.RedisConn.Expire(*r.Ctx, key, time.Duration(ttl)*time.Second)
+	}
+
+	return id, err
+}
+
+/** 由新到舊取出 stream 資料 **/
+func (r *RedisPool) XRevRange(key, end, start string, count int64) ([]redis.XMessage, error) {
+	return r.RedisConn.XRevRangeN(*r.Ctx, key, end, start, count).Result()
+}
+
+/** 由舊到新取出 stream 資料 **/
+func (r *RedisPool) XRange(key, start, end string, count int64) ([]redis.XMessage, error) {
+	return r.RedisConn.XRangeN(*r.Ctx, key, start, end, count).Result()
+}
+
+/** 刪除 stream 資料 **/
+func (r *RedisPool) XDel(key string, ids ...string) (int64, error) {
+	return r.RedisConn.XDel(*r.Ctx, key, ids...).Result()
+}
+
+/** 確認連線 **/
+func (r *RedisPool) Ping() error {
+	return r.RedisConn.Ping(*r.Ctx).Err()
+}
+
+/** 以 ctx 執行指令, ctx 帶有 span 時記錄 redis 子 span **/
+func (r *RedisPool) WithContext(ctx context.Context) IRedis {
+	return &RedisPool{
+		RedisConn: r.RedisConn,
+		Ctx:       &ctx,
+	}
+}
+
+// 記錄 redis 錯誤次數, redis.Nil 不計算
+type metricsHook struct{}
+
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			metrics.RedisErrors.Inc("dial")
+		}
+		return conn, err
+	}
+}
+
+func (metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if err != nil && err != redis.Nil {
+			metrics.RedisErrors.Inc(cmd.Name())
+		}
+		return err
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			if cmdErr := cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+				metrics.RedisErrors.Inc(cmd.Name())
+			}
+		}
+		return err
+	}
+}
+
+// ctx 帶有 span 時為每個指令建立子 span, 背景作業不產生新的 trace
+type traceHook struct{}
+
+func (traceHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (traceHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if tracing.SpanFromContext(ctx) == nil {
+			return next(ctx, cmd)
+		}
+		ctx, span := tracing.Start(ctx, "redis "+cmd.Name(), tracing.KindClient,
+			tracing.String("db.system", "redis"),
+			tracing.String("db.operation", cmd.Name()),
+		)
+		err := next(ctx, cmd)
+		if err != redis.Nil {
+			span.SetError(err)
+		}
+		span.End()
+		return err
+	}
+}
+
+func (traceHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if tracing.SpanFromContext(ctx) == nil {
+			return next(ctx, cmds)
+		}
+		ctx, span := tracing.Start(ctx, "redis pipeline", tracing.KindClient,
+			tracing.String("db.system", "redis"),
+			tracing.Int("db.redis.pipeline_length", len(cmds)),
+		)
+		err := next(ctx, cmds)
+		if err != redis.Nil {
+			span.SetError(err)
+		}
+		span.End()
+		return err
+	}
+}
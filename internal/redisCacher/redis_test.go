@@ -187,6 +187,24 @@ func TestHGetAll(t *testing.T) {
 	})
 }
 
+func TestHGetKeys(t *testing.T) {
+	setRedisTest()
+	t.Run("case 1", func(t *testing.T) {
+		err := Conn.HSet("Test:HGetKeys:1", map[string]interface{}{"status": 1}, 0)
+		assert.Equal(t, err, nil)
+		err = Conn.HSet("Test:HGetKeys:2", map[string]interface{}{"name": "a"}, 0)
+		assert.Equal(t, err, nil)
+
+		values, err := Conn.HGetKeys([]string{"Test:HGetKeys:1", "Test:HGetKeys:2", "Test:HGetKeys:3"}, "status")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, values, []string{"1", "", ""})
+
+		values, err = Conn.HGetKeys(nil, "status")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(values), 0)
+	})
+}
+
 func TestScan(t *testing.T) {
 	setRedisTest()
 	t.Run("case 1", func(t *testing.T) {
@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"context"
+	"dcron/internal/httptarget"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const maxAttempts = 10
+
+// 舊版 retry=true 的重試方式: 最多 3 次, 每次間隔 100ms
+var Legacy = Policy{
+	MaxAttempts:  3,
+	InitialDelay: 100,
+	Multiplier:   1,
+}
+
+// 任務的重試策略, 零值為不重試
+type Policy struct {
+	MaxAttempts  int     `json:"max_attempts" example:"3"`    // 最多嘗試次數(含第一次), 上限 10
+	InitialDelay int64   `json:"initial_delay" example:"100"` // 第一次重試前等待時間 MILLISECOND
+	Multiplier   float64 `json:"multiplier" example:"2"`      // 每次重試等待時間的倍數, 預設 1
+	MaxDelay     int64   `json:"max_delay" example:"5000"`    // 等待時間上限 MILLISECOND, 0 為不限制
+	Jitter       float64 `json:"jitter" example:"0.2"`        // 等待時間隨機浮動比例 0~1
+	RetryOn      string  `json:"retry_on" example:"429,5xx"`  // 需重試的 http 狀態碼, 格式同 success_rule codes, 空值表示失敗都重試
+}
+
+// 是否未設定
+func (p Policy) IsZero() bool {
+	return p == Policy{}
+}
+
+// 檢查策略格式
+func (p Policy) Validate() error {
+	if p.MaxAttempts < 0 || p.MaxAttempts > maxAttempts {
+		return errors.New("retry policy max_attempts must be between 0 and 10")
+	}
+	if p.InitialDelay < 0 || p.MaxDelay < 0 {
+		return errors.New("retry policy delay must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return errors.New("retry policy multiplier must be at least 1")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.New("retry policy jitter must be between 0 and 1")
+	}
+	if p.RetryOn != "" {
+		if err := httptarget.ValidateCodes(p.RetryOn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 總嘗試次數, 至少 1 次
+func (p Policy) Attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// 失敗後是否重試, code 為 0 表示沒有 http 回應(連線錯誤或 nsq), 一律重試
+func (p Policy) ShouldRetry(attempt int, code int) bool {
+	if attempt >= p.Attempts() {
+		return false
+	}
+	if p.RetryOn == "" || code == 0 {
+		return true
+	}
+	return httptarget.MatchCode(p.RetryOn, code)
+}
+
+// 第 attempt 次失敗後的等待時間
+func (p Policy) Delay(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(delay) * time.Millisecond
+}
+
+// 等待重試, ctx 結束時提早返回 false
+func Wait(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,82 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy Policy
+		valid  bool
+	}{
+		{"default", Policy{}, true},
+		{"legacy", Legacy, true},
+		{"full", Policy{MaxAttempts: 5, InitialDelay: 100, Multiplier: 2, MaxDelay: 1000, Jitter: 0.2, RetryOn: "429,5xx"}, true},
+		{"too many attempts", Policy{MaxAttempts: 11}, false},
+		{"negative attempts", Policy{MaxAttempts: -1}, false},
+		{"negative delay", Policy{MaxAttempts: 3, InitialDelay: -1}, false},
+		{"negative max delay", Policy{MaxAttempts: 3, MaxDelay: -1}, false},
+		{"multiplier below one", Policy{MaxAttempts: 3, Multiplier: 0.5}, false},
+		{"jitter above one", Policy{MaxAttempts: 3, Jitter: 1.5}, false},
+		{"bad retry on", Policy{MaxAttempts: 3, RetryOn: "abc"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyShouldRetry(t *testing.T) {
+	assert.False(t, Policy{}.ShouldRetry(1, 500))
+
+	policy := Policy{MaxAttempts: 3, RetryOn: "429,5xx"}
+	assert.True(t, policy.ShouldRetry(1, 503))
+	assert.True(t, policy.ShouldRetry(2, 429))
+	assert.False(t, policy.ShouldRetry(3, 503))
+	assert.False(t, policy.ShouldRetry(1, 404))
+	// 沒有 http 回應一律重試
+	assert.True(t, policy.ShouldRetry(1, 0))
+
+	all := Policy{MaxAttempts: 2}
+	assert.True(t, all.ShouldRetry(1, 404))
+	assert.False(t, all.ShouldRetry(2, 404))
+}
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{MaxAttempts: 5, InitialDelay: 100, Multiplier: 2, MaxDelay: 300}
+	assert.Equal(t, 100*time.Millisecond, policy.Delay(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Delay(2))
+	assert.Equal(t, 300*time.Millisecond, policy.Delay(3))
+	assert.Equal(t, 300*time.Millisecond, policy.Delay(4))
+
+	assert.Equal(t, 100*time.Millisecond, Legacy.Delay(1))
+	assert.Equal(t, 100*time.Millisecond, Legacy.Delay(2))
+
+	jitter := Policy{MaxAttempts: 3, InitialDelay: 1000, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		d := jitter.Delay(1)
+		assert.GreaterOrEqual(t, d, 800*time.Millisecond)
+		assert.LessOrEqual(t, d, 1200*time.Millisecond)
+	}
+}
+
+func TestWait(t *testing.T) {
+	assert.True(t, Wait(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, Wait(ctx, time.Minute))
+	assert.False(t, Wait(ctx, 0))
+}
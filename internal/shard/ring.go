@@ -0,0 +1,46 @@
+package shard
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// 一致性雜湊環, 每個節點放 replicas 個虛擬節點讓分配更平均
+type ring struct {
+	hashes []uint32
+	nodes  map[uint32]string
+}
+
+func newRing(nodes []string, replicas int) *ring {
+	r := &ring{
+		hashes: make([]uint32, 0, len(nodes)*replicas),
+		nodes:  make(map[uint32]string, len(nodes)*replicas),
+	}
+
+	for _, node := range nodes {
+		for i := 0; i < replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(node + "#" + strconv.Itoa(i)))
+			r.hashes = append(r.hashes, h)
+			r.nodes[h] = node
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+
+	return r
+}
+
+// 取得 key 所屬節點, 環上沒有節點時回傳空字串
+func (r *ring) get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if idx == len(r.hashes) {
+		idx = 0
+	}
+
+	return r.nodes[r.hashes[idx]]
+}
@@ -0,0 +1,179 @@
+package shard
+
+import (
+	"context"
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	nodePrefix = "SHARD_NODE_" // 節點心跳, TTL 為心跳間隔的 3 倍
+
+	defaultHeartbeat = 5
+	defaultReplicas  = 100
+)
+
+var (
+	logger    *logrus.Logger
+	enabled   bool
+	hostName  string
+	heartbeat int64
+	replicas  int
+
+	mutex   sync.RWMutex
+	members []string
+	hashing = newRing(nil, 0)
+)
+
+type Status struct {
+	Enabled  bool     `json:"enabled"`   // 是否啟用分片模式
+	HostName string   `json:"host_name"` // 本節點名稱
+	Nodes    []string `json:"nodes"`     // 目前存活的節點
+}
+
+func ConfigInit() {
+	instance := server.GetServerInstance()
+	env := instance.GetEnv()
+
+	logger = instance.GetLogger()
+	enabled = env.ShardMode
+	heartbeat = env.ShardHeartbeat
+	if heartbeat < 1 {
+		heartbeat = defaultHeartbeat
+	}
+	replicas = env.ShardReplicas
+	if replicas < 1 {
+		replicas = defaultReplicas
+	}
+	hostName = instance.GetHostName()
+
+	// 選主模式只有 leader 執行排程, 不能再分片
+	if enabled && env.LeaderElection {
+		logger.Warn("SHARD_MODE is ignored when LEADER_ELECTION is enabled")
+		enabled = false
+	}
+}
+
+// 是否啟用分片模式
+func Enabled() bool {
+	return enabled
+}
+
+// 任務是否由本節點負責, 未啟用分片模式一律負責
+func Owns(jobID string) bool {
+	if !enabled {
+		return true
+	}
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return hashing.get(jobID) == hostName
+}
+
+// 取得任務所屬節點
+func Owner(jobID string) string {
+	if !enabled {
+		return hostName
+	}
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return hashing.get(jobID)
+}
+
+// 註冊本節點並載入目前節點清單, 需在匯入任務前呼叫
+func Join() error {
+	if !enabled {
+		return nil
+	}
+	if err := beat(); err != nil {
+		return err
+	}
+	_, err := refresh()
+	return err
+}
+
+// 定時送出心跳, 節點異動時呼叫 onChange 重新分配任務
+func Run(ctx context.Context, onChange func()) {
+	if !enabled {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(heartbeat) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			redisCacher.Conn.Del(nodePrefix + hostName)
+			return
+		case <-ticker.C:
+			if err := beat(); err != nil {
+				logger.WithField("host_name", hostName).Errorf("Shard heartbeat error: %v", err)
+				continue
+			}
+			changed, err := refresh()
+			if err != nil {
+				logger.WithField("host_name", hostName).Errorf("Shard refresh error: %v", err)
+				continue
+			}
+			if changed {
+				logger.WithField("nodes", Nodes()).Info("Shard nodes changed, rebalancing")
+				onChange()
+			}
+		}
+	}
+}
+
+func beat() error {
+	return redisCacher.Conn.Set(nodePrefix+hostName, time.Now().Unix(), heartbeat*3)
+}
+
+// 重新載入節點清單, 回傳節點是否有異動
+func refresh() (bool, error) {
+	records, err := redisCacher.Conn.Scan(nodePrefix + "*")
+	if err != nil {
+		return false, err
+	}
+
+	nodes := []string{hostName}
+	for _, v := range records {
+		if node := strings.TrimPrefix(v, nodePrefix); node != hostName {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Strings(nodes)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if reflect.DeepEqual(nodes, members) {
+		return false, nil
+	}
+	members = nodes
+	hashing = newRing(nodes, replicas)
+
+	return true, nil
+}
+
+// 目前存活的節點
+func Nodes() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return append([]string{}, members...)
+}
+
+// 查詢分片狀態
+func GetStatus() Status {
+	return Status{
+		Enabled:  enabled,
+		HostName: hostName,
+		Nodes:    Nodes(),
+	}
+}
@@ -0,0 +1,106 @@
+package shard
+
+import (
+	"dcron/internal/redisCacher"
+	"dcron/server"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setShardTest(name string) {
+	redisCacher.SetMiniredis()
+
+	logger = server.GetServerInstance().GetLogger()
+	enabled = true
+	hostName = name
+	heartbeat = defaultHeartbeat
+	replicas = defaultReplicas
+	members = nil
+	hashing = newRing(nil, 0)
+}
+
+func TestRing(t *testing.T) {
+	t.Run("empty ring", func(t *testing.T) {
+		r := newRing(nil, defaultReplicas)
+		assert.Equal(t, "", r.get("100001"))
+	})
+
+	t.Run("spread and stable", func(t *testing.T) {
+		nodes := []string{"node-a", "node-b", "node-c"}
+		r := newRing(nodes, defaultReplicas)
+
+		count := make(map[string]int)
+		for i := 0; i < 3000; i++ {
+			key := fmt.Sprintf("%d", 400000+i)
+			owner := r.get(key)
+			assert.Equal(t, owner, r.get(key))
+			count[owner]++
+		}
+		for _, node := range nodes {
+			assert.Greater(t, count[node], 500, node)
+		}
+	})
+
+	t.Run("only keys of removed node move", func(t *testing.T) {
+		before := newRing([]string{"node-a", "node-b", "node-c"}, defaultReplicas)
+		after := newRing([]string{"node-a", "node-b"}, defaultReplicas)
+
+		for i := 0; i < 3000; i++ {
+			key := fmt.Sprintf("%d", 400000+i)
+			if owner := before.get(key); owner != "node-c" {
+				assert.Equal(t, owner, after.get(key), key)
+			}
+		}
+	})
+}
+
+func TestJoinAndRefresh(t *testing.T) {
+	setShardTest("node-a")
+
+	err := Join()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"node-a"}, Nodes())
+	assert.True(t, Owns("100001"))
+
+	ttl, err := redisCacher.Conn.TTL(nodePrefix + "node-a")
+	assert.Nil(t, err)
+	assert.Equal(t, int(defaultHeartbeat*3), ttl)
+
+	// node-b 加入
+	redisCacher.Conn.Set(nodePrefix+"node-b", 1, 15)
+	changed, err := refresh()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"node-a", "node-b"}, Nodes())
+
+	owned := 0
+	for i := 0; i < 100; i++ {
+		jobID := fmt.Sprintf("%d", 400000+i)
+		if Owns(jobID) {
+			owned++
+			assert.Equal(t, "node-a", Owner(jobID))
+		}
+	}
+	assert.Greater(t, owned, 0)
+	assert.Less(t, owned, 100)
+
+	changed, err = refresh()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	// node-b 心跳過期
+	redisCacher.Conn.Del(nodePrefix + "node-b")
+	changed, err = refresh()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.True(t, Owns("100001"))
+}
+
+func TestOwnsDisabled(t *testing.T) {
+	setShardTest("node-a")
+	enabled = false
+
+	assert.True(t, Owns("100001"))
+}
@@ -0,0 +1,378 @@
+package tracing
+
+import (
+	"context"
+	"dcron/server"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/*
+ * 分散式追蹤, 以 OpenTelemetry SDK 建立及送出 span, 以 W3C traceparent 傳遞
+ *
+ * 未設定 exporter 時不建立 span, Start 回傳 nil, Span 的方法皆可對 nil 呼叫
+ */
+
+// span 種類
+type Kind = trace.SpanKind
+
+const (
+	KindInternal = trace.SpanKindInternal
+	KindServer   = trace.SpanKindServer
+	KindClient   = trace.SpanKindClient
+	KindProducer = trace.SpanKindProducer
+)
+
+// span 狀態
+const (
+	StatusUnset = codes.Unset
+	StatusOK    = codes.Ok
+	StatusError = codes.Error
+)
+
+const (
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+
+	scopeName = "dcron"
+)
+
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+type Config struct {
+	Exporter    string  // "" 不啟用, otlp 或 file
+	Endpoint    string  // OTLP/HTTP 位址, 例: http://127.0.0.1:4318
+	File        string  // file exporter 寫入的檔案, 每行一個 span
+	SampleRatio float64 // 新 trace 的取樣比例, 不在 (0, 1] 時為 1
+	ServiceName string  // 空值為 dcron
+	HostName    string
+}
+
+type Attribute = attribute.KeyValue
+
+func String(key, value string) Attribute {
+	return attribute.String(key, value)
+}
+
+func Int(key string, value int) Attribute {
+	return attribute.Int(key, value)
+}
+
+func Bool(key string, value bool) Attribute {
+	return attribute.Bool(key, value)
+}
+
+var propagator = propagation.TraceContext{}
+
+// 解析 W3C traceparent: 00-{trace-id}-{parent-id}-{flags}
+func ParseTraceParent(s string) (trace.SpanContext, error) {
+	ctx := propagator.Extract(context.Background(), propagation.MapCarrier{"traceparent": strings.TrimSpace(s)})
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return sc, ErrInvalidTraceParent
+	}
+	return sc, nil
+}
+
+type Span struct {
+	span trace.Span
+}
+
+func (s *Span) SpanContext() trace.SpanContext {
+	if s == nil {
+		return trace.SpanContext{}
+	}
+	return s.span.SpanContext()
+}
+
+// 32 碼 hex, 未啟用時為空字串
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.span.SpanContext().TraceID().String()
+}
+
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(attrs...)
+}
+
+func (s *Span) SetStatus(code codes.Code, message string) {
+	if s == nil {
+		return
+	}
+	s.span.SetStatus(code, message)
+}
+
+// err 為 nil 時不變更
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// 結束並送出, 重複呼叫只送出一次
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
+
+type spanKey struct{}
+
+// ctx 中目前的 span, 沒有時為 nil; Extract 帶入的遠端 span 不算
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// 將 span 放入 ctx, 用於不沿用原 ctx 取消的背景作業; span 為 nil 時回傳原 ctx
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(trace.ContextWithSpan(ctx, s.span), spanKey{}, s)
+}
+
+// 帶入上游的 traceparent, 無效時回傳原 ctx
+func Extract(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": strings.TrimSpace(traceparent)})
+}
+
+// 傳給下游的 traceparent, 未啟用或沒有 span 時為空字串
+func TraceParent(ctx context.Context) string {
+	if current() == nil || ctx == nil {
+		return ""
+	}
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier["traceparent"]
+}
+
+// ctx 中的 trace id, 沒有時為空字串
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// 建立 span, ctx 中有 span 時為其子 span, 否則開始新的 trace
+func Start(ctx context.Context, name string, kind Kind, attrs ...Attribute) (context.Context, *Span) {
+	return StartWithLinks(ctx, name, kind, nil, attrs...)
+}
+
+// 建立關聯其他 trace 的 span, links 為 traceparent, 無效的忽略
+func StartWithLinks(ctx context.Context, name string, kind Kind, links []string, attrs ...Attribute) (context.Context, *Span) {
+	t := current()
+	if t == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opts := []trace.SpanStartOption{trace.WithSpanKind(kind), trace.WithAttributes(attrs...)}
+	for _, link := range links {
+		if sc, err := ParseTraceParent(link); err == nil {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, opts...)
+	s := &Span{span: span}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// 目前使用的 provider, 重新設定時整個替換
+type provider struct {
+	sdk    *sdktrace.TracerProvider
+	tracer trace.Tracer
+	closer io.Closer // file exporter 開啟的檔案
+}
+
+var (
+	mu     sync.RWMutex
+	tracer *provider
+	logger *logrus.Logger
+)
+
+func current() *provider {
+	mu.RLock()
+	defer mu.RUnlock()
+	return tracer
+}
+
+func ConfigInit() {
+	instance := server.GetServerInstance()
+	env := instance.GetEnv()
+	logger = instance.GetLogger()
+
+	// SDK 背景送出失敗時記錄
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Errorf("trace export error: %v", err)
+	}))
+
+	err := Configure(Config{
+		Exporter:    env.TraceExporter,
+		Endpoint:    env.TraceEndpoint,
+		File:        env.TraceFile,
+		SampleRatio: env.TraceSampleRatio,
+		ServiceName: env.TraceServiceName,
+		HostName:    instance.GetHostName(),
+	})
+	if err != nil {
+		logger.Errorf("tracing config error: %v", err)
+	}
+}
+
+// 套用設定, 取代並關閉原本的 provider
+func Configure(cfg Config) error {
+	var (
+		exp    sdktrace.SpanExporter
+		closer io.Closer
+		err    error
+	)
+	switch cfg.Exporter {
+	case "":
+	case ExporterOTLP:
+		exp, err = newOTLPExporter(cfg.Endpoint)
+	case ExporterFile:
+		exp, closer, err = newFileExporter(cfg.File)
+	default:
+		err = fmt.Errorf("unknown trace exporter: %s", cfg.Exporter)
+	}
+	if err != nil {
+		return err
+	}
+
+	var next *provider
+	if exp != nil {
+		sdk := sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exp),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio(cfg)))),
+			sdktrace.WithResource(resource.NewSchemaless(
+				attribute.String("service.name", serviceName(cfg)),
+				attribute.String("host.name", hostName(cfg)),
+			)),
+		)
+		next = &provider{sdk: sdk, tracer: sdk.Tracer(scopeName), closer: closer}
+	}
+
+	mu.Lock()
+	prev := tracer
+	tracer = next
+	mu.Unlock()
+
+	if prev != nil {
+		prev.shutdown(context.Background())
+	}
+	return nil
+}
+
+// 停止並送出剩餘的 span
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	prev := tracer
+	tracer = nil
+	mu.Unlock()
+
+	if prev == nil {
+		return nil
+	}
+	return prev.shutdown(ctx)
+}
+
+func (p *provider) shutdown(ctx context.Context) error {
+	err := p.sdk.Shutdown(ctx)
+	if p.closer != nil {
+		p.closer.Close()
+	}
+	return err
+}
+
+// OTLP/HTTP, POST {endpoint}/v1/traces
+func newOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	if endpoint == "" {
+		return nil, errors.New("trace endpoint is required for otlp exporter")
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid trace endpoint: %s", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u.Path += "/v1/traces"
+	}
+	return otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(u.String()))
+}
+
+// 以 JSON 附加寫入檔案, 每行一個 span
+func newFileExporter(path string) (sdktrace.SpanExporter, io.Closer, error) {
+	if path == "" {
+		return nil, nil, errors.New("trace file is required for file exporter")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return exp, f, nil
+}
+
+func serviceName(cfg Config) string {
+	if cfg.ServiceName != "" {
+		return cfg.ServiceName
+	}
+	return "dcron"
+}
+
+func hostName(cfg Config) string {
+	if cfg.HostName != "" {
+		return cfg.HostName
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+func sampleRatio(cfg Config) float64 {
+	if cfg.SampleRatio <= 0 || cfg.SampleRatio > 1 {
+		return 1
+	}
+	return cfg.SampleRatio
+}
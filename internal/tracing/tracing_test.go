@@ -0,0 +1,233 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// file exporter 每行一個 span 的欄位
+type fileSpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+type fileSpan struct {
+	Name        string
+	SpanContext fileSpanContext
+	Parent      fileSpanContext
+	SpanKind    int
+	Attributes  []struct {
+		Key   string
+		Value struct{ Value interface{} }
+	}
+	Links []struct {
+		SpanContext fileSpanContext
+	}
+	Status struct {
+		Code        string
+		Description string
+	}
+	Resource []struct {
+		Key   string
+		Value struct{ Value interface{} }
+	}
+}
+
+// 以 file exporter 啟用追蹤, 回傳讀取已送出 span 的函式
+func setFileTest(t *testing.T) func() []fileSpan {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	assert.Nil(t, Configure(Config{Exporter: ExporterFile, File: path, ServiceName: "dcron-test"}))
+	t.Cleanup(func() {
+		Shutdown(context.Background())
+	})
+
+	return func() []fileSpan {
+		assert.Nil(t, Shutdown(context.Background()))
+
+		f, err := os.Open(path)
+		assert.Nil(t, err)
+		defer f.Close()
+
+		var spans []fileSpan
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var s fileSpan
+			assert.Nil(t, json.Unmarshal(scanner.Bytes(), &s))
+			resource := map[string]interface{}{}
+			for _, kv := range s.Resource {
+				resource[kv.Key] = kv.Value.Value
+			}
+			assert.Equal(t, "dcron-test", resource["service.name"])
+			spans = append(spans, s)
+		}
+		return spans
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, err)
+	assert.True(t, sc.IsSampled())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID().String())
+
+	sc, err = ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	assert.Nil(t, err)
+	assert.False(t, sc.IsSampled())
+
+	// 之後的版本可附加欄位
+	_, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.Nil(t, err)
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, err := ParseTraceParent(s)
+		assert.Equal(t, ErrInvalidTraceParent, err, s)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	assert.Nil(t, Configure(Config{}))
+
+	ctx, span := Start(context.Background(), "noop", KindInternal)
+	assert.Nil(t, span)
+	assert.Equal(t, "", TraceParent(ctx))
+	assert.Equal(t, "", span.TraceID())
+
+	// nil span 的方法不會 panic
+	span.SetAttributes(String("a", "b"))
+	span.SetError(errors.New("failed"))
+	span.End()
+
+	assert.NotNil(t, Configure(Config{Exporter: "jaeger"}))
+	assert.NotNil(t, Configure(Config{Exporter: ExporterOTLP}))
+	assert.NotNil(t, Configure(Config{Exporter: ExporterOTLP, Endpoint: "127.0.0.1:4318"}))
+	assert.NotNil(t, Configure(Config{Exporter: ExporterFile}))
+}
+
+func TestSpans(t *testing.T) {
+	read := setFileTest(t)
+
+	ctx := Extract(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, SpanFromContext(ctx))
+	ctx, parent := Start(ctx, "GET /api/job/info", KindServer, String("http.method", "GET"))
+	assert.Equal(t, parent, SpanFromContext(ctx))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", parent.TraceID())
+	assert.Equal(t, parent.TraceID(), TraceID(ctx))
+
+	childCtx, child := Start(ctx, "redis get", KindClient, Int("db.index", 2), Bool("cached", true))
+	assert.Equal(t, parent.TraceID(), child.TraceID())
+	assert.Equal(t, "00-"+child.TraceID()+"-"+child.SpanContext().SpanID().String()+"-01", TraceParent(childCtx))
+	child.SetError(errors.New("connection refused"))
+	child.End()
+	child.End()
+
+	// 背景作業只沿用 span
+	bg := ContextWithSpan(context.Background(), parent)
+	assert.Equal(t, TraceParent(ctx), TraceParent(bg))
+
+	_, root := StartWithLinks(context.Background(), "cronjob.run", KindInternal, []string{TraceParent(ctx), "invalid", ""})
+	assert.NotEqual(t, parent.TraceID(), root.TraceID())
+	root.End()
+	parent.End()
+
+	spans := read()
+	assert.Len(t, spans, 3)
+	byName := map[string]fileSpan{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+
+	server := byName["GET /api/job/info"]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID)
+	assert.Equal(t, int(KindServer), server.SpanKind)
+	assert.Equal(t, "http.method", server.Attributes[0].Key)
+	assert.Equal(t, "GET", server.Attributes[0].Value.Value)
+
+	redis := byName["redis get"]
+	assert.Equal(t, server.SpanContext.TraceID, redis.SpanContext.TraceID)
+	assert.Equal(t, server.SpanContext.SpanID, redis.Parent.SpanID)
+	assert.Equal(t, "Error", redis.Status.Code)
+	assert.Equal(t, "connection refused", redis.Status.Description)
+	assert.Equal(t, float64(2), redis.Attributes[0].Value.Value)
+	assert.Equal(t, true, redis.Attributes[1].Value.Value)
+
+	run := byName["cronjob.run"]
+	assert.Equal(t, "0000000000000000", run.Parent.SpanID)
+	if assert.Len(t, run.Links, 1) {
+		assert.Equal(t, server.SpanContext, run.Links[0].SpanContext)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	read := setFileTest(t)
+
+	// 未取樣的上游, 子 span 不送出但仍傳遞 traceparent
+	ctx := Extract(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	ctx, span := Start(ctx, "skipped", KindServer)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID())
+	assert.Regexp(t, `^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-00$`, TraceParent(ctx))
+	span.End()
+
+	assert.Len(t, read(), 0)
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		path        string
+		contentType string
+		req         coltracepb.ExportTraceServiceRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		proto.Unmarshal(b, &req)
+	}))
+	defer srv.Close()
+
+	assert.Nil(t, Configure(Config{Exporter: ExporterOTLP, Endpoint: srv.URL + "/"}))
+	_, span := Start(context.Background(), "cronjob.run", KindInternal)
+	span.End()
+	assert.Nil(t, Shutdown(context.Background()))
+
+	assert.Equal(t, "/v1/traces", path)
+	assert.Equal(t, "application/x-protobuf", contentType)
+	if assert.Len(t, req.ResourceSpans, 1) {
+		rs := req.ResourceSpans[0]
+		resource := map[string]string{}
+		for _, kv := range rs.Resource.Attributes {
+			resource[kv.Key] = kv.Value.GetStringValue()
+		}
+		assert.Equal(t, "dcron", resource["service.name"])
+		sc := span.SpanContext()
+		traceID := sc.TraceID()
+		assert.Equal(t, traceID[:], rs.ScopeSpans[0].Spans[0].TraceId)
+	}
+
+	// 已包含路徑時不重複附加
+	assert.Nil(t, Configure(Config{Exporter: ExporterOTLP, Endpoint: srv.URL + "/v1/traces"}))
+	_, span = Start(context.Background(), "cronjob.run", KindInternal)
+	span.End()
+	assert.Nil(t, Shutdown(context.Background()))
+	assert.Equal(t, "/v1/traces", path)
+}
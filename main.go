@@ -5,9 +5,15 @@ import (
 	"dcron/handler"
 	"dcron/httpserver"
 	"dcron/internal/cronjob"
+	"dcron/internal/deadletter"
+	"dcron/internal/history"
+	"dcron/internal/leader"
+	"dcron/internal/notify"
 	"dcron/internal/nsqtarget"
 	"dcron/internal/redisCacher"
+	"dcron/internal/shard"
 	"dcron/internal/snowflake"
+	"dcron/internal/tracing"
 	"dcron/server"
 
 	"net/http"
@@ -107,12 +113,32 @@ func start(c *cli.Context) {
 	server.GetServerInstance().GetWorker().GracefulStop()
 	logger.Info("停止Goworker完成")
 
+	// 等待背景送出中的通知
+	notifyCtx, notifyCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := notify.Wait(notifyCtx); err != nil {
+		logger.Printf("notify wait error: %v", err)
+	}
+	notifyCancel()
+
+	// 送出剩餘的 span
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := tracing.Shutdown(flushCtx); err != nil {
+		logger.Printf("tracing shutdown error: %v", err)
+	}
+	flushCancel()
+
 	<-time.After(time.Duration(env.GraceShutdownTime) * time.Second)
 	logger.Println("退出完成...")
 }
 
 func loadConfig(ctx context.Context) {
+	tracing.ConfigInit()
 	redisCacher.ConfigInit()
+	leader.ConfigInit()
+	shard.ConfigInit()
+	history.ConfigInit()
+	deadletter.ConfigInit()
+	notify.ConfigInit()
 	cronjob.ConfigInit()
 	nsqtarget.ConfigInit()
 	snowflake.ConfigInit()
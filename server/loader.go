@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"dcron/internal/goworker"
+	"fmt"
 	"os"
 	"time"
 
@@ -31,9 +32,24 @@ func Loader(c *cli.Context, ctx *context.Context) {
 		gracefulCtx: ctx,
 		goworker:    worker,
 		nsqProducer: producer,
+		hostName:    initHostName(env),
 	}
 }
 
+// 節點名稱, 未設定 HOST_NAME 時使用 hostname-pid
+func initHostName(env EnvStruct) string {
+	if env.HostName != "" {
+		return env.HostName
+	}
+
+	hostName, err := os.Hostname()
+	if err != nil {
+		hostName = "dcron"
+	}
+
+	return fmt.Sprintf("%s-%d", hostName, os.Getpid())
+}
+
 // 載入環境變數
 func initEnv(c *cli.Context) (env EnvStruct) {
 	viper.SetConfigFile(c.String("config"))
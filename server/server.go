@@ -18,28 +18,55 @@ type Server struct {
 	gracefulCtx *context.Context
 	goworker    *goworker.Pool
 	nsqProducer *nsq.Producer
+	hostName    string
 }
 
 type EnvStruct struct {
-	AppEnv              string `mapstructure:"APP_ENV" json:"APP_ENV"`
-	ServerPort          string `mapstructure:"SERVER_PORT" json:"SERVER_PORT"`
-	RedisHost           string `mapstructure:"REDIS_HOST" json:"REDIS_HOST"`
-	RedisDB             int    `mapstructure:"REDIS_DB" json:"REDIS_DB"`
-	NsqdHost            string `mapstructure:"NSQD_HOST" json:"NSQD_HOST"`
-	NsqdMaxInFlight     int    `mapstructure:"NSQD_MAXINFLIGHT" json:"NSQD_MAXINFLIGHT"`
-	NsqdDialTimeout     int    `mapstructure:"NSQD_DIALTIMEOUT" json:"NSQD_DIALTIMEOUT"`
-	NsqdMaxAttempts     int    `mapstructure:"NSQD_MAXATTEMPTS" json:"NSQD_MAXATTEMPTS"`
-	NsqdMaxRequeueDelay int    `mapstructure:"NSQD_MAXREQUEUEDELAY" json:"NSQD_MAXREQUEUEDELAY"`
-	WorkerPoolSize      int64  `mapstructure:"WORKER_POOL_SIZE" json:"WORKER_POOL_SIZE"`
-	WorkerMaxOpen       int64  `mapstructure:"WORKER_MAX_OPEN" json:"WORKER_MAX_OPEN"`
-	WorkerIdle          int64  `mapstructure:"WORKER_IDLE" json:"WORKER_IDLE"`
-	WorkerLifeTime      int    `mapstructure:"WORKER_LIFE_TIME" json:"WORKER_LIFE_TIME"`
-	GraceShutdownTime   int    `mapstructure:"GRACE_SHUTDOWN_TIME" json:"GRACE_SHUTDOWN_TIME"`
+	AppEnv              string  `mapstructure:"APP_ENV" json:"APP_ENV"`
+	ServerPort          string  `mapstructure:"SERVER_PORT" json:"SERVER_PORT"`
+	RedisHost           string  `mapstructure:"REDIS_HOST" json:"REDIS_HOST"`
+	RedisDB             int     `mapstructure:"REDIS_DB" json:"REDIS_DB"`
+	NsqdHost            string  `mapstructure:"NSQD_HOST" json:"NSQD_HOST"`
+	NsqdMaxInFlight     int     `mapstructure:"NSQD_MAXINFLIGHT" json:"NSQD_MAXINFLIGHT"`
+	NsqdDialTimeout     int     `mapstructure:"NSQD_DIALTIMEOUT" json:"NSQD_DIALTIMEOUT"`
+	NsqdMaxAttempts     int     `mapstructure:"NSQD_MAXATTEMPTS" json:"NSQD_MAXATTEMPTS"`
+	NsqdMaxRequeueDelay int     `mapstructure:"NSQD_MAXREQUEUEDELAY" json:"NSQD_MAXREQUEUEDELAY"`
+	WorkerPoolSize      int64   `mapstructure:"WORKER_POOL_SIZE" json:"WORKER_POOL_SIZE"`
+	WorkerMaxOpen       int64   `mapstructure:"WORKER_MAX_OPEN" json:"WORKER_MAX_OPEN"`
+	WorkerIdle          int64   `mapstructure:"WORKER_IDLE" json:"WORKER_IDLE"`
+	WorkerLifeTime      int     `mapstructure:"WORKER_LIFE_TIME" json:"WORKER_LIFE_TIME"`
+	GraceShutdownTime   int     `mapstructure:"GRACE_SHUTDOWN_TIME" json:"GRACE_SHUTDOWN_TIME"`
+	HostName            string  `mapstructure:"HOST_NAME" json:"HOST_NAME"`
+	LeaderElection      bool    `mapstructure:"LEADER_ELECTION" json:"LEADER_ELECTION"`
+	LeaderLeaseTime     int64   `mapstructure:"LEADER_LEASE_TIME" json:"LEADER_LEASE_TIME"`
+	ShardMode           bool    `mapstructure:"SHARD_MODE" json:"SHARD_MODE"`
+	ShardHeartbeat      int64   `mapstructure:"SHARD_HEARTBEAT" json:"SHARD_HEARTBEAT"`
+	ShardReplicas       int     `mapstructure:"SHARD_REPLICAS" json:"SHARD_REPLICAS"`
+	HistoryMaxLen       int64   `mapstructure:"HISTORY_MAX_LEN" json:"HISTORY_MAX_LEN"`
+	HistoryTTL          int64   `mapstructure:"HISTORY_TTL" json:"HISTORY_TTL"`
+	HistoryBodySize     int     `mapstructure:"HISTORY_BODY_SIZE" json:"HISTORY_BODY_SIZE"`
+	MisfireGraceTime    int64   `mapstructure:"MISFIRE_GRACE_TIME" json:"MISFIRE_GRACE_TIME"`
+	Timezone            string  `mapstructure:"TIMEZONE" json:"TIMEZONE"`
+	DeadLetterMaxLen    int64   `mapstructure:"DEADLETTER_MAX_LEN" json:"DEADLETTER_MAX_LEN"`
+	DeadLetterTTL       int64   `mapstructure:"DEADLETTER_TTL" json:"DEADLETTER_TTL"`
+	TraceExporter       string  `mapstructure:"TRACE_EXPORTER" json:"TRACE_EXPORTER"`
+	TraceEndpoint       string  `mapstructure:"TRACE_ENDPOINT" json:"TRACE_ENDPOINT"`
+	TraceFile           string  `mapstructure:"TRACE_FILE" json:"TRACE_FILE"`
+	TraceSampleRatio    float64 `mapstructure:"TRACE_SAMPLE_RATIO" json:"TRACE_SAMPLE_RATIO"`
+	TraceServiceName    string  `mapstructure:"TRACE_SERVICE_NAME" json:"TRACE_SERVICE_NAME"`
+	SmtpHost            string  `mapstructure:"SMTP_HOST" json:"SMTP_HOST"`
+	SmtpPort            int     `mapstructure:"SMTP_PORT" json:"SMTP_PORT"`
+	SmtpUsername        string  `mapstructure:"SMTP_USERNAME" json:"SMTP_USERNAME"`
+	SmtpPassword        string  `mapstructure:"SMTP_PASSWORD" json:"-"`
+	SmtpFrom            string  `mapstructure:"SMTP_FROM" json:"SMTP_FROM"`
+	ReconcileInterval   int64   `mapstructure:"RECONCILE_INTERVAL" json:"RECONCILE_INTERVAL"`
 }
 
 func GetServerInstance() *Server {
 	if serverObject == nil {
-		serverObject = &Server{}
+		serverObject = &Server{
+			logger: initLog(),
+		}
 	}
 	return serverObject
 }
@@ -67,3 +94,8 @@ func (s *Server) GetWorker() *goworker.Pool {
 func (s *Server) GetNSQProducer() *nsq.Producer {
 	return s.nsqProducer
 }
+
+// 節點名稱, 用來識別叢集中的各個 dcron
+func (s *Server) GetHostName() string {
+	return s.hostName
+}